@@ -0,0 +1,239 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type APIKeyHandler struct {
+	Service service.IAPIKeyService
+	Logger  *logrus.Logger
+}
+
+func NewAPIKeyHandler(e *echo.Echo, service service.IAPIKeyService, log *logrus.Logger) *APIKeyHandler {
+	a := &APIKeyHandler{Service: service, Logger: log}
+
+	// api keys are managed by admins with an access token, an api key cannot manage keys
+	manage := Authorize(rbac.ManageAPIKeys)
+
+	//Routes
+	router := e.Group("api/apikeys")
+	router.GET("", a.GetAllAPIKeys, manage)
+	router.GET("/:id", a.GetAPIKeyById, manage)
+	router.POST("", a.CreateAPIKey, manage)
+	router.POST("/:id/rotate", a.RotateAPIKey, manage)
+	router.DELETE("/:id", a.RevokeAPIKey, manage)
+
+	return a
+}
+
+// GetAllAPIKeys => To get request for listing api keys with their usage
+
+// GetAllAPIKeys godoc
+// @Summary get all api keys with their usage, newest first with pagination
+// @ID get-all-api-keys
+// @Produce json
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /apikeys [get]
+func (h APIKeyHandler) GetAllAPIKeys(c echo.Context) error {
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	apiKeyList, total, err := h.Service.GetAll(c.Request().Context(), skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	apiKeysResponse := make([]dtos.APIKeyResponse, 0, len(apiKeyList))
+	for _, apiKey := range apiKeyList {
+		apiKeysResponse = append(apiKeysResponse, toAPIKeyResponse(apiKey, ""))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           apiKeysResponse,
+		Pagination:     newPagination(skip, limit, len(apiKeysResponse), total),
+	}
+
+	h.Logger.Info("All api keys are listed.")
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetAPIKeyById => To get request find an api key by id
+
+// GetAPIKeyById godoc
+// @Summary get an api key with its usage by ID
+// @ID get-api-key-by-id
+// @Produce json
+// @Param id path string true "api key ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Security BearerAuth
+// @Router /apikeys/{id} [get]
+func (h APIKeyHandler) GetAPIKeyById(c echo.Context) error {
+	query := c.Param("id")
+
+	apiKey, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toAPIKeyResponse(apiKey, ""),
+	}
+
+	h.Logger.Infof("{%v} with id api key is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// CreateAPIKey => To post request for creating new an api key, the key is only in this response
+
+// CreateAPIKey godoc
+// @Summary create an api key for a machine client, the key is sent as "X-API-Key: <key>" and cannot be read again
+// @ID create-api-key
+// @Accept json
+// @Produce json
+// @Param data body dtos.APIKeyRequest true "api key data"
+// @Success 201 {object} response.JSONSuccessResultData "data => dtos.APIKeyResponse"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /apikeys [post]
+func (h APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	var apiKeyRequest dtos.APIKeyRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&apiKeyRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(apiKeyRequest); err != nil {
+		return err
+	}
+
+	apiKey, key, err := h.Service.Create(c.Request().Context(), apiKeyRequest.Name, apiKeyRequest.Scopes)
+
+	if err != nil {
+		return err
+	}
+
+	h.Logger.Infof("{%v} with id api key is created.", apiKey.ID)
+	return keyResponse(c, http.StatusCreated, apiKey, key)
+}
+
+// RotateAPIKey => To post request for replacing the key of an api key, the old key stops working
+
+// RotateAPIKey godoc
+// @Summary replace the key of an api key, name, scopes & usage are kept and the old key cannot be used again
+// @ID rotate-api-key
+// @Produce json
+// @Param id path string true "api key ID"
+// @Success 200 {object} response.JSONSuccessResultData "data => dtos.APIKeyResponse"
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /apikeys/{id}/rotate [post]
+func (h APIKeyHandler) RotateAPIKey(c echo.Context) error {
+	query := c.Param("id")
+
+	apiKey, key, err := h.Service.Rotate(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	h.Logger.Infof("{%v} with id api key is rotated.", query)
+	return keyResponse(c, http.StatusOK, apiKey, key)
+}
+
+// RevokeAPIKey => To delete request by id, revoked keys are kept with their usage
+
+// RevokeAPIKey godoc
+// @Summary revoke an api key by ID
+// @ID revoke-api-key
+// @Produce json
+// @Param id path string true "api key ID"
+// @Success 200 {object} response.JSONSuccessResultId
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /apikeys/{id} [delete]
+func (h APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	query := c.Param("id")
+
+	if err := h.Service.Revoke(c.Request().Context(), query); err != nil {
+		return err
+	}
+
+	// to response id and success boolean
+	jsonSuccessResultId := response.JSONSuccessResultId{
+		ID:      query,
+		Success: true,
+	}
+
+	h.Logger.Infof("{%v} with id api key is revoked.", jsonSuccessResultId.ID)
+	return c.JSON(http.StatusOK, jsonSuccessResultId)
+}
+
+// keyResponse => responses with a key must not be cached
+func keyResponse(c echo.Context, status int, apiKey models.APIKey, key string) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	return c.JSON(status, response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toAPIKeyResponse(apiKey, key),
+	})
+}
+
+// toAPIKeyResponse => mapping from model to response dto, key is empty except create & rotate
+func toAPIKeyResponse(apiKey models.APIKey, key string) dtos.APIKeyResponse {
+	apiKeyResponse := dtos.APIKeyResponse{
+		ID:           apiKey.ID,
+		Name:         apiKey.Name,
+		Prefix:       apiKey.Prefix,
+		Key:          key,
+		Scopes:       apiKey.Scopes,
+		CreatedBy:    apiKey.CreatedBy,
+		CreatedDate:  apiKey.CreatedDate.Time(),
+		RequestCount: apiKey.RequestCount,
+	}
+	if apiKey.RevokedAt != nil {
+		revokedAt := apiKey.RevokedAt.Time()
+		apiKeyResponse.RevokedAt = &revokedAt
+	}
+	if apiKey.LastUsedAt != nil {
+		lastUsedAt := apiKey.LastUsedAt.Time()
+		apiKeyResponse.LastUsedAt = &lastUsedAt
+	}
+
+	return apiKeyResponse
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+type AuditHandler struct {
+	Service service.IAuditService
+	Logger  *logrus.Logger
+}
+
+func NewAuditHandler(e *echo.Echo, service service.IAuditService, log *logrus.Logger) *AuditHandler {
+	a := &AuditHandler{Service: service, Logger: log}
+
+	//Routes
+	e.GET("api/books/:id/history", a.GetBookHistory, Authorize(rbac.ReadBooks))
+	e.GET("api/audit", a.GetAuditRecords)
+
+	return a
+}
+
+// GetBookHistory => To get request for listing changes of a book, newest first
+
+// GetBookHistory godoc
+// @Summary get change history of a book item by ID
+// @ID get-book-history
+// @Produce json
+// @Param id path string true "book ID"
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id}/history [get]
+func (h AuditHandler) GetBookHistory(c echo.Context) error {
+	query := c.Param("id")
+
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	records, total, err := h.Service.GetBookHistory(c.Request().Context(), query, skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           toAuditResponses(records),
+		Pagination:     newPagination(skip, limit, len(records), total),
+	}
+
+	h.Logger.Infof("History of {%v} with id is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetAuditRecords => To get request for listing audit records of every entity with filters
+
+// GetAuditRecords godoc
+// @Summary get audit records, newest first
+// @ID get-audit-records
+// @Produce json
+// @Param entitytype query string false "e.g. book"
+// @Param entityid query string false "id of the changed entity"
+// @Param action query string false "create, update, patch, delete, restore, purge"
+// @Param actor query string false "who made the change"
+// @Param requestid query string false "X-Request-ID of the change"
+// @Param from query string false "RFC3339 date, inclusive"
+// @Param to query string false "RFC3339 date, exclusive"
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /audit [get]
+func (h AuditHandler) GetAuditRecords(c echo.Context) error {
+	query, err := parseAuditQuery(c)
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	records, total, err := h.Service.Find(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           toAuditResponses(records),
+		Pagination:     newPagination(query.Skip, query.Limit, len(records), total),
+	}
+
+	h.Logger.Infof("%v of %v audit records are listed.", len(records), total)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+func parseAuditQuery(c echo.Context) (models.AuditQuery, error) {
+	query := models.AuditQuery{
+		EntityType: c.QueryParam("entitytype"),
+		EntityID:   c.QueryParam("entityid"),
+		Action:     c.QueryParam("action"),
+		Actor:      c.QueryParam("actor"),
+		RequestID:  c.QueryParam("requestid"),
+	}
+
+	var err error
+	if query.Skip, query.Limit, err = parsePaging(c.QueryParams()); err != nil {
+		return query, err
+	}
+
+	for name, target := range map[string]**time.Time{"from": &query.From, "to": &query.To} {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return query, fmt.Errorf("{%v} must be an RFC3339 date", name)
+		}
+		*target = &date
+	}
+
+	return query, nil
+}
+
+// toAuditResponses => mapping from models to response dtos
+func toAuditResponses(records []models.AuditRecord) []dtos.AuditRecordResponse {
+	auditResponse := make([]dtos.AuditRecordResponse, 0, len(records))
+
+	for _, record := range records {
+		changes := make([]dtos.FieldChangeResponse, 0, len(record.Changes))
+		for _, change := range record.Changes {
+			changes = append(changes, dtos.FieldChangeResponse{Field: change.Field, Before: change.Before, After: change.After})
+		}
+
+		auditResponse = append(auditResponse, dtos.AuditRecordResponse{
+			ID:         record.ID,
+			Date:       record.Date.Time(),
+			EntityType: record.EntityType,
+			EntityID:   record.EntityID,
+			Action:     record.Action,
+			Actor:      record.Actor,
+			RequestID:  record.RequestID,
+			Changes:    changes,
+		})
+	}
+
+	return auditResponse
+}
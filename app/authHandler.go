@@ -0,0 +1,145 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type AuthHandler struct {
+	Service service.IAuthService
+	Logger  *logrus.Logger
+}
+
+func NewAuthHandler(e *echo.Echo, service service.IAuthService, log *logrus.Logger) *AuthHandler {
+	a := &AuthHandler{Service: service, Logger: log}
+
+	//Routes
+	router := e.Group("api/auth")
+	router.POST("/login", a.Login)
+	router.POST("/refresh", a.Refresh)
+	router.POST("/logout", a.Logout)
+
+	return a
+}
+
+// Login => To post request for getting an access token and a refresh token with username & password
+
+// Login godoc
+// @Summary log in with username & password, access token is used as "Authorization: Bearer <token>"
+// @ID login
+// @Accept json
+// @Produce json
+// @Param data body dtos.LoginRequest true "credentials"
+// @Success 200 {object} response.JSONSuccessResultData "data => dtos.TokenResponse"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Router /auth/login [post]
+func (h AuthHandler) Login(c echo.Context) error {
+	var loginRequest dtos.LoginRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&loginRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(loginRequest); err != nil {
+		return err
+	}
+
+	tokens, err := h.Service.Login(c.Request().Context(), loginRequest.Username, loginRequest.Password)
+
+	if err != nil {
+		return err
+	}
+
+	h.Logger.Infof("{%v} user is logged in.", loginRequest.Username)
+	return tokenResponse(c, tokens)
+}
+
+// Refresh => To post request for rotating a refresh token, the old one cannot be used again
+
+// Refresh godoc
+// @Summary get a new access token and a new refresh token with a refresh token
+// @ID refresh-token
+// @Accept json
+// @Produce json
+// @Param data body dtos.RefreshTokenRequest true "refresh token"
+// @Success 200 {object} response.JSONSuccessResultData "data => dtos.TokenResponse"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Router /auth/refresh [post]
+func (h AuthHandler) Refresh(c echo.Context) error {
+	var refreshRequest dtos.RefreshTokenRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&refreshRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(refreshRequest); err != nil {
+		return err
+	}
+
+	tokens, err := h.Service.Refresh(c.Request().Context(), refreshRequest.RefreshToken)
+
+	if err != nil {
+		return err
+	}
+
+	h.Logger.Info("Refresh token is rotated.")
+	return tokenResponse(c, tokens)
+}
+
+// Logout => To post request for revoking the refresh tokens of a login
+
+// Logout godoc
+// @Summary revoke the refresh token and every token rotated from the same login
+// @ID logout
+// @Accept json
+// @Param data body dtos.RefreshTokenRequest true "refresh token"
+// @Success 204
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Router /auth/logout [post]
+func (h AuthHandler) Logout(c echo.Context) error {
+	var refreshRequest dtos.RefreshTokenRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&refreshRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(refreshRequest); err != nil {
+		return err
+	}
+
+	if err := h.Service.Logout(c.Request().Context(), refreshRequest.RefreshToken); err != nil {
+		return err
+	}
+
+	h.Logger.Info("Refresh tokens of a login are revoked.")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tokenResponse => tokens must not be cached
+func tokenResponse(c echo.Context, tokens models.TokenPair) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	return c.JSON(http.StatusOK, response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data: dtos.TokenResponse{
+			AccessToken:  tokens.AccessToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    tokens.ExpiresIn,
+			RefreshToken: tokens.RefreshToken,
+		},
+	})
+}
@@ -0,0 +1,277 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type AuthorHandler struct {
+	Service service.IAuthorService
+	Logger  *logrus.Logger
+}
+
+func NewAuthorHandler(e *echo.Echo, service service.IAuthorService, log *logrus.Logger) *AuthorHandler {
+	a := &AuthorHandler{Service: service, Logger: log}
+
+	//Routes
+	router := e.Group("api/authors")
+	router.GET("", a.GetAllAuthors)
+	router.GET("/:id", a.GetAuthorById)
+	router.POST("", a.CreateAuthor)
+	router.PUT("", a.UpdateAuthor)
+	router.DELETE("/:id", a.DeleteAuthor)
+	router.GET("/:id/books", a.GetAuthorBooks)
+
+	return a
+}
+
+// GetAllAuthors => To get request for listing all of authors
+
+// GetAllAuthors godoc
+// @Summary get all authors ordered by name with pagination
+// @ID get-all-authors
+// @Produce json
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /authors [get]
+func (h AuthorHandler) GetAllAuthors(c echo.Context) error {
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	authorList, total, err := h.Service.GetAll(c.Request().Context(), skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	authorsResponse := make([]dtos.AuthorResponse, 0, len(authorList))
+	for _, author := range authorList {
+		authorsResponse = append(authorsResponse, toAuthorResponse(author))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           authorsResponse,
+		Pagination:     newPagination(skip, limit, len(authorsResponse), total),
+	}
+
+	h.Logger.Info("All authors are listed.")
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetAuthorById => To get request find an author by id
+
+// GetAuthorById godoc
+// @Summary get an author by ID
+// @ID get-author-by-id
+// @Produce json
+// @Param id path string true "author ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Security BearerAuth
+// @Router /authors/{id} [get]
+func (h AuthorHandler) GetAuthorById(c echo.Context) error {
+	query := c.Param("id")
+
+	author, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toAuthorResponse(author),
+	}
+
+	h.Logger.Infof("{%v} with id author is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// CreateAuthor => To post request for creating new an author
+
+// CreateAuthor godoc
+// @Summary add a new author, names are compared without case, spaces and punctuation
+// @ID create-author
+// @Accept json
+// @Produce json
+// @Param data body dtos.AuthorRequest true "author data"
+// @Success 201 {object} response.JSONSuccessResultId
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /authors [post]
+func (h AuthorHandler) CreateAuthor(c echo.Context) error {
+	var authorRequest dtos.AuthorRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&authorRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(authorRequest); err != nil {
+		return err
+	}
+
+	result, err := h.Service.Insert(c.Request().Context(), models.Author{Name: authorRequest.Name})
+
+	if err != nil {
+		return err
+	}
+
+	// to response id and success boolean
+	jsonSuccessResultId := response.JSONSuccessResultId{
+		ID:      result.ID,
+		Success: true,
+	}
+
+	h.Logger.Infof("{%v} with id author is created.", jsonSuccessResultId.ID)
+	return c.JSON(http.StatusCreated, jsonSuccessResultId)
+}
+
+// UpdateAuthor => To put request for renaming an author
+
+// UpdateAuthor godoc
+// @Summary update an author by ID
+// @ID update-author
+// @Accept json
+// @Produce json
+// @Param data body dtos.AuthorUpdateRequest true "author data"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /authors [put]
+func (h AuthorHandler) UpdateAuthor(c echo.Context) error {
+	var authorRequest dtos.AuthorUpdateRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&authorRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(authorRequest); err != nil {
+		return err
+	}
+
+	result, err := h.Service.Update(c.Request().Context(), models.Author{ID: authorRequest.ID, Name: authorRequest.Name})
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toAuthorResponse(result),
+	}
+
+	h.Logger.Infof("{%v} with id author is updated.", result.ID)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// DeleteAuthor => To delete request by id, authors of books cannot be deleted
+
+// DeleteAuthor godoc
+// @Summary delete an author by ID
+// @ID delete-author
+// @Produce json
+// @Param id path string true "author ID"
+// @Success 200 {object} response.JSONSuccessResultId
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /authors/{id} [delete]
+func (h AuthorHandler) DeleteAuthor(c echo.Context) error {
+	query := c.Param("id")
+
+	result, err := h.Service.Delete(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	// to response id and success boolean
+	jsonSuccessResultId := response.JSONSuccessResultId{
+		ID:      query,
+		Success: result,
+	}
+
+	h.Logger.Infof("{%v} with id author is deleted.", jsonSuccessResultId.ID)
+	return c.JSON(http.StatusOK, jsonSuccessResultId)
+}
+
+// GetAuthorBooks => To get request for listing books of an author
+
+// GetAuthorBooks godoc
+// @Summary get books of an author with pagination, sorting and filtering like /books
+// @ID get-author-books
+// @Produce json
+// @Param id path string true "author ID"
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Param sort query string false "sort fields, e.g. title,-createddate"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /authors/{id}/books [get]
+func (h AuthorHandler) GetAuthorBooks(c echo.Context) error {
+	id := c.Param("id")
+
+	query, err := parseBookQuery(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	bookList, total, err := h.Service.GetBooks(c.Request().Context(), id, query)
+
+	if err != nil {
+		return err
+	}
+
+	booksResponse := make([]dtos.BookResponse, 0, len(bookList))
+	for _, book := range bookList {
+		booksResponse = append(booksResponse, toBookResponse(book))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           booksResponse,
+		Pagination:     newPagination(query.Skip, query.Limit, len(booksResponse), total),
+	}
+
+	h.Logger.Infof("Books of {%v} with id author are listed.", id)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// toAuthorResponse => mapping from model to response dto
+func toAuthorResponse(author models.Author) dtos.AuthorResponse {
+	return dtos.AuthorResponse{
+		ID:   author.ID,
+		Name: author.Name,
+	}
+}
@@ -0,0 +1,255 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/response"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// maxBulkSize => max item count of a bulk request, bigger batches must be split by the client
+const maxBulkSize = 1000
+
+// errInvalidBulkSize => item count of a bulk request is out of its limits
+var errInvalidBulkSize = errors.New(errors.Invalid, "invalid_bulk_size", "invalid item count")
+
+// errRequiredID => an item of a bulk delete request has no id
+var errRequiredID = errors.New(errors.Invalid, "id_required", "id is required")
+
+// BulkCreateBooks => To post request for creating many books at once, e.g. catalog sync
+
+// BulkCreateBooks godoc
+// @Summary add many items to the book list, a failed item doesn't stop the others
+// @ID bulk-create-books
+// @Produce json
+// @Param data body []dtos.BookCreateRequest true "book data, at most 1000 items"
+// @Param Accept-Language header string false "language of the validation messages, en (default) or tr"
+// @Success 200 {object} response.JSONSuccessResultData "data => []dtos.BulkItemResponse, status 201 for created items"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/bulk [post]
+func (h BookHandler) BulkCreateBooks(c echo.Context) error {
+	var bookRequests []dtos.BookCreateRequest
+
+	// We parse the data as json into the slice
+	if err := c.Bind(&bookRequests); err != nil {
+		return err
+	}
+
+	if err := checkBulkSize(len(bookRequests)); err != nil {
+		return err
+	}
+
+	items := make([]dtos.BulkItemResponse, len(bookRequests))
+	var books []models.Book
+	var positions []int
+
+	for i, bookRequest := range bookRequests {
+		if !validBulkItem(c, bookRequest, i, &items[i]) {
+			continue
+		}
+		books = append(books, toBook(bookRequest))
+		positions = append(positions, i)
+	}
+
+	results, err := h.Service.BulkInsert(c.Request().Context(), books)
+
+	return h.bulkResponse(c, items, positions, results, err, http.StatusCreated)
+}
+
+// BulkUpsertBooks => To post request for creating or changing many books by their ids at once
+
+// BulkUpsertBooks godoc
+// @Summary create or change many books by their ids, versions are not checked, a failed item doesn't stop the others
+// @ID bulk-upsert-books
+// @Produce json
+// @Param data body []dtos.BookUpsertRequest true "book data with ids, at most 1000 items"
+// @Param Accept-Language header string false "language of the validation messages, en (default) or tr"
+// @Success 200 {object} response.JSONSuccessResultData "data => []dtos.BulkItemResponse, status 201 for created, 200 for changed items"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/bulk/upsert [post]
+func (h BookHandler) BulkUpsertBooks(c echo.Context) error {
+	var bookRequests []dtos.BookUpsertRequest
+
+	// We parse the data as json into the slice
+	if err := c.Bind(&bookRequests); err != nil {
+		return err
+	}
+
+	if err := checkBulkSize(len(bookRequests)); err != nil {
+		return err
+	}
+
+	items := make([]dtos.BulkItemResponse, len(bookRequests))
+	var books []models.Book
+	var positions []int
+
+	for i, bookRequest := range bookRequests {
+		items[i].ID = bookRequest.ID
+		if !validBulkItem(c, bookRequest, i, &items[i]) {
+			continue
+		}
+		book := toBook(bookRequest.BookCreateRequest)
+		book.ID = bookRequest.ID
+		books = append(books, book)
+		positions = append(positions, i)
+	}
+
+	results, err := h.Service.BulkUpsert(c.Request().Context(), books)
+
+	return h.bulkResponse(c, items, positions, results, err, http.StatusOK)
+}
+
+// BulkDeleteBooks => To post request for moving many books into trash at once
+
+// BulkDeleteBooks godoc
+// @Summary move many books into trash by their ids, a failed item doesn't stop the others
+// @ID bulk-delete-books
+// @Produce json
+// @Param data body []string true "book ids, at most 1000 items"
+// @Success 200 {object} response.JSONSuccessResultData "data => []dtos.BulkItemResponse, status 404 for unknown ids"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/bulk/delete [post]
+func (h BookHandler) BulkDeleteBooks(c echo.Context) error {
+	var ids []string
+
+	// We parse the data as json into the slice
+	if err := c.Bind(&ids); err != nil {
+		return err
+	}
+
+	if err := checkBulkSize(len(ids)); err != nil {
+		return err
+	}
+
+	items := make([]dtos.BulkItemResponse, len(ids))
+	var valid []string
+	var positions []int
+
+	for i, id := range ids {
+		if id == "" {
+			items[i].Index = i
+			setItemProblem(c, &items[i], errRequiredID)
+			continue
+		}
+		valid = append(valid, id)
+		positions = append(positions, i)
+	}
+
+	var results []models.BulkResult
+	var err error
+	if len(valid) > 0 {
+		results, err = h.Service.BulkDelete(c.Request().Context(), valid)
+	}
+
+	return h.bulkResponse(c, items, positions, results, err, http.StatusOK)
+}
+
+// checkBulkSize => a bulk request has at least one and at most maxBulkSize items
+func checkBulkSize(size int) error {
+	if size < 1 || size > maxBulkSize {
+		return errInvalidBulkSize.Withf("item count must be between 1 and %d", maxBulkSize)
+	}
+	return nil
+}
+
+// validBulkItem => to validate an item of a bulk request, item response is filled with its validation errors
+func validBulkItem(c echo.Context, request interface{}, index int, item *dtos.BulkItemResponse) bool {
+	item.Index = index
+
+	err := c.Validate(request)
+
+	if err == nil {
+		return true
+	}
+
+	setItemProblem(c, item, err)
+
+	return false
+}
+
+// bulkResponse => results of the service are put into the item responses by their positions in the request
+// => response is 200 even if some items failed, status of every item is in its response
+func (h BookHandler) bulkResponse(c echo.Context, items []dtos.BulkItemResponse, positions []int, results []models.BulkResult,
+	err error, successStatus int) error {
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		items[positions[result.Index]] = h.toBulkItemResponse(c, positions[result.Index], result, successStatus)
+	}
+	for _, item := range items {
+		if item.Status >= http.StatusBadRequest {
+			failed++
+		}
+	}
+
+	h.Logger.Infof("Bulk request is done, %v of %v items failed.", failed, len(items))
+	return c.JSON(http.StatusOK, response.JSONSuccessResultData{
+		TotalItemCount: len(items),
+		Data:           items,
+	})
+}
+
+// toBulkItemResponse => errors of the items are mapped like the problems of the single item handlers
+func (h BookHandler) toBulkItemResponse(c echo.Context, index int, result models.BulkResult, successStatus int) dtos.BulkItemResponse {
+	item := dtos.BulkItemResponse{Index: index, ID: result.ID, Status: successStatus}
+
+	if result.Err == nil {
+		if result.Created {
+			item.Status = http.StatusCreated
+		}
+		return item
+	}
+
+	if setItemProblem(c, &item, result.Err) >= http.StatusInternalServerError {
+		h.Logger.Errorf("StatusInternalServerError: {%v} with id: %v", result.ID, result.Err.Error())
+	}
+
+	return item
+}
+
+// setItemProblem => status, code, message & field errors of a failed item, its status is returned
+func setItemProblem(c echo.Context, item *dtos.BulkItemResponse, err error) int {
+	problem := toProblem(err, translatorOf(c))
+
+	item.Status = problem.Status
+	item.Code = problem.Code
+	item.Message = problem.Detail
+	item.Errors = problem.Errors
+
+	return problem.Status
+}
+
+// toBook => mapping from request dto to model
+func toBook(bookRequest dtos.BookCreateRequest) models.Book {
+	// we can use automapper, but it will cause performance loss.
+	return models.Book{
+		Title:     bookRequest.Title,
+		Quantity:  bookRequest.Quantity,
+		Author:    bookRequest.Author,
+		AuthorIDs: bookRequest.AuthorIDs,
+		ISBN10:    bookRequest.ISBN10,
+		ISBN13:    bookRequest.ISBN13,
+		Category:  bookRequest.Category,
+		Tags:      bookRequest.Tags,
+	}
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"RestfulWithEcho/export"
+	"RestfulWithEcho/models"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// exportColumns => columns which can be exported, in their default order
+var exportColumns = []string{
+	"id", "title", "author", "authorids", "isbn10", "isbn13", "category", "tags",
+	"quantity", "reserved", "available", "version", "createddate", "updateddate",
+}
+
+// exportFlushRows => streamed rows are flushed to the client after this many rows
+const exportFlushRows = 500
+
+// ExportBooks => To get request for downloading the books with the same filters as the list
+
+// ExportBooks godoc
+// @Summary export the filtered books as csv, ndjson or xlsx, they are streamed from the database
+// @ID export-books
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (default), ndjson or xlsx"
+// @Param columns query string false "columns in order, e.g. title,isbn13,quantity; all columns by default"
+// @Param sort query string false "sort fields, e.g. title,-createddate"
+// @Param author query string false "author filter, also the other filters of the list"
+// @Success 200 {string} string "export file, dates are RFC 3339 in UTC"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/export [get]
+func (h BookHandler) ExportBooks(c echo.Context) error {
+	params := c.QueryParams()
+
+	format := params.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	var query models.BookQuery
+	columns, err := parseColumns(params)
+
+	if err == nil && export.ContentType(format) == "" {
+		err = export.ErrUnknownFormat
+	}
+	if err == nil {
+		query.Sort, err = parseSort(params.Get("sort"))
+	}
+	if err == nil {
+		query.Filters, err = parseFilters(params)
+	}
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, export.ContentType(format))
+	response.Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=\"books-%v.%v\"", time.Now().UTC().Format("20060102"), format))
+	response.WriteHeader(http.StatusOK)
+
+	// status is already sent, so an error after this point can only be logged and the file is left incomplete
+	writer, err := export.NewWriter(response, format, columns)
+
+	count := 0
+	if err == nil {
+		err = h.Service.Export(c.Request().Context(), query, func(book models.Book) error {
+			values := make([]interface{}, 0, len(columns))
+			for _, column := range columns {
+				values = append(values, bookColumn(book, column))
+			}
+
+			if count++; count%exportFlushRows == 0 {
+				response.Flush()
+			}
+			return writer.Write(values)
+		})
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		h.Logger.Errorf("Export is stopped after %v books: %v", count, err.Error())
+		return nil
+	}
+
+	h.Logger.Infof("%v books are exported as %v.", count, format)
+	return nil
+}
+
+// parseColumns => "title,isbn13" => selected columns in the given order, all columns if it is empty
+func parseColumns(params url.Values) ([]string, error) {
+	raw := params.Get("columns")
+	if raw == "" {
+		return exportColumns, nil
+	}
+
+	known := map[string]bool{}
+	for _, column := range exportColumns {
+		known[column] = true
+	}
+
+	var columns []string
+	for _, column := range strings.Split(raw, ",") {
+		column = strings.TrimSpace(column)
+		if !known[column] {
+			return nil, fmt.Errorf("{%v} cannot be exported, columns can be %v", column, strings.Join(exportColumns, ", "))
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// bookColumn => value of a column, dates are time.Time, so every format writes them the same way
+func bookColumn(book models.Book, column string) interface{} {
+	switch column {
+	case "id":
+		return book.ID
+	case "title":
+		return book.Title
+	case "author":
+		return book.Author
+	case "authorids":
+		return book.AuthorIDs
+	case "isbn10":
+		return book.ISBN10
+	case "isbn13":
+		return book.ISBN13
+	case "category":
+		return book.Category
+	case "tags":
+		return book.Tags
+	case "quantity":
+		return book.Quantity
+	case "reserved":
+		return book.Reserved
+	case "available":
+		return book.Quantity - book.Reserved
+	case "version":
+		return book.Version
+	case "createddate":
+		return book.CreatedDate.Time()
+	case "updateddate":
+		return book.UpdatedDate.Time()
+	}
+	return nil
+}
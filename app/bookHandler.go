@@ -3,15 +3,20 @@ package app
 import (
 	"RestfulWithEcho/dtos"
 	"RestfulWithEcho/errors"
+	"RestfulWithEcho/isbn"
 	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
 	"RestfulWithEcho/response"
 	"RestfulWithEcho/service"
 	"fmt"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/mongo"
+	"io"
 	"net/http"
+	"reflect"
+	"strings"
 )
 
 type BookHandler struct {
@@ -21,7 +26,8 @@ type BookHandler struct {
 
 // BookValidator echo validator for books
 type BookValidator struct {
-	validator *validator.Validate
+	validator  *validator.Validate
+	translator *ut.UniversalTranslator
 }
 
 // Validate validates books request body
@@ -29,8 +35,44 @@ func (b *BookValidator) Validate(i interface{}) error {
 	return b.validator.Struct(i)
 }
 
-// for validation
-var v = validator.New()
+// NewBookValidator => validator of the book dtos with their custom tags, e.g. for imports outside of http
+func NewBookValidator() (*BookValidator, error) {
+	v := validator.New()
+
+	// errors of the fields are responded with their json names
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	if err := registerBookValidations(v); err != nil {
+		return nil, err
+	}
+
+	translator, err := registerTranslations(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BookValidator{validator: v, translator: translator}, nil
+}
+
+// registerBookValidations => custom tags of the book dtos
+// => isbn10 & isbn13 replace the tags of the validator package, hyphens and spaces are normalized like they are stored
+func registerBookValidations(v *validator.Validate) error {
+	if err := v.RegisterValidation("isbn10", func(fl validator.FieldLevel) bool {
+		return isbn.Valid10(isbn.Normalize(fl.Field().String()))
+	}); err != nil {
+		return err
+	}
+
+	return v.RegisterValidation("isbn13", func(fl validator.FieldLevel) bool {
+		return isbn.Valid13(isbn.Normalize(fl.Field().String()))
+	})
+}
 
 func NewBookHandler(e *echo.Echo, service service.IBookService, log *logrus.Logger) *BookHandler {
 
@@ -43,56 +85,362 @@ func NewBookHandler(e *echo.Echo, service service.IBookService, log *logrus.Logg
 	router := e.Group("api/books")
 	b := &BookHandler{Service: service, Logger: log}
 
-	e.Validator = &BookValidator{validator: v}
+	// for validation
+	bookValidator, err := NewBookValidator()
+	if err != nil {
+		log.Fatal(err)
+	}
+	e.Validator = bookValidator
+
+	// policies of the routes => read for viewers, write for editors, delete for admins
+	// => quantity can be set on create, changing it later needs rbac.ChangeStock (checked by the handlers)
+	read := Authorize(rbac.ReadBooks)
+	write := Authorize(rbac.WriteBooks)
+	remove := Authorize(rbac.DeleteBooks)
 
 	//Routes
-	router.GET("", b.GetAllBooks)
-	router.GET("/:id", b.GetBookById)
-	router.POST("", b.CreateBook)
-	router.PUT("", b.UpdateBook)
-	router.DELETE("/:id", b.DeleteBook)
+	router.GET("", b.GetAllBooks, read)
+	router.GET("/search", b.SearchBooks, read)
+	router.GET("/facets", b.GetBookFacets, read)
+	router.GET("/export", b.ExportBooks, read)
+	router.GET("/isbn/:isbn", b.GetBookByISBN, read)
+	router.GET("/:id", b.GetBookById, read)
+	router.POST("", b.CreateBook, write)
+	router.PUT("", b.UpdateBook, write)
+	router.PATCH("/:id", b.PatchBook, write)
+	router.DELETE("/:id", b.DeleteBook, remove)
+	router.POST("/bulk", b.BulkCreateBooks, write)
+	// upsert can change quantities of the existing books
+	router.POST("/bulk/upsert", b.BulkUpsertBooks, Authorize(rbac.WriteBooks, rbac.ChangeStock))
+	router.POST("/bulk/delete", b.BulkDeleteBooks, remove)
+	router.GET("/trash", b.GetTrash, read)
+	router.POST("/:id/restore", b.RestoreBook, remove)
+
+	// admin routes
+	admin := e.Group("api/admin")
+	admin.POST("/books/purge", b.PurgeTrash, remove)
 
 	return b
 }
 
-// GetAllBooks => To get request for listing all of books
+// GetAllBooks => To get request for listing books page by page with sorting and filtering
 
 // GetAllBooks godoc
-// @Summary get all items in the book list
+// @Summary get items in the book list with pagination, sorting and filtering
 // @ID get-all-books
 // @Produce json
-// @Success 200 {array} response.JSONSuccessResultData
-// @Success 500 {object} errors.InternalServerError
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Param limit query int false "alternative to pageSize"
+// @Param offset query int false "alternative to page"
+// @Param sort query string false "sort fields, e.g. title,-createddate"
+// @Param author query string false "author filter, also author[like]=..."
+// @Param title query string false "title filter, also title[like]=..."
+// @Param quantity[gte] query int false "quantity filter, operators: eq, ne, gt, gte, lt, lte, in"
+// @Param cursor query string false "keyset pagination mode, empty for the first page then next_cursor of the previous response"
+// @Param authorids query string false "books of an author"
+// @Param expand query string false "authors => to embed the authors of the books"
+// @Param category query string false "books of a category and its sub categories, e.g. Fiction/Fantasy"
+// @Param tag query string false "books with a tag, repeat it for books with all of the tags"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /books [get]
 func (h BookHandler) GetAllBooks(c echo.Context) error {
-	bookList, err := h.Service.GetAll()
+	if c.QueryParams().Has("cursor") {
+		return h.getAllBooksByCursor(c)
+	}
+
+	query, err := parseBookQuery(c.QueryParams())
 
 	if err != nil {
-		h.Logger.Errorf("StatusInternalServerError: %v", err.Error())
-		return c.JSON(http.StatusInternalServerError, errors.InternalServerError{
-			Message: "Something went wrong!",
-		})
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	expand, err := parseExpand(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	bookList, total, err := h.Service.GetAll(c.Request().Context(), query)
+
+	if err != nil {
+		return err
 	}
 
 	// we can use automapper, but it will cause performance loss.
-	var bookResponse dtos.BookResponse
-	var booksResponse []dtos.BookResponse
+	booksResponse, err := h.toBookResponses(c, bookList, expand)
 
-	for _, book := range bookList {
-		bookResponse.ID = book.ID
-		bookResponse.Title = book.Title
-		bookResponse.Quantity = book.Quantity
-		bookResponse.Author = book.Author
-		booksResponse = append(booksResponse, bookResponse)
+	if err != nil {
+		return err
 	}
 
-	// to response success result data
+	// to response success result data with pagination metadata
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           booksResponse,
+		Pagination:     newPagination(query.Skip, query.Limit, len(booksResponse), total),
+	}
+
+	h.Logger.Infof("%v of %v books are listed.", len(booksResponse), total)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// getAllBooksByCursor => GetAllBooks in keyset pagination mode (?cursor=)
+func (h BookHandler) getAllBooksByCursor(c echo.Context) error {
+	params := c.QueryParams()
+
+	if err := validateCursorQuery(params); err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	query, err := parseBookQuery(params)
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	expand, err := parseExpand(params)
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	bookList, nextCursor, err := h.Service.GetAllByCursor(c.Request().Context(), query, params.Get("cursor"))
+
+	if err != nil {
+		return err
+	}
+
+	booksResponse, err := h.toBookResponses(c, bookList, expand)
+
+	if err != nil {
+		return err
+	}
+
+	// total count is not calculated in cursor mode => TotalItemCount is the count of this page
 	jsonSuccessResultData := response.JSONSuccessResultData{
 		TotalItemCount: len(booksResponse),
 		Data:           booksResponse,
+		Cursor: &response.Cursor{
+			PageSize:   int(query.Limit),
+			NextCursor: nextCursor,
+			HasNext:    nextCursor != "",
+		},
 	}
 
-	h.Logger.Info("All books are listed.")
+	h.Logger.Infof("%v books are listed by cursor.", len(booksResponse))
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// toBookResponses => mapping from models to response dtos, authors are embedded if they are expanded
+func (h BookHandler) toBookResponses(c echo.Context, books []models.Book, expand map[string]bool) ([]dtos.BookResponse, error) {
+	var authors map[string]models.Author
+
+	if expand["authors"] {
+		var err error
+		if authors, err = h.Service.GetAuthors(c.Request().Context(), books); err != nil {
+			return nil, err
+		}
+	}
+
+	booksResponse := make([]dtos.BookResponse, 0, len(books))
+	for _, book := range books {
+		bookResponse := toBookResponse(book)
+		if authors != nil {
+			bookResponse.Authors = toBookAuthors(book, authors)
+		}
+		booksResponse = append(booksResponse, bookResponse)
+	}
+
+	return booksResponse, nil
+}
+
+// toBookAuthors => authors of a book in the order of its authorids, deleted authors are skipped
+func toBookAuthors(book models.Book, authors map[string]models.Author) []dtos.AuthorResponse {
+	authorsResponse := make([]dtos.AuthorResponse, 0, len(book.AuthorIDs))
+	for _, id := range book.AuthorIDs {
+		if author, ok := authors[id]; ok {
+			authorsResponse = append(authorsResponse, toAuthorResponse(author))
+		}
+	}
+	return authorsResponse
+}
+
+// toBookResponse => mapping from model to response dto
+func toBookResponse(book models.Book) dtos.BookResponse {
+	bookResponse := dtos.BookResponse{
+		ID:        book.ID,
+		Title:     book.Title,
+		Author:    book.Author,
+		AuthorIDs: book.AuthorIDs,
+		ISBN10:    book.ISBN10,
+		ISBN13:    book.ISBN13,
+		Category:  book.Category,
+		Tags:      book.Tags,
+		Quantity:  book.Quantity,
+		Version:   book.Version,
+		Reserved:  book.Reserved,
+		Available: book.Quantity - book.Reserved,
+	}
+	if book.DeletedAt != nil {
+		deletedAt := book.DeletedAt.Time()
+		bookResponse.DeletedAt = &deletedAt
+	}
+
+	return bookResponse
+}
+
+// SearchBooks => To get request for full-text search over title and author
+
+// SearchBooks godoc
+// @Summary search books by title and author, ordered by relevance
+// @ID search-books
+// @Produce json
+// @Param q query string true "search text"
+// @Param limit query int false "max result count (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/search [get]
+func (h BookHandler) SearchBooks(c echo.Context) error {
+	text := strings.TrimSpace(c.QueryParam("q"))
+	limit, err := intParam(c.QueryParams(), "limit", defaultPageSize)
+
+	if err == nil && (limit < 1 || limit > maxPageSize) {
+		err = fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	if err == nil && text == "" {
+		err = fmt.Errorf("q is required")
+	}
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	results, err := h.Service.Search(c.Request().Context(), text, int64(limit))
+
+	if err != nil {
+		return err
+	}
+
+	searchResponse := make([]dtos.BookSearchResponse, 0, len(results))
+	for _, result := range results {
+		searchResponse = append(searchResponse, dtos.BookSearchResponse{
+			BookResponse: toBookResponse(result.Book),
+			Score:        result.Score,
+			Highlights:   result.Highlights,
+		})
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: len(searchResponse),
+		Data:           searchResponse,
+	}
+
+	h.Logger.Infof("%v books are found for {%v}.", len(searchResponse), text)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetBookFacets => To get request for the book counts per category, tag and author, e.g. for the filters of a catalog page
+
+// GetBookFacets godoc
+// @Summary get book counts per category, tag and author of the filtered books
+// @ID get-book-facets
+// @Produce json
+// @Param limit query int false "max value count of each facet (max 100)"
+// @Param category query string false "books of a category and its sub categories"
+// @Param tag query string false "books with a tag"
+// @Success 200 {object} dtos.BookFacetsResponse
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/facets [get]
+func (h BookHandler) GetBookFacets(c echo.Context) error {
+	var query models.BookQuery
+	limit, err := intParam(c.QueryParams(), "limit", defaultPageSize)
+
+	if err == nil && (limit < 1 || limit > maxPageSize) {
+		err = fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	if err == nil {
+		query.Filters, err = parseFilters(c.QueryParams())
+	}
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	facets, err := h.Service.Facets(c.Request().Context(), query, int64(limit))
+
+	if err != nil {
+		return err
+	}
+
+	h.Logger.Info("Book facets are listed.")
+	return c.JSON(http.StatusOK, dtos.BookFacetsResponse{
+		Categories: toFacetCountResponses(facets.Categories),
+		Tags:       toFacetCountResponses(facets.Tags),
+		Authors:    toFacetCountResponses(facets.Authors),
+	})
+}
+
+// toFacetCountResponses => mapping from model to response dto, never null in json
+func toFacetCountResponses(counts []models.FacetCount) []dtos.FacetCountResponse {
+	countsResponse := make([]dtos.FacetCountResponse, 0, len(counts))
+	for _, count := range counts {
+		countsResponse = append(countsResponse, dtos.FacetCountResponse{
+			Value: count.Value,
+			Name:  count.Name,
+			Count: count.Count,
+		})
+	}
+
+	return countsResponse
+}
+
+// GetBookByISBN => To get request find a book by ISBN-10 or ISBN-13, e.g. a scanned barcode
+
+// GetBookByISBN godoc
+// @Summary get a book item by ISBN-10 or ISBN-13, hyphens are allowed
+// @ID get-book-by-isbn
+// @Produce json
+// @Param isbn path string true "ISBN-10 or ISBN-13"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/isbn/{isbn} [get]
+func (h BookHandler) GetBookByISBN(c echo.Context) error {
+	query := c.Param("isbn")
+
+	book, err := h.Service.GetBookByISBN(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toBookResponse(book),
+	}
+
+	c.Response().Header().Set(headerETag, etag(book.Version))
+	h.Logger.Infof("{%v} with isbn is listed.", query)
 	return c.JSON(http.StatusOK, jsonSuccessResultData)
 }
 
@@ -103,34 +451,47 @@ func (h BookHandler) GetAllBooks(c echo.Context) error {
 // @ID get-book-by-id
 // @Produce json
 // @Param id path string true "book ID"
+// @Param expand query string false "authors => to embed the authors of the book"
+// @Param If-None-Match header string false "ETag of the cached book"
 // @Success 200 {object} response.JSONSuccessResultData
-// @Success 404 {object} errors.NotFoundError
-// @Success 500 {object} errors.InternalServerError
+// @Success 304 "book is not changed"
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /books/{id} [get]
 func (h BookHandler) GetBookById(c echo.Context) error {
 	query := c.Param("id")
 
-	book, err := h.Service.GetBookById(query)
+	expand, err := parseExpand(c.QueryParams())
 
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			h.Logger.Errorf("Not found exception: {%v} with id not found!", query)
-			return c.JSON(http.StatusNotFound, errors.NotFoundError{
-				Message: fmt.Sprintf("Not found exception: {%v} with id not found!", query),
-			})
-		}
-		h.Logger.Errorf("StatusInternalServerError: %v", err.Error())
-		return c.JSON(http.StatusInternalServerError, errors.InternalServerError{
-			Message: "Something went wrong!",
-		})
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	book, err := h.Service.GetBookById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	// version of the book is the ETag => client cache is still valid if it is the same
+	tag := etag(book.Version)
+	c.Response().Header().Set(headerETag, tag)
+
+	if ifNoneMatch := c.Request().Header.Get(headerIfNoneMatch); ifNoneMatch != "" && matchesIfNoneMatch(ifNoneMatch, tag) {
+		return c.NoContent(http.StatusNotModified)
 	}
 
 	// mapping
-	var bookResponse dtos.BookResponse
-	bookResponse.ID = book.ID
-	bookResponse.Title = book.Title
-	bookResponse.Author = book.Author
-	bookResponse.Quantity = book.Quantity
+	booksResponse, err := h.toBookResponses(c, []models.Book{book}, expand)
+
+	if err != nil {
+		return err
+	}
+	bookResponse := booksResponse[0]
 
 	// to response success result data => single one
 	jsonSuccessResultData := response.JSONSuccessResultData{
@@ -147,11 +508,19 @@ func (h BookHandler) GetBookById(c echo.Context) error {
 // CreateBook godoc
 // @Summary add a new item to the book list
 // @ID create-book
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
 // @Param data body dtos.BookCreateRequest true "book data"
+// @Param Accept-Language header string false "language of the validation messages, en (default) or tr"
 // @Success 201 {object} response.JSONSuccessResultId
-// @Success 400 {object} errors.BadRequestError
-// @Success 500 {object} errors.InternalServerError
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 415 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /books [post]
 func (h BookHandler) CreateBook(c echo.Context) error {
 
@@ -159,33 +528,17 @@ func (h BookHandler) CreateBook(c echo.Context) error {
 
 	// We parse the data as json into the struct
 	if err := c.Bind(&bookRequest); err != nil {
-		h.Logger.Errorf("Bad Request. It cannot be binding! %v", err.Error())
-		return c.JSON(http.StatusBadRequest, errors.BadRequestError{
-			Message: fmt.Sprintf("Bad Request. It cannot be binding! %v", err.Error()),
-		})
+		return err
 	}
 
 	if err := c.Validate(bookRequest); err != nil {
-		h.Logger.Errorf("Bad Request! %v", err.Error())
-		return c.JSON(http.StatusBadRequest, errors.BadRequestError{
-			Message: fmt.Sprintf("Bad Request! %v", err.Error()),
-		})
+		return err
 	}
 
-	var book models.Book
-
-	// we can use automapper, but it will cause performance loss.
-	book.Title = bookRequest.Title
-	book.Quantity = bookRequest.Quantity
-	book.Author = bookRequest.Author
-
-	result, err := h.Service.Insert(book)
+	result, err := h.Service.Insert(c.Request().Context(), toBook(bookRequest))
 
 	if err != nil {
-		h.Logger.Errorf("StatusInternalServerError: %v", err.Error())
-		return c.JSON(http.StatusInternalServerError, &errors.InternalServerError{
-			Message: "Book cannot create! Something went wrong.",
-		})
+		return err
 	}
 
 	// to response id and success boolean
@@ -194,6 +547,7 @@ func (h BookHandler) CreateBook(c echo.Context) error {
 		Success: true,
 	}
 
+	c.Response().Header().Set(headerETag, etag(result.Version))
 	h.Logger.Infof("{%v} with id is created.", jsonSuccessResultId.ID)
 	return c.JSON(http.StatusCreated, jsonSuccessResultId)
 }
@@ -203,11 +557,22 @@ func (h BookHandler) CreateBook(c echo.Context) error {
 // UpdateBook godoc
 // @Summary update an item to the book list
 // @ID update-book
-// @Produce json
+// @Accept json,xml,application/msgpack
+// @Produce json,xml,application/msgpack
 // @Param data body dtos.BookUpdateRequest true "book data"
+// @Param If-Match header string false "ETag of the book which is being changed"
+// @Param Accept-Language header string false "language of the validation messages, en (default) or tr"
 // @Success 200 {object} response.JSONSuccessResultId
-// @Success 400 {object} errors.BadRequestError
-// @Success 500 {object} errors.InternalServerError
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 412 {object} errors.Problem
+// @Success 415 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /books [put]
 func (h BookHandler) UpdateBook(c echo.Context) error {
 
@@ -215,25 +580,29 @@ func (h BookHandler) UpdateBook(c echo.Context) error {
 
 	// we parse the data as json into the struct
 	if err := c.Bind(&bookUpdateRequest); err != nil {
-		h.Logger.Errorf("Bad Request! %v", err)
-		return c.JSON(http.StatusBadRequest, errors.BadRequestError{
-			Message: fmt.Sprintf("Bad Request. It cannot be binding! %v", err.Error()),
-		})
+		return err
 	}
 
 	// validation
 	if err := c.Validate(bookUpdateRequest); err != nil {
-		h.Logger.Errorf("Bad Request! %v", err)
-		return c.JSON(http.StatusBadRequest, errors.BadRequestError{
-			Message: fmt.Sprintf("Bad Request! %v", err.Error()),
-		})
+		return err
 	}
 
-	if _, err := h.Service.GetBookById(bookUpdateRequest.ID); err != nil {
-		h.Logger.Errorf("Not found exception: {%v} with id not found!", bookUpdateRequest.ID)
-		return c.JSON(http.StatusNotFound, errors.NotFoundError{
-			Message: fmt.Sprintf("Not found exception: {%v} with id not found!", bookUpdateRequest.ID),
-		})
+	// If-Match => the book is updated only if it is still at the version which client has
+	version, err := parseIfMatch(c.Request().Header.Get(headerIfMatch))
+	if err != nil {
+		return errInvalidHeader.Withf("%v", err)
+	}
+
+	current, err := h.Service.GetBookById(c.Request().Context(), bookUpdateRequest.ID)
+	if err != nil {
+		return err
+	}
+
+	if current.Quantity != bookUpdateRequest.Quantity {
+		if err := authorize(c, rbac.ChangeStock); err != nil {
+			return err
+		}
 	}
 
 	var book models.Book
@@ -243,46 +612,150 @@ func (h BookHandler) UpdateBook(c echo.Context) error {
 	book.Title = bookUpdateRequest.Title
 	book.Quantity = bookUpdateRequest.Quantity
 	book.Author = bookUpdateRequest.Author
+	book.AuthorIDs = bookUpdateRequest.AuthorIDs
+	book.ISBN10 = bookUpdateRequest.ISBN10
+	book.ISBN13 = bookUpdateRequest.ISBN13
+	book.Category = bookUpdateRequest.Category
+	book.Tags = bookUpdateRequest.Tags
+	book.Version = version
+	// quantity is authorized against the read version, so without If-Match the write is pinned to it too,
+	// a concurrent stock change makes it fail instead of being overwritten
+	if book.Version == 0 {
+		book.Version = current.Version
+	}
 
-	result, err := h.Service.Update(book)
+	result, err := h.Service.Update(c.Request().Context(), book)
 
-	if err != nil || result == false {
-		h.Logger.Errorf("StatusInternalServerError: {%v} ", err.Error())
-		return c.JSON(http.StatusInternalServerError, &errors.InternalServerError{
-			Message: "Book cannot create! Something went wrong.",
-		})
+	if version == 0 && errors.Is(err, service.ErrVersionMismatch) {
+		// client didn't send a version, so the conflict is not a failed precondition of it
+		return service.ErrConcurrentChange.Withf("{%v} with id book is changed by somebody else, try again", book.ID)
+	}
+	if err != nil {
+		return err
 	}
 
 	// to response id and success boolean
 	jsonSuccessResultId := response.JSONSuccessResultId{
-		ID:      book.ID,
-		Success: result,
+		ID:      result.ID,
+		Success: true,
 	}
 
+	c.Response().Header().Set(headerETag, etag(result.Version))
+
 	h.Logger.Infof("{%v} with id is updated.", jsonSuccessResultId.ID)
 	return c.JSON(http.StatusOK, jsonSuccessResultId)
 }
 
-// DeleteBook => To delete request by id as a parameter
+// PatchBook => To patch request for changing some fields of exist book
+
+// PatchBook godoc
+// @Summary change some fields of a book item by JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// @ID patch-book
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "book ID"
+// @Param If-Match header string false "ETag of the book which is being changed"
+// @Param Accept-Language header string false "language of the validation messages, en (default) or tr"
+// @Success 200 {object} response.JSONSuccessResultId
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 412 {object} errors.Problem
+// @Success 415 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id} [patch]
+func (h BookHandler) PatchBook(c echo.Context) error {
+	query := c.Param("id")
+
+	version, err := parseIfMatch(c.Request().Header.Get(headerIfMatch))
+	if err != nil {
+		return errInvalidHeader.Withf("%v", err)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return errInvalidBody.Withf("%v", err)
+	}
+
+	book, err := h.Service.GetBookById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	if version > 0 && version != book.Version {
+		return service.ErrVersionMismatch.Withf("{%v} with id book is changed after version %v", query, version)
+	}
+
+	patched, err := applyBookPatch(book, c.Request().Header.Get(echo.HeaderContentType), body)
+
+	if err != nil {
+		return err
+	}
+
+	// patched book must still be valid with the same rules
+	if err := c.Validate(patched); err != nil {
+		return err
+	}
+
+	fields := changedBookFields(book, patched)
+
+	if _, ok := fields["quantity"]; ok {
+		if err := authorize(c, rbac.ChangeStock); err != nil {
+			return err
+		}
+	}
+
+	if len(fields) > 0 {
+		// the version which was read is used even without If-Match, so a change in between is not overwritten
+		book, err = h.Service.Patch(c.Request().Context(), query, book.Version, fields)
+
+		// with If-Match the client asked for its version, so a change in between is a failed precondition
+		if version > 0 && errors.Is(err, service.ErrConcurrentChange) {
+			return service.ErrVersionMismatch.Withf("{%v} with id book is changed after version %v", query, version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// to response id and success boolean
+	jsonSuccessResultId := response.JSONSuccessResultId{
+		ID:      book.ID,
+		Success: true,
+	}
+
+	c.Response().Header().Set(headerETag, etag(book.Version))
+	h.Logger.Infof("{%v} with id is patched, %v fields are changed.", jsonSuccessResultId.ID, len(fields))
+	return c.JSON(http.StatusOK, jsonSuccessResultId)
+}
+
+// DeleteBook => To delete request by id as a parameter, book is moved into trash
 
 // DeleteBook godoc
-// @Summary delete a book item by ID
+// @Summary move a book item into trash by ID
 // @ID delete-book-by-id
 // @Produce json
 // @Param id path string true "book ID"
 // @Success 200 {object} response.JSONSuccessResultId
-// @Success 404 {object} errors.NotFoundError
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /books/{id} [delete]
 func (h BookHandler) DeleteBook(c echo.Context) error {
 	query := c.Param("id")
 
-	result, err := h.Service.Delete(query)
+	result, err := h.Service.Delete(c.Request().Context(), query)
 
-	if err != nil || result == false {
-		h.Logger.Errorf("Not found exception: {%v} with id not found!", query)
-		return c.JSON(http.StatusNotFound, errors.NotFoundError{
-			Message: fmt.Sprintf("Not found exception: {%v} with id not found!", query),
-		})
+	if err != nil {
+		return err
 	}
 
 	// to response id and success boolean
@@ -294,3 +767,109 @@ func (h BookHandler) DeleteBook(c echo.Context) error {
 	h.Logger.Infof("{%v} with id is deleted.", jsonSuccessResultId.ID)
 	return c.JSON(http.StatusOK, jsonSuccessResultId)
 }
+
+// GetTrash => To get request for listing deleted books which are not purged yet
+
+// GetTrash godoc
+// @Summary get deleted items of the book list with pagination, sorting and filtering
+// @ID get-trash
+// @Produce json
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Param sort query string false "sort fields, e.g. -deletedat"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/trash [get]
+func (h BookHandler) GetTrash(c echo.Context) error {
+	query, err := parseBookQuery(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	bookList, total, err := h.Service.GetTrash(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	booksResponse := make([]dtos.BookResponse, 0, len(bookList))
+	for _, book := range bookList {
+		booksResponse = append(booksResponse, toBookResponse(book))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           booksResponse,
+		Pagination:     newPagination(query.Skip, query.Limit, len(booksResponse), total),
+	}
+
+	h.Logger.Infof("%v of %v deleted books are listed.", len(booksResponse), total)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// RestoreBook => To post request for taking a book back from trash
+
+// RestoreBook godoc
+// @Summary restore a deleted book item by ID
+// @ID restore-book
+// @Produce json
+// @Param id path string true "book ID"
+// @Success 200 {object} response.JSONSuccessResultId
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id}/restore [post]
+func (h BookHandler) RestoreBook(c echo.Context) error {
+	query := c.Param("id")
+
+	result, err := h.Service.Restore(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	// to response id and success boolean
+	jsonSuccessResultId := response.JSONSuccessResultId{
+		ID:      query,
+		Success: result,
+	}
+
+	h.Logger.Infof("{%v} with id is restored.", jsonSuccessResultId.ID)
+	return c.JSON(http.StatusOK, jsonSuccessResultId)
+}
+
+// PurgeTrash => To post request for deleting permanently the books which are in trash longer than retention
+
+// PurgeTrash godoc
+// @Summary delete permanently the book items in trash older than the retention window
+// @ID purge-trash
+// @Produce json
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /admin/books/purge [post]
+func (h BookHandler) PurgeTrash(c echo.Context) error {
+	count, err := h.Service.PurgeTrash(c.Request().Context())
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(count),
+		Data:           nil,
+	}
+
+	h.Logger.Infof("%v books are purged from trash.", count)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
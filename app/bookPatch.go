@@ -0,0 +1,127 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/isbn"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/patch"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+)
+
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+)
+
+// errUnsupportedPatch => content type of PATCH body is not one of the patch formats
+var errUnsupportedPatch = errors.New(errors.UnsupportedMediaType, "unsupported_patch",
+	fmt.Sprintf("Content-Type must be %v or %v", mimeMergePatch, mimeJSONPatch))
+
+// errPatchTestFailed => "test" operation of a JSON Patch doesn't match the book
+var errPatchTestFailed = errors.New(errors.Conflict, "patch_test_failed", "test operation of the patch failed")
+
+// errInvalidPatch => patch cannot be applied or the patched book is not a book
+var errInvalidPatch = errors.New(errors.Invalid, "invalid_patch", "invalid patch")
+
+// applyBookPatch => to apply RFC 7396 or RFC 6902 patch (selected by content type) to the patchable fields of a book
+func applyBookPatch(book models.Book, contentType string, body []byte) (dtos.BookPatchDocument, error) {
+	var patched dtos.BookPatchDocument
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	// authorids & tags are empty arrays instead of null, so "/authorids/-" can be added with JSON Patch
+	authorIDs := append([]string{}, book.AuthorIDs...)
+	tags := append([]string{}, book.Tags...)
+
+	document, err := json.Marshal(dtos.BookPatchDocument{Title: book.Title, Author: book.Author, AuthorIDs: authorIDs,
+		ISBN10: book.ISBN10, ISBN13: book.ISBN13, Category: book.Category, Tags: tags, Quantity: book.Quantity})
+	if err != nil {
+		return patched, err
+	}
+
+	var result []byte
+	switch mediaType {
+	case mimeMergePatch:
+		result, err = patch.MergePatch(document, body)
+	case mimeJSONPatch:
+		result, err = patch.ApplyJSONPatch(document, body)
+	default:
+		return patched, errUnsupportedPatch
+	}
+	if errors.Is(err, patch.ErrTestFailed) {
+		return patched, errPatchTestFailed.Wrap(err)
+	}
+	if err != nil {
+		return patched, errInvalidPatch.Withf("%v", err)
+	}
+
+	// id, version or unknown fields cannot be added with a patch
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return patched, errInvalidPatch.Withf("patched book is not valid: %v", err)
+	}
+
+	return patched, nil
+}
+
+// changedBookFields => only changed fields are written to database (bson name => new value)
+func changedBookFields(book models.Book, patched dtos.BookPatchDocument) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	if patched.Title != book.Title {
+		fields["title"] = patched.Title
+	}
+	if patched.Author != book.Author {
+		fields["author"] = patched.Author
+	}
+	if !equalStrings(patched.AuthorIDs, book.AuthorIDs) {
+		fields["authorids"] = patched.AuthorIDs
+	}
+	// both forms are written together, the one which is not changed is derived from the changed one by service
+	// => if the changed one is cleared, the stored value of the other one is kept
+	isbn10Changed := isbn.Normalize(patched.ISBN10) != book.ISBN10
+	isbn13Changed := isbn.Normalize(patched.ISBN13) != book.ISBN13
+	if isbn10Changed || isbn13Changed {
+		fields["isbn10"], fields["isbn13"] = book.ISBN10, book.ISBN13
+		if isbn10Changed {
+			fields["isbn10"] = patched.ISBN10
+			if !isbn13Changed && patched.ISBN10 != "" {
+				fields["isbn13"] = ""
+			}
+		}
+		if isbn13Changed {
+			fields["isbn13"] = patched.ISBN13
+			if !isbn10Changed && patched.ISBN13 != "" {
+				fields["isbn10"] = ""
+			}
+		}
+	}
+	if patched.Category != book.Category {
+		fields["category"] = patched.Category
+	}
+	if !equalStrings(patched.Tags, book.Tags) {
+		fields["tags"] = patched.Tags
+	}
+	if patched.Quantity != book.Quantity {
+		fields["quantity"] = patched.Quantity
+	}
+
+	return fields
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,293 @@
+package app
+
+import (
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/response"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// field types which can be used for sorting and filtering on books
+const (
+	fieldString = iota
+	fieldInt
+	fieldDate
+)
+
+// bookQueryFields => whitelist of the book fields (bson names) which can be used in query params
+var bookQueryFields = map[string]int{
+	"title":       fieldString,
+	"author":      fieldString,
+	"authorids":   fieldString,
+	"isbn10":      fieldString,
+	"isbn13":      fieldString,
+	"category":    fieldString,
+	"tags":        fieldString,
+	"quantity":    fieldInt,
+	"createddate": fieldDate,
+	"updateddate": fieldDate,
+	"deletedat":   fieldDate,
+}
+
+// filterAliases => query params which filter another field => category=Fiction lists sub categories too
+var filterAliases = map[string]string{
+	"category": "categorypath",
+	"tag":      "tags",
+}
+
+// operators => allowed operators for "field[op]=value" filters
+var operators = map[string]bool{
+	"eq": true, "ne": true, "gt": true, "gte": true, "lt": true, "lte": true, "in": true, "like": true,
+}
+
+// reserved query params, they are not filters
+var reservedParams = map[string]bool{
+	"page": true, "pageSize": true, "limit": true, "offset": true, "sort": true, "cursor": true, "expand": true,
+	"format": true, "columns": true,
+}
+
+// singleParams => params which can be given only once, "sort=title&sort=-title" would be read as one of them silently
+var singleParams = []string{"page", "pageSize", "limit", "offset", "sort", "cursor"}
+
+// expandable relations of a book => ?expand=authors
+var bookExpands = map[string]bool{"authors": true}
+
+// parseBookQuery => to convert list query params into models.BookQuery
+// => page & pageSize or limit & offset, sort=title,-createddate, author=x, quantity[gte]=5
+func parseBookQuery(params url.Values) (models.BookQuery, error) {
+	var query models.BookQuery
+	var err error
+
+	for _, name := range singleParams {
+		if len(params[name]) > 1 {
+			return query, fmt.Errorf("{%v} can be given only once", name)
+		}
+	}
+
+	if query.Skip, query.Limit, err = parsePaging(params); err != nil {
+		return query, err
+	}
+
+	if query.Sort, err = parseSort(params.Get("sort")); err != nil {
+		return query, err
+	}
+
+	if query.Filters, err = parseFilters(params); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+// parseExpand => "authors" => relations which are embedded into book responses
+func parseExpand(params url.Values) (map[string]bool, error) {
+	expand := map[string]bool{}
+
+	for _, value := range params["expand"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if !bookExpands[name] {
+				return nil, fmt.Errorf("{%v} cannot be expanded", name)
+			}
+			expand[name] = true
+		}
+	}
+
+	return expand, nil
+}
+
+// newPagination => metadata of a page, count is the item count of the page
+func newPagination(skip int64, limit int64, count int, total int64) *response.Pagination {
+	pageSize := int(limit)
+
+	return &response.Pagination{
+		Page:       int(skip)/pageSize + 1,
+		PageSize:   pageSize,
+		Offset:     int(skip),
+		TotalPages: (int(total) + pageSize - 1) / pageSize,
+		HasNext:    skip+int64(count) < total,
+	}
+}
+
+// validateCursorQuery => cursor mode has its own order (createddate & _id), so page, offset and sort cannot be used with it
+func validateCursorQuery(params url.Values) error {
+	for _, name := range []string{"page", "offset", "sort"} {
+		if params.Has(name) {
+			return fmt.Errorf("{%v} cannot be used together with cursor", name)
+		}
+	}
+	return nil
+}
+
+// parsePaging => page & pageSize or limit & offset to skip & limit
+func parsePaging(params url.Values) (int64, int64, error) {
+	limit, err := intParam(params, "pageSize", defaultPageSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	if params.Has("limit") {
+		if limit, err = intParam(params, "limit", defaultPageSize); err != nil {
+			return 0, 0, err
+		}
+	}
+	if limit < 1 || limit > maxPageSize {
+		return 0, 0, fmt.Errorf("page size must be between 1 and %d", maxPageSize)
+	}
+
+	var offset int
+	if params.Has("offset") {
+		if offset, err = intParam(params, "offset", 0); err != nil {
+			return 0, 0, err
+		}
+	} else {
+		page, err := intParam(params, "page", 1)
+		if err != nil {
+			return 0, 0, err
+		}
+		if page < 1 {
+			return 0, 0, fmt.Errorf("page must be greater than 0")
+		}
+		if page-1 > math.MaxInt/limit {
+			return 0, 0, fmt.Errorf("page is too large")
+		}
+		offset = (page - 1) * limit
+	}
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("offset cannot be negative")
+	}
+
+	return int64(offset), int64(limit), nil
+}
+
+// parseSort => "title,-createddate" => title ascending, createddate descending
+func parseSort(sort string) ([]models.SortField, error) {
+	var fields []models.SortField
+	sorted := map[string]bool{}
+
+	if sort == "" {
+		return fields, nil
+	}
+
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		descending := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+		if _, ok := bookQueryFields[name]; !ok {
+			return nil, fmt.Errorf("cannot sort by {%v}", part)
+		}
+		if sorted[name] {
+			return nil, fmt.Errorf("cannot sort by {%v} more than once", name)
+		}
+		sorted[name] = true
+		fields = append(fields, models.SortField{Field: name, Descending: descending})
+	}
+
+	return fields, nil
+}
+
+// parseFilters => "author=x" (eq) or "quantity[gte]=5"
+func parseFilters(params url.Values) ([]models.Filter, error) {
+	var filters []models.Filter
+
+	for key, values := range params {
+		if reservedParams[key] {
+			continue
+		}
+
+		name, operator := key, "eq"
+		if i := strings.Index(key, "["); i > 0 {
+			// "quantity[gte" is not ignored as an unknown param, its operator is invalid
+			name, operator = key[:i], strings.TrimSuffix(key[i+1:], "]")
+			if !strings.HasSuffix(key, "]") {
+				operator = key[i:]
+			}
+		}
+
+		fieldType, ok := bookQueryFields[name]
+		field, aliased := filterAliases[name]
+		if aliased {
+			fieldType, ok = fieldString, true
+		} else {
+			field = name
+		}
+		if !ok {
+			// unknown params are ignored as before
+			continue
+		}
+		if !operators[operator] {
+			return nil, fmt.Errorf("unknown filter operator {%v} for {%v}", operator, name)
+		}
+		if operator == "like" && fieldType != fieldString {
+			return nil, fmt.Errorf("like operator can only be used with text fields, not {%v}", name)
+		}
+
+		for _, raw := range values {
+			// values are compared like they are stored
+			switch field {
+			case "tags":
+				raw = models.NormalizeTag(raw)
+			case "categorypath":
+				raw, _ = models.CategoryPath(raw)
+			}
+
+			value, err := parseFilterValue(fieldType, operator, raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for {%v}: %v", key, err)
+			}
+			filters = append(filters, models.Filter{Field: field, Operator: operator, Value: value})
+		}
+	}
+
+	return filters, nil
+}
+
+func parseFilterValue(fieldType int, operator string, raw string) (interface{}, error) {
+	if operator == "in" {
+		var list []interface{}
+		for _, item := range strings.Split(raw, ",") {
+			value, err := parseScalar(fieldType, strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	return parseScalar(fieldType, raw)
+}
+
+func parseScalar(fieldType int, raw string) (interface{}, error) {
+	switch fieldType {
+	case fieldInt:
+		return strconv.Atoi(raw)
+	case fieldDate:
+		return time.Parse(time.RFC3339, raw)
+	default:
+		return raw, nil
+	}
+}
+
+func intParam(params url.Values, name string, defaultValue int) (int, error) {
+	raw := params.Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("{%v} must be a number", name)
+	}
+
+	return value, nil
+}
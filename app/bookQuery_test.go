@@ -0,0 +1,144 @@
+package app
+
+import (
+	"RestfulWithEcho/models"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBookQuery(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		query   string
+		skip    int64
+		limit   int64
+		sort    []models.SortField
+		filters []models.Filter
+		err     string
+	}{
+		{name: "defaults", query: "", limit: defaultPageSize},
+		{name: "page", query: "page=3&pageSize=10", skip: 20, limit: 10},
+		{name: "limit & offset", query: "limit=5&offset=7", skip: 7, limit: 5},
+		{name: "offset wins over page", query: "page=4&offset=1", skip: 1, limit: defaultPageSize},
+		{name: "max page size", query: "pageSize=100", limit: maxPageSize},
+		{name: "zero limit", query: "limit=0", err: "page size must be between"},
+		{name: "negative limit", query: "limit=-5", err: "page size must be between"},
+		{name: "huge limit", query: "pageSize=101", err: "page size must be between"},
+		{name: "limit out of int range", query: "limit=99999999999999999999", err: "{limit} must be a number"},
+		{name: "zero page", query: "page=0", err: "page must be greater than 0"},
+		{name: "huge page", query: "page=9223372036854775807&pageSize=100", err: "page is too large"},
+		{name: "negative offset", query: "offset=-1", err: "offset cannot be negative"},
+		{name: "page is not a number", query: "page=two", err: "{page} must be a number"},
+		{name: "sort", query: "sort=title,-createddate,+quantity", limit: defaultPageSize,
+			sort: []models.SortField{{Field: "title"}, {Field: "createddate", Descending: true}, {Field: "quantity"}}},
+		{name: "unknown sort field", query: "sort=price", err: "cannot sort by {price}"},
+		{name: "sort by id", query: "sort=-_id", err: "cannot sort by {-_id}"},
+		{name: "empty sort field", query: "sort=title,,quantity", err: "cannot sort by {}"},
+		{name: "same sort field twice", query: "sort=title,-title", err: "cannot sort by {title} more than once"},
+		{name: "filters", query: "author=Frank&quantity[gte]=5", limit: defaultPageSize,
+			filters: []models.Filter{{Field: "author", Operator: "eq", Value: "Frank"}, {Field: "quantity", Operator: "gte", Value: 5}}},
+		{name: "in filter", query: "quantity[in]=1, 2", limit: defaultPageSize,
+			filters: []models.Filter{{Field: "quantity", Operator: "in", Value: []interface{}{1, 2}}}},
+		{name: "date filter", query: "createddate[lt]=2024-01-02T03:04:05Z", limit: defaultPageSize,
+			filters: []models.Filter{{Field: "createddate", Operator: "lt", Value: created}}},
+		{name: "aliases are normalized", query: "category=Fiction%20/%20SciFi&tag=%20Go%20", limit: defaultPageSize,
+			filters: []models.Filter{{Field: "categorypath", Operator: "eq", Value: "Fiction/SciFi"},
+				{Field: "tags", Operator: "eq", Value: "go"}}},
+		{name: "unknown params are ignored", query: "price[gte]=5&foo=bar", limit: defaultPageSize},
+		{name: "range value is not a number", query: "quantity[gte]=five", err: "invalid value for {quantity[gte]}"},
+		{name: "range without operator", query: "quantity[]=5", err: "unknown filter operator {} for {quantity}"},
+		{name: "range without closing bracket", query: "quantity[gte=5", err: "unknown filter operator {[gte} for {quantity}"},
+		{name: "nested range", query: "quantity[gte][lt]=5", err: "unknown filter operator {gte][lt} for {quantity}"},
+		{name: "unknown operator", query: "quantity[between]=1,5", err: "unknown filter operator {between}"},
+		{name: "like on a number", query: "quantity[like]=5", err: "like operator can only be used with text fields"},
+		{name: "date is not RFC 3339", query: "createddate[gt]=2024-01-02", err: "invalid value for {createddate[gt]}"},
+		{name: "empty item of in", query: "quantity[in]=1,,2", err: "invalid value for {quantity[in]}"},
+		{name: "repeated filter", query: "quantity[gte]=1&quantity[gte]=3", limit: defaultPageSize,
+			filters: []models.Filter{{Field: "quantity", Operator: "gte", Value: 1}, {Field: "quantity", Operator: "gte", Value: 3}}},
+		{name: "repeated sort", query: "sort=title&sort=-title", err: "{sort} can be given only once"},
+		{name: "repeated page", query: "page=1&page=2", err: "{page} can be given only once"},
+		{name: "repeated limit", query: "limit=5&limit=50", err: "{limit} can be given only once"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatalf("query cannot be parsed: %v", err)
+			}
+
+			query, err := parseBookQuery(params)
+
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected %q error, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("query is rejected: %v", err)
+			}
+			if query.Skip != test.skip || query.Limit != test.limit {
+				t.Fatalf("expected skip %d & limit %d, got %d & %d", test.skip, test.limit, query.Skip, query.Limit)
+			}
+			if !reflect.DeepEqual(query.Sort, test.sort) {
+				t.Fatalf("expected %+v sort, got %+v", test.sort, query.Sort)
+			}
+			if !sameFilters(query.Filters, test.filters) {
+				t.Fatalf("expected %+v filters, got %+v", test.filters, query.Filters)
+			}
+		})
+	}
+}
+
+// sameFilters => params are read from a map, so filters of different params are compared in any order
+func sameFilters(got []models.Filter, expected []models.Filter) bool {
+	if len(got) != len(expected) {
+		return false
+	}
+
+	used := make([]bool, len(got))
+	for _, filter := range expected {
+		found := false
+		for i := range got {
+			if !used[i] && reflect.DeepEqual(got[i], filter) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseExpand(t *testing.T) {
+	expand, err := parseExpand(url.Values{"expand": {"authors", " authors "}})
+	if err != nil || !reflect.DeepEqual(expand, map[string]bool{"authors": true}) {
+		t.Fatalf("unexpected expand: %v (%v)", expand, err)
+	}
+
+	if _, err := parseExpand(url.Values{"expand": {"authors,reviews"}}); err == nil {
+		t.Fatal("unknown relation is expanded")
+	}
+}
+
+func TestValidateCursorQuery(t *testing.T) {
+	for _, query := range []string{"cursor=x&page=2", "cursor=x&offset=1", "cursor=x&sort=title"} {
+		params, _ := url.ParseQuery(query)
+		if err := validateCursorQuery(params); err == nil {
+			t.Errorf("%v: paging of pages is accepted with cursor", query)
+		}
+	}
+
+	params, _ := url.ParseQuery("cursor=x&limit=10&author=Frank")
+	if err := validateCursorQuery(params); err != nil {
+		t.Fatalf("cursor query is rejected: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// echo has no constants for conditional request headers
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// etag => version of a book as a strong entity tag => "3"
+func etag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseIfMatch => expected version from If-Match header, zero means there is no precondition ("" or "*")
+func parseIfMatch(header string) (int, error) {
+	header = strings.TrimSpace(header)
+
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	// weak tags cannot be used with If-Match (RFC 7232 strong comparison)
+	if strings.Contains(header, ",") || strings.HasPrefix(header, "W/") {
+		return 0, fmt.Errorf("If-Match must be a single strong ETag")
+	}
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("If-Match {%v} is not a valid ETag", header)
+	}
+
+	return version, nil
+}
+
+// matchesIfNoneMatch => If-None-Match uses weak comparison and can have a list of tags
+func matchesIfNoneMatch(header string, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
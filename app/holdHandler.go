@@ -0,0 +1,226 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type HoldHandler struct {
+	Service service.IHoldService
+	Logger  *logrus.Logger
+}
+
+func NewHoldHandler(e *echo.Echo, service service.IHoldService, log *logrus.Logger) *HoldHandler {
+	h := &HoldHandler{Service: service, Logger: log}
+
+	//Routes
+	// ready holds reserve copies of the stock, so only inventory roles can place & cancel them
+	e.POST("api/books/:id/holds", h.PlaceHold, Authorize(rbac.ChangeStock))
+	e.GET("api/books/:id/holds", h.GetHoldQueue, Authorize(rbac.ReadBooks))
+	router := e.Group("api/holds")
+	router.GET("/:id", h.GetHoldById, Authorize(rbac.ReadBooks))
+	router.POST("/:id/cancel", h.CancelHold, Authorize(rbac.ChangeStock))
+
+	return h
+}
+
+// PlaceHold => To post request for joining the hold queue of an out of stock book
+
+// PlaceHold godoc
+// @Summary join the FIFO hold queue of a book item which has no available copies
+// @ID place-hold
+// @Accept json
+// @Produce json
+// @Param id path string true "book ID"
+// @Param data body dtos.HoldRequest true "hold data"
+// @Success 201 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id}/holds [post]
+func (h HoldHandler) PlaceHold(c echo.Context) error {
+	query := c.Param("id")
+
+	var holdRequest dtos.HoldRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&holdRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(holdRequest); err != nil {
+		return err
+	}
+
+	hold, position, err := h.Service.Place(c.Request().Context(), query, holdRequest.MemberID)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toHoldResponse(hold, position),
+	}
+
+	h.Logger.Infof("{%v} with id member is waiting for {%v} with id at position %v.", hold.MemberID, query, position)
+	return c.JSON(http.StatusCreated, jsonSuccessResultData)
+}
+
+// GetHoldQueue => To get request for listing the hold queue of a book
+
+// GetHoldQueue godoc
+// @Summary get active holds of a book item, ready ones first and then the waiting ones in order
+// @ID get-hold-queue
+// @Produce json
+// @Param id path string true "book ID"
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id}/holds [get]
+func (h HoldHandler) GetHoldQueue(c echo.Context) error {
+	query := c.Param("id")
+
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	holds, total, err := h.Service.GetQueue(c.Request().Context(), query, skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	// ready holds are listed first, waiting positions continue after the first waiting hold of the page
+	holdsResponse := make([]dtos.HoldResponse, 0, len(holds))
+	position := int64(0)
+	for _, hold := range holds {
+		if hold.Status == models.HoldWaiting && position == 0 {
+			if _, position, err = h.Service.GetById(c.Request().Context(), hold.ID); err != nil {
+				return err
+			}
+		} else if hold.Status == models.HoldWaiting {
+			position++
+		}
+		holdsResponse = append(holdsResponse, toHoldResponse(hold, position))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           holdsResponse,
+		Pagination:     newPagination(skip, limit, len(holdsResponse), total),
+	}
+
+	h.Logger.Infof("Hold queue of {%v} with id is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetHoldById => To get request find a hold by id with its position in queue
+
+// GetHoldById godoc
+// @Summary get a hold by ID, position is given while it is waiting
+// @ID get-hold-by-id
+// @Produce json
+// @Param id path string true "hold ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /holds/{id} [get]
+func (h HoldHandler) GetHoldById(c echo.Context) error {
+	query := c.Param("id")
+
+	hold, position, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toHoldResponse(hold, position),
+	}
+
+	h.Logger.Infof("{%v} with id hold is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// CancelHold => To post request for leaving the hold queue
+
+// CancelHold godoc
+// @Summary cancel a waiting or ready hold, a held copy goes to the next member
+// @ID cancel-hold
+// @Produce json
+// @Param id path string true "hold ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /holds/{id}/cancel [post]
+func (h HoldHandler) CancelHold(c echo.Context) error {
+	query := c.Param("id")
+
+	hold, err := h.Service.Cancel(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toHoldResponse(hold, 0),
+	}
+
+	h.Logger.Infof("{%v} with id hold is cancelled.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// toHoldResponse => mapping from model to response dto
+func toHoldResponse(hold models.Hold, position int64) dtos.HoldResponse {
+	holdResponse := dtos.HoldResponse{
+		ID:          hold.ID,
+		BookID:      hold.BookID,
+		MemberID:    hold.MemberID,
+		Status:      hold.Status,
+		Position:    position,
+		CreatedDate: hold.CreatedDate.Time(),
+	}
+	if hold.ReadyDate != nil {
+		readyDate := hold.ReadyDate.Time()
+		holdResponse.ReadyDate = &readyDate
+	}
+	if hold.PickupUntil != nil {
+		pickupUntil := hold.PickupUntil.Time()
+		holdResponse.PickupUntil = &pickupUntil
+	}
+	if hold.FinishedDate != nil {
+		finishedDate := hold.FinishedDate.Time()
+		holdResponse.FinishedDate = &finishedDate
+	}
+
+	return holdResponse
+}
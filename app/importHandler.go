@@ -0,0 +1,226 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/importer"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+// importSyncLimit => imports with more rows than this run as background jobs, their status is polled
+const importSyncLimit = maxBulkSize
+
+type ImportHandler struct {
+	Service service.IImportService
+	Logger  *logrus.Logger
+}
+
+func NewImportHandler(e *echo.Echo, service service.IImportService, log *logrus.Logger) *ImportHandler {
+	h := &ImportHandler{Service: service, Logger: log}
+
+	//Routes
+	// upsert imports also need rbac.ChangeStock (checked by the handler)
+	e.POST("api/books/import", h.ImportBooks, Authorize(rbac.WriteBooks))
+	router := e.Group("api/imports")
+	router.GET("/:id", h.GetImportById)
+	router.GET("/:id/errors", h.GetImportErrors)
+
+	return h
+}
+
+// ImportBooks => To post request for importing a csv or ndjson catalog file
+
+// ImportBooks godoc
+// @Summary import books from a csv (with a header row) or an ndjson file, rows are validated like a new book
+// @ID import-books
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "csv or ndjson file, list columns of csv (authorids, tags) are separated by ;"
+// @Param format formData string false "csv or ndjson, the extension of the file is used if it is empty"
+// @Param dryrun formData bool false "only check the rows, nothing is written"
+// @Param upsert formData bool false "change the books which have the same isbn (or title & author) instead of creating new ones"
+// @Success 200 {object} response.JSONSuccessResultData "finished import"
+// @Success 202 {object} response.JSONSuccessResultData "big import which runs in background, Location is its status url"
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/import [post]
+func (h ImportHandler) ImportBooks(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+
+	if err != nil {
+		return errInvalidBody.Withf("file is required: %v", err)
+	}
+
+	job := models.ImportJob{FileName: fileHeader.Filename, Format: c.FormValue("format")}
+	if job.Format == "" {
+		job.Format = importer.FormatOf(fileHeader.Filename)
+	}
+
+	job.DryRun, err = boolValue(c.FormValue("dryrun"), "dryrun")
+	if err == nil {
+		job.Upsert, err = boolValue(c.FormValue("upsert"), "upsert")
+	}
+	if err != nil {
+		return errInvalidBody.Withf("%v", err)
+	}
+
+	// upsert can change quantities of the existing books
+	if job.Upsert {
+		if err := authorize(c, rbac.ChangeStock); err != nil {
+			return err
+		}
+	}
+
+	file, err := fileHeader.Open()
+
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// rows are validated with the validator of the book handler
+	rows, err := importer.Parse(file, job.Format, c.Validate)
+
+	if err != nil {
+		return errInvalidBody.Withf("%v", err)
+	}
+
+	background := len(rows) > importSyncLimit
+	job, err = h.Service.Start(c.Request().Context(), job, rows, background)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toImportResponse(job),
+	}
+
+	if background {
+		c.Response().Header().Set(echo.HeaderLocation, importURL(job.ID))
+		h.Logger.Infof("{%v} with id import of %v rows is started.", job.ID, job.Total)
+		return c.JSON(http.StatusAccepted, jsonSuccessResultData)
+	}
+
+	h.Logger.Infof("{%v} with id import is %v.", job.ID, job.Status)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetImportById => To get request for the status of an import job
+
+// GetImportById godoc
+// @Summary get status and counts of an import job by ID
+// @ID get-import-by-id
+// @Produce json
+// @Param id path string true "import ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /imports/{id} [get]
+func (h ImportHandler) GetImportById(c echo.Context) error {
+	query := c.Param("id")
+
+	job, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toImportResponse(job),
+	}
+
+	h.Logger.Infof("{%v} with id import is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetImportErrors => To get request for downloading the error report of an import job
+
+// GetImportErrors godoc
+// @Summary download the rows of an import job which are not imported as csv (line, message)
+// @ID get-import-errors
+// @Produce text/csv
+// @Param id path string true "import ID"
+// @Success 200 {string} string "csv report"
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /imports/{id}/errors [get]
+func (h ImportHandler) GetImportErrors(c echo.Context) error {
+	query := c.Param("id")
+
+	job, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"import-%v-errors.csv\"", job.ID))
+	c.Response().WriteHeader(http.StatusOK)
+
+	h.Logger.Infof("Error report of {%v} with id import is listed.", query)
+	return importer.WriteReport(c.Response(), job)
+}
+
+// toImportResponse => mapping from model to response dto
+func toImportResponse(job models.ImportJob) dtos.ImportResponse {
+	importResponse := dtos.ImportResponse{
+		ID:          job.ID,
+		Status:      job.Status,
+		FileName:    job.FileName,
+		Format:      job.Format,
+		DryRun:      job.DryRun,
+		Upsert:      job.Upsert,
+		Total:       job.Total,
+		Processed:   job.Processed,
+		Created:     job.Created,
+		Updated:     job.Updated,
+		Failed:      job.Failed,
+		Error:       job.Error,
+		CreatedDate: job.CreatedDate.Time(),
+	}
+	if job.Failed > 0 {
+		importResponse.Report = importURL(job.ID) + "/errors"
+	}
+	if job.FinishedDate != nil {
+		finishedDate := job.FinishedDate.Time()
+		importResponse.FinishedDate = &finishedDate
+	}
+
+	return importResponse
+}
+
+func importURL(id string) string {
+	return "/api/imports/" + id
+}
+
+// boolValue => "" is false, otherwise it must be a boolean like true, false, 1 or 0
+func boolValue(raw string, name string) (bool, error) {
+	if raw == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("{%v} must be true or false", name)
+	}
+
+	return value, nil
+}
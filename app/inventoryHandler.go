@@ -0,0 +1,195 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type InventoryHandler struct {
+	Service service.IInventoryService
+	Logger  *logrus.Logger
+}
+
+func NewInventoryHandler(e *echo.Echo, service service.IInventoryService, log *logrus.Logger) *InventoryHandler {
+	i := &InventoryHandler{Service: service, Logger: log}
+
+	//Routes
+	router := e.Group("api/books")
+	router.POST("/:id/stock", i.MoveStock, Authorize(rbac.ChangeStock))
+	router.GET("/:id/stock/movements", i.GetStockMovements, Authorize(rbac.ReadBooks))
+	router.GET("/low-stock", i.GetLowStockBooks, Authorize(rbac.ReadBooks))
+
+	return i
+}
+
+// MoveStock => To post request for incrementing or decrementing the quantity of a book
+
+// MoveStock godoc
+// @Summary increment or decrement the quantity of a book item with a reason
+// @ID move-stock
+// @Accept json
+// @Produce json
+// @Param id path string true "book ID"
+// @Param data body dtos.StockMovementRequest true "movement data"
+// @Success 201 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id}/stock [post]
+func (h InventoryHandler) MoveStock(c echo.Context) error {
+	query := c.Param("id")
+
+	var movementRequest dtos.StockMovementRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&movementRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(movementRequest); err != nil {
+		return err
+	}
+
+	movement, err := h.Service.Move(c.Request().Context(), query, movementRequest.Type, movementRequest.Reason,
+		movementRequest.Quantity, movementRequest.Note)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toStockMovementResponse(movement),
+	}
+
+	h.Logger.Infof("{%v} with id: %v %v by %v (%v).", query, movement.Type, movement.Quantity, movement.Actor, movement.Reason)
+	return c.JSON(http.StatusCreated, jsonSuccessResultData)
+}
+
+// GetStockMovements => To get request for listing the stock ledger of a book
+
+// GetStockMovements godoc
+// @Summary get stock movements of a book item, newest first
+// @ID get-stock-movements
+// @Produce json
+// @Param id path string true "book ID"
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id}/stock/movements [get]
+func (h InventoryHandler) GetStockMovements(c echo.Context) error {
+	query := c.Param("id")
+
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	movements, total, err := h.Service.GetMovements(c.Request().Context(), query, skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	movementsResponse := make([]dtos.StockMovementResponse, 0, len(movements))
+	for _, movement := range movements {
+		movementsResponse = append(movementsResponse, toStockMovementResponse(movement))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           movementsResponse,
+		Pagination:     newPagination(skip, limit, len(movementsResponse), total),
+	}
+
+	h.Logger.Infof("Stock movements of {%v} with id are listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetLowStockBooks => To get request for listing books which are low in stock
+
+// GetLowStockBooks godoc
+// @Summary get book items whose quantity is at or below the threshold, lowest first
+// @ID get-low-stock-books
+// @Produce json
+// @Param threshold query int false "quantity threshold, configured one by default"
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/low-stock [get]
+func (h InventoryHandler) GetLowStockBooks(c echo.Context) error {
+	params := c.QueryParams()
+
+	skip, limit, err := parsePaging(params)
+
+	var threshold *int
+	if err == nil && params.Has("threshold") {
+		var value int
+		if value, err = intParam(params, "threshold", 0); err == nil {
+			threshold = &value
+		}
+	}
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	bookList, total, err := h.Service.LowStock(c.Request().Context(), threshold, skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	booksResponse := make([]dtos.BookResponse, 0, len(bookList))
+	for _, book := range bookList {
+		booksResponse = append(booksResponse, toBookResponse(book))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           booksResponse,
+		Pagination:     newPagination(skip, limit, len(booksResponse), total),
+	}
+
+	h.Logger.Infof("%v of %v low stock books are listed.", len(booksResponse), total)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// toStockMovementResponse => mapping from model to response dto
+func toStockMovementResponse(movement models.StockMovement) dtos.StockMovementResponse {
+	return dtos.StockMovementResponse{
+		ID:            movement.ID,
+		BookID:        movement.BookID,
+		Date:          movement.Date.Time(),
+		Type:          movement.Type,
+		Reason:        movement.Reason,
+		Quantity:      movement.Quantity,
+		QuantityAfter: movement.QuantityAfter,
+		Note:          movement.Note,
+		Actor:         movement.Actor,
+	}
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+type LoanHandler struct {
+	Service service.ILoanService
+	Logger  *logrus.Logger
+}
+
+func NewLoanHandler(e *echo.Echo, service service.ILoanService, log *logrus.Logger) *LoanHandler {
+	l := &LoanHandler{Service: service, Logger: log}
+
+	//Routes
+	// checkouts & returns change the quantities of books, so only inventory roles can make them
+	router := e.Group("api/loans")
+	router.POST("", l.CheckoutBook, Authorize(rbac.ChangeStock))
+	router.GET("/overdue", l.GetOverdueLoans, Authorize(rbac.ReadBooks))
+	router.GET("/:id", l.GetLoanById, Authorize(rbac.ReadBooks))
+	router.POST("/:id/return", l.ReturnBook, Authorize(rbac.ChangeStock))
+
+	return l
+}
+
+// CheckoutBook => To post request for lending a copy of a book to a member
+
+// CheckoutBook godoc
+// @Summary check out a book item for a member, available copies are decremented until it is returned
+// @ID checkout-book
+// @Accept json
+// @Produce json
+// @Param data body dtos.LoanRequest true "loan data"
+// @Success 201 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /loans [post]
+func (h LoanHandler) CheckoutBook(c echo.Context) error {
+	var loanRequest dtos.LoanRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&loanRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(loanRequest); err != nil {
+		return err
+	}
+
+	loan, err := h.Service.Checkout(c.Request().Context(), loanRequest.MemberID, loanRequest.BookID)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toLoanResponse(loan, time.Now()),
+	}
+
+	h.Logger.Infof("{%v} with id is lent to {%v} with id member.", loan.BookID, loan.MemberID)
+	return c.JSON(http.StatusCreated, jsonSuccessResultData)
+}
+
+// GetLoanById => To get request find a loan by id
+
+// GetLoanById godoc
+// @Summary get a loan by ID
+// @ID get-loan-by-id
+// @Produce json
+// @Param id path string true "loan ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Security BearerAuth
+// @Router /loans/{id} [get]
+func (h LoanHandler) GetLoanById(c echo.Context) error {
+	query := c.Param("id")
+
+	loan, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toLoanResponse(loan, time.Now()),
+	}
+
+	h.Logger.Infof("{%v} with id loan is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// ReturnBook => To post request for returning a borrowed copy
+
+// ReturnBook godoc
+// @Summary return the copy of a loan, it is available again
+// @ID return-book
+// @Produce json
+// @Param id path string true "loan ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /loans/{id}/return [post]
+func (h LoanHandler) ReturnBook(c echo.Context) error {
+	query := c.Param("id")
+
+	loan, err := h.Service.Return(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toLoanResponse(loan, time.Now()),
+	}
+
+	h.Logger.Infof("{%v} with id loan is returned.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetOverdueLoans => To get request for listing active loans whose due date is passed
+
+// GetOverdueLoans godoc
+// @Summary get overdue loans, most overdue first
+// @ID get-overdue-loans
+// @Produce json
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /loans/overdue [get]
+func (h LoanHandler) GetOverdueLoans(c echo.Context) error {
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	loans, total, err := h.Service.GetOverdue(c.Request().Context(), skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           toLoanResponses(loans, time.Now()),
+		Pagination:     newPagination(skip, limit, len(loans), total),
+	}
+
+	h.Logger.Info("Overdue loans are listed.")
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// toLoanResponse => mapping from model to response dto, a loan is overdue if it is not returned until its due date
+func toLoanResponse(loan models.Loan, now time.Time) dtos.LoanResponse {
+	loanResponse := dtos.LoanResponse{
+		ID:       loan.ID,
+		MemberID: loan.MemberID,
+		BookID:   loan.BookID,
+		LoanDate: loan.LoanDate.Time(),
+		DueDate:  loan.DueDate.Time(),
+	}
+	if loan.ReturnedDate != nil {
+		returnedDate := loan.ReturnedDate.Time()
+		loanResponse.ReturnedDate = &returnedDate
+	} else {
+		loanResponse.Overdue = now.After(loanResponse.DueDate)
+	}
+
+	return loanResponse
+}
+
+func toLoanResponses(loans []models.Loan, now time.Time) []dtos.LoanResponse {
+	loansResponse := make([]dtos.LoanResponse, 0, len(loans))
+	for _, loan := range loans {
+		loansResponse = append(loansResponse, toLoanResponse(loan, now))
+	}
+	return loansResponse
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+type MemberHandler struct {
+	Service service.IMemberService
+	Logger  *logrus.Logger
+}
+
+func NewMemberHandler(e *echo.Echo, service service.IMemberService, log *logrus.Logger) *MemberHandler {
+	m := &MemberHandler{Service: service, Logger: log}
+
+	//Routes
+	router := e.Group("api/members")
+	router.GET("", m.GetAllMembers)
+	router.GET("/:id", m.GetMemberById)
+	router.POST("", m.CreateMember)
+	router.PUT("", m.UpdateMember)
+	router.DELETE("/:id", m.DeleteMember)
+	router.GET("/:id/loans", m.GetMemberLoans)
+
+	return m
+}
+
+// GetAllMembers => To get request for listing all of members
+
+// GetAllMembers godoc
+// @Summary get all members ordered by name with pagination
+// @ID get-all-members
+// @Produce json
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /members [get]
+func (h MemberHandler) GetAllMembers(c echo.Context) error {
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	memberList, total, err := h.Service.GetAll(c.Request().Context(), skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	membersResponse := make([]dtos.MemberResponse, 0, len(memberList))
+	for _, member := range memberList {
+		membersResponse = append(membersResponse, toMemberResponse(member))
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           membersResponse,
+		Pagination:     newPagination(skip, limit, len(membersResponse), total),
+	}
+
+	h.Logger.Info("All members are listed.")
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// GetMemberById => To get request find a member by id
+
+// GetMemberById godoc
+// @Summary get a member by ID
+// @ID get-member-by-id
+// @Produce json
+// @Param id path string true "member ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Security BearerAuth
+// @Router /members/{id} [get]
+func (h MemberHandler) GetMemberById(c echo.Context) error {
+	query := c.Param("id")
+
+	member, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toMemberResponse(member),
+	}
+
+	h.Logger.Infof("{%v} with id member is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// CreateMember => To post request for creating new a member
+
+// CreateMember godoc
+// @Summary add a new member
+// @ID create-member
+// @Accept json
+// @Produce json
+// @Param data body dtos.MemberCreateRequest true "member data, maxloans zero means the default limit"
+// @Success 201 {object} response.JSONSuccessResultId
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /members [post]
+func (h MemberHandler) CreateMember(c echo.Context) error {
+	var memberRequest dtos.MemberCreateRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&memberRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(memberRequest); err != nil {
+		return err
+	}
+
+	member := models.Member{Name: memberRequest.Name, Email: memberRequest.Email, MaxLoans: memberRequest.MaxLoans}
+
+	result, err := h.Service.Insert(c.Request().Context(), member)
+
+	if err != nil {
+		return err
+	}
+
+	// to response id and success boolean
+	jsonSuccessResultId := response.JSONSuccessResultId{
+		ID:      result.ID,
+		Success: true,
+	}
+
+	h.Logger.Infof("{%v} with id member is created.", jsonSuccessResultId.ID)
+	return c.JSON(http.StatusCreated, jsonSuccessResultId)
+}
+
+// UpdateMember => To put request for changing a member
+
+// UpdateMember godoc
+// @Summary update a member by ID
+// @ID update-member
+// @Accept json
+// @Produce json
+// @Param data body dtos.MemberUpdateRequest true "member data"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /members [put]
+func (h MemberHandler) UpdateMember(c echo.Context) error {
+	var memberRequest dtos.MemberUpdateRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&memberRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(memberRequest); err != nil {
+		return err
+	}
+
+	member := models.Member{ID: memberRequest.ID, Name: memberRequest.Name, Email: memberRequest.Email,
+		MaxLoans: memberRequest.MaxLoans}
+
+	result, err := h.Service.Update(c.Request().Context(), member)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toMemberResponse(result),
+	}
+
+	h.Logger.Infof("{%v} with id member is updated.", result.ID)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// DeleteMember => To delete request by id, members with active loans cannot be deleted
+
+// DeleteMember godoc
+// @Summary delete a member by ID
+// @ID delete-member
+// @Produce json
+// @Param id path string true "member ID"
+// @Success 200 {object} response.JSONSuccessResultId
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /members/{id} [delete]
+func (h MemberHandler) DeleteMember(c echo.Context) error {
+	query := c.Param("id")
+
+	result, err := h.Service.Delete(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	// to response id and success boolean
+	jsonSuccessResultId := response.JSONSuccessResultId{
+		ID:      query,
+		Success: result,
+	}
+
+	h.Logger.Infof("{%v} with id member is deleted.", jsonSuccessResultId.ID)
+	return c.JSON(http.StatusOK, jsonSuccessResultId)
+}
+
+// GetMemberLoans => To get request for listing loan history of a member
+
+// GetMemberLoans godoc
+// @Summary get loans of a member, newest first
+// @ID get-member-loans
+// @Produce json
+// @Param id path string true "member ID"
+// @Param page query int false "page number, starts from 1"
+// @Param pageSize query int false "page size (max 100)"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /members/{id}/loans [get]
+func (h MemberHandler) GetMemberLoans(c echo.Context) error {
+	query := c.Param("id")
+
+	skip, limit, err := parsePaging(c.QueryParams())
+
+	if err != nil {
+		return errInvalidQuery.Withf("%v", err)
+	}
+
+	loans, total, err := h.Service.GetLoans(c.Request().Context(), query, skip, limit)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: int(total),
+		Data:           toLoanResponses(loans, time.Now()),
+		Pagination:     newPagination(skip, limit, len(loans), total),
+	}
+
+	h.Logger.Infof("Loans of {%v} with id member are listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// toMemberResponse => mapping from model to response dto
+func toMemberResponse(member models.Member) dtos.MemberResponse {
+	return dtos.MemberResponse{
+		ID:          member.ID,
+		Name:        member.Name,
+		Email:       member.Email,
+		MaxLoans:    member.MaxLoans,
+		ActiveLoans: member.ActiveLoans,
+	}
+}
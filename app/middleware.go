@@ -0,0 +1,167 @@
+package app
+
+import (
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/requestinfo"
+	"RestfulWithEcho/service"
+	"RestfulWithEcho/tenant"
+	"github.com/labstack/echo/v4"
+	"strings"
+)
+
+// HeaderUser => name of the user who makes the request, it is used for audit records
+const HeaderUser = "X-User"
+
+// HeaderAPIKey => key of a machine client, it is sent instead of an access token
+const HeaderAPIKey = "X-API-Key"
+
+// HeaderTenant => bookstore of the request, it must be the tenant of the access token or api key
+const HeaderTenant = "X-Tenant-ID"
+
+// RequestInfo => to put actor and request id into the request context, so service layer can use them
+// => it must be used after middleware.RequestID, which sets X-Request-ID
+func RequestInfo() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			info := requestinfo.Info{
+				Actor:     c.Request().Header.Get(HeaderUser),
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+			}
+			if info.Actor == "" {
+				info.Actor = requestinfo.Anonymous
+			}
+
+			c.SetRequest(c.Request().WithContext(requestinfo.With(c.Request().Context(), info)))
+			return next(c)
+		}
+	}
+}
+
+// APIKeyAuth => routes under the prefixes accept "X-API-Key: <key>" instead of an access token
+// => it must be used before Authenticate, which doesn't check the requests authenticated by an api key
+func APIKeyAuth(keys service.IAPIKeyService, prefixes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderAPIKey)
+			if key == "" || !underPrefixes(c.Path(), prefixes) {
+				return next(c)
+			}
+
+			apiKey, err := keys.Authenticate(c.Request().Context(), key)
+			if err != nil {
+				return err
+			}
+
+			info := requestinfo.From(c.Request().Context())
+			info.Actor = "apikey:" + apiKey.Name
+			info.APIKeyID = apiKey.ID
+			info.Scopes = apiKey.Scopes
+			info.TenantID = tenant.OrDefault(apiKey.TenantID)
+
+			c.SetRequest(c.Request().WithContext(requestinfo.With(c.Request().Context(), info)))
+			return next(c)
+		}
+	}
+}
+
+// Authenticate => routes under the prefixes (e.g. api/books) need a valid access token, "Authorization: Bearer <token>"
+// => user of the token is the actor of the request instead of X-User, so it must be used after RequestInfo
+func Authenticate(auth service.IAuthService, prefixes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !underPrefixes(c.Path(), prefixes) || requestinfo.From(c.Request().Context()).APIKeyID != "" {
+				return next(c)
+			}
+
+			scheme, token, _ := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+				return service.ErrInvalidToken.Withf("access token is required")
+			}
+
+			claims, err := auth.Authenticate(strings.TrimSpace(token))
+			if err != nil {
+				return err
+			}
+
+			info := requestinfo.From(c.Request().Context())
+			info.Actor = claims.Username
+			info.UserID = claims.Subject
+			info.Roles = claims.Roles
+			info.TenantID = tenant.OrDefault(claims.Tenant)
+
+			c.SetRequest(c.Request().WithContext(requestinfo.With(c.Request().Context(), info)))
+			return next(c)
+		}
+	}
+}
+
+// ResolveTenant => tenant of the requests under the prefixes, repositories scope the documents to it
+// => tenant of the access token or api key, X-Tenant-ID header or subdomain of the base domain must be the same tenant
+// => requests without credentials cannot choose a tenant, they use tenant.Default (or they are rejected if it is required)
+// => it must be used after APIKeyAuth & Authenticate
+func ResolveTenant(baseDomain string, required bool, prefixes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !underPrefixes(c.Path(), prefixes) {
+				return next(c)
+			}
+
+			info := requestinfo.From(c.Request().Context())
+			switch {
+			case info.TenantID != "":
+				requested := tenant.Normalize(c.Request().Header.Get(HeaderTenant))
+				if requested == "" {
+					requested = tenant.FromHost(c.Request().Host, baseDomain)
+				}
+				if requested != "" && requested != info.TenantID {
+					return tenant.ErrTenantMismatch.Withf("{%v} tenant is requested with the credentials of {%v}", requested, info.TenantID)
+				}
+			case required:
+				return tenant.ErrTenantRequired.Withf("tenant is required, send an access token or an api key of a tenant")
+			default:
+				// a header or subdomain without credentials would open the data of any tenant
+				info.TenantID = tenant.Default
+			}
+
+			c.SetRequest(c.Request().WithContext(requestinfo.With(c.Request().Context(), info)))
+			return next(c)
+		}
+	}
+}
+
+// underPrefixes => route path is one of the prefixes or under one of them, e.g. api/books/:id is under api/books
+func underPrefixes(path string, prefixes []string) bool {
+	path = strings.TrimPrefix(path, "/")
+	for _, prefix := range prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize => route middleware which allows only the users who have all of the permissions, e.g.
+// router.DELETE("/:id", b.DeleteBook, Authorize(rbac.DeleteBooks)) => the route must be under Authenticate
+func Authorize(permissions ...rbac.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := authorize(c, permissions...); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// authorize => checks of the handlers which depend on the request, e.g. quantity of a book is changed
+// => permissions of an api key are its scopes
+func authorize(c echo.Context, permissions ...rbac.Permission) error {
+	info := requestinfo.From(c.Request().Context())
+	if info.APIKeyID != "" {
+		return rbac.AuthorizeScopes(info.Scopes, permissions...)
+	}
+	if info.UserID == "" {
+		return service.ErrInvalidToken.Withf("access token is required")
+	}
+	return rbac.Authorize(info.Roles, permissions...)
+}
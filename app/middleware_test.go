@@ -0,0 +1,147 @@
+package app
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/requestinfo"
+	"RestfulWithEcho/service"
+	"RestfulWithEcho/tenant"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// contextWith => echo context of a request which is authenticated like info
+func contextWith(info requestinfo.Info) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, "/api/books", nil)
+	req = req.WithContext(requestinfo.With(req.Context(), info))
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestAuthorize(t *testing.T) {
+	user := func(roles ...string) requestinfo.Info {
+		return requestinfo.Info{Actor: "alice", UserID: "user-1", Roles: roles}
+	}
+	apiKey := func(scopes ...string) requestinfo.Info {
+		return requestinfo.Info{Actor: "apikey:shop", APIKeyID: "key-1", Scopes: scopes}
+	}
+
+	tests := []struct {
+		name        string
+		info        requestinfo.Info
+		permissions []rbac.Permission
+		err         error
+	}{
+		{name: "anonymous", info: requestinfo.Info{Actor: requestinfo.Anonymous},
+			permissions: []rbac.Permission{rbac.ReadBooks}, err: service.ErrInvalidToken},
+		{name: "viewer reads", info: user(rbac.Viewer), permissions: []rbac.Permission{rbac.ReadBooks}},
+		{name: "viewer cannot reserve", info: user(rbac.Viewer),
+			permissions: []rbac.Permission{rbac.ChangeStock}, err: rbac.ErrForbidden},
+		{name: "editor cannot change stock", info: user(rbac.Editor),
+			permissions: []rbac.Permission{rbac.WriteBooks, rbac.ChangeStock}, err: rbac.ErrForbidden},
+		{name: "inventory manager changes stock", info: user(rbac.InventoryManager),
+			permissions: []rbac.Permission{rbac.ChangeStock}},
+		{name: "admin deletes", info: user(rbac.Admin), permissions: []rbac.Permission{rbac.DeleteBooks}},
+		{name: "scope of the key", info: apiKey("books:read", "books:stock"),
+			permissions: []rbac.Permission{rbac.ChangeStock}},
+		{name: "missing scope", info: apiKey("books:read"),
+			permissions: []rbac.Permission{rbac.ChangeStock}, err: rbac.ErrForbidden},
+		// roles of an api key request are ignored, its scopes are its permissions
+		{name: "roles are not used with a key", info: requestinfo.Info{APIKeyID: "key-1", UserID: "user-1", Roles: []string{rbac.Admin}},
+			permissions: []rbac.Permission{rbac.ReadBooks}, err: rbac.ErrForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			handler := Authorize(test.permissions...)(func(c echo.Context) error {
+				called = true
+				return nil
+			})
+
+			err := handler(contextWith(test.info))
+
+			if test.err == nil {
+				if err != nil || !called {
+					t.Fatalf("request must be allowed, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.err) || called {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestUnderPrefixes(t *testing.T) {
+	prefixes := []string{"api/books", "api/loans"}
+
+	tests := []struct {
+		path  string
+		under bool
+	}{
+		{path: "/api/books", under: true},
+		{path: "api/books/:id", under: true},
+		{path: "/api/loans/:id/return", under: true},
+		{path: "/api/bookshelf", under: false},
+		{path: "/api/auth/login", under: false},
+	}
+
+	for _, test := range tests {
+		if under := underPrefixes(test.path, prefixes); under != test.under {
+			t.Errorf("%v: expected %v, got %v", test.path, test.under, under)
+		}
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     requestinfo.Info
+		header   string
+		host     string
+		required bool
+		tenant   string
+		err      error
+	}{
+		{name: "tenant of the credentials", info: requestinfo.Info{UserID: "user-1", TenantID: "acme"}, tenant: "acme"},
+		{name: "same tenant in header", info: requestinfo.Info{UserID: "user-1", TenantID: "acme"}, header: "ACME", tenant: "acme"},
+		{name: "same tenant in subdomain", info: requestinfo.Info{APIKeyID: "key-1", TenantID: "acme"},
+			host: "acme.books.example.com", tenant: "acme"},
+		{name: "another tenant in header", info: requestinfo.Info{UserID: "user-1", TenantID: "acme"},
+			header: "globex", err: tenant.ErrTenantMismatch},
+		{name: "another tenant in subdomain", info: requestinfo.Info{APIKeyID: "key-1", TenantID: "acme"},
+			host: "globex.books.example.com", err: tenant.ErrTenantMismatch},
+		{name: "anonymous cannot choose a tenant", header: "acme", host: "acme.books.example.com", tenant: tenant.Default},
+		{name: "anonymous is rejected if tenant is required", header: "acme", required: true, err: tenant.ErrTenantRequired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := contextWith(test.info)
+			c.SetPath("/api/books")
+			c.Request().Header.Set(HeaderTenant, test.header)
+			c.Request().Host = test.host
+
+			resolved := ""
+			handler := ResolveTenant("books.example.com", test.required, "api/books")(func(c echo.Context) error {
+				resolved = requestinfo.From(c.Request().Context()).TenantID
+				return nil
+			})
+
+			err := handler(c)
+
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil || resolved != test.tenant {
+				t.Fatalf("expected %v tenant, got %v (%v)", test.tenant, resolved, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"RestfulWithEcho/errors"
+	"encoding/json"
+	"fmt"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// mimeProblemJSON => media type of the error responses (RFC 7807)
+const mimeProblemJSON = "application/problem+json"
+
+// problemTypeBase => type of a problem is its code under this path, e.g. /problems/book_not_found
+const problemTypeBase = "/problems/"
+
+// errors of the requests which are checked by the handlers themselves (query params, headers, files ...)
+var (
+	errInvalidQuery  = errors.New(errors.Invalid, "invalid_query", "invalid query")
+	errInvalidHeader = errors.New(errors.Invalid, "invalid_header", "invalid header")
+	errInvalidBody   = errors.New(errors.Invalid, "invalid_body", "invalid body")
+	errValidation    = errors.New(errors.Invalid, errors.CodeValidationFailed, "validation failed")
+	errInternal      = errors.New(errors.Internal, errors.CodeInternal, "Something went wrong!")
+)
+
+// ProblemHandler => the only place where errors of the handlers are turned into responses
+// => domain errors of the services have their own status & code, validation errors have the errors of the fields,
+// errors of echo (unknown routes, binding ...) keep their status and the other errors are internal errors
+func ProblemHandler(log *logrus.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		trans := translatorOf(c)
+		problem := toProblem(err, trans)
+		problem.Instance = c.Request().URL.Path
+
+		// only the failures of the server are errors, problems of the clients are warnings
+		if problem.Status >= http.StatusInternalServerError {
+			log.Errorf("StatusInternalServerError: %v", err.Error())
+		} else {
+			log.Warnf("%v! %v", problem.Title, err.Error())
+		}
+
+		if c.Response().Committed {
+			return
+		}
+
+		// messages of the validation errors are in the language of Accept-Language
+		if len(problem.Errors) > 0 && trans != nil {
+			c.Response().Header().Add(echo.HeaderVary, headerAcceptLanguage)
+			c.Response().Header().Set(headerContentLanguage, trans.Locale())
+		}
+
+		// 401 responses must tell the scheme of the credentials
+		if problem.Status == http.StatusUnauthorized {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+		}
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(problem.Status)
+		} else {
+			err = writeProblem(c, problem)
+		}
+
+		if err != nil {
+			log.Errorf("Problem response cannot be written: %v", err.Error())
+		}
+	}
+}
+
+// toProblem => problem details of an error, the causes of the errors are never responded
+// => messages of the validation errors are translated by trans, they are the messages of the validator if it is nil
+func toProblem(err error, trans ut.Translator) errors.Problem {
+	if fieldErrors, ok := err.(validator.ValidationErrors); ok {
+		err = errValidation.WithFields(validationFields(fieldErrors, trans)).Wrap(err)
+	}
+
+	if e, ok := errors.As(err); ok {
+		return newProblem(e.Kind.Status(), e.Code, e.Message, e.Fields)
+	}
+
+	if he, ok := err.(*echo.HTTPError); ok {
+		return newProblem(he.Code, statusCode(he.Code), fmt.Sprint(he.Message), nil)
+	}
+
+	return newProblem(errInternal.Kind.Status(), errInternal.Code, errInternal.Message, nil)
+}
+
+func newProblem(status int, code string, detail string, fields []errors.FieldError) errors.Problem {
+	return errors.Problem{
+		Type:   problemTypeBase + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: fields,
+	}
+}
+
+// statusCode => code of the errors which have only a status, e.g. 405 => method_not_allowed
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return errors.CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.ReplaceAll(text, "-", " ")), " ", "_")
+}
+
+// writeProblem => problem is written as json whatever the negotiated format is
+func writeProblem(c echo.Context, problem errors.Problem) error {
+	data, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	return c.Blob(problem.Status, mimeProblemJSON, data)
+}
+
+// fieldParamTags => tags whose params are struct fields, they are responded with their json names like the fields
+var fieldParamTags = map[string]bool{
+	"required_with":        true,
+	"required_with_all":    true,
+	"required_without":     true,
+	"required_without_all": true,
+}
+
+// validationFields => errors of the validator with the json paths of the fields and their translated messages
+func validationFields(fieldErrors validator.ValidationErrors, trans ut.Translator) []errors.FieldError {
+	fields := make([]errors.FieldError, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		message := fieldError.Error()
+		if trans != nil {
+			message = fieldError.Translate(trans)
+		}
+
+		param := fieldError.Param()
+		if fieldParamTags[fieldError.Tag()] {
+			param = strings.ToLower(param)
+		}
+
+		fields = append(fields, errors.FieldError{
+			Field:   fieldPath(fieldError),
+			Rule:    fieldError.Tag(),
+			Param:   param,
+			Message: message,
+		})
+	}
+	return fields
+}
+
+// fieldPath => namespace of the field without the name of the request struct, e.g. tags[0]
+func fieldPath(fieldError validator.FieldError) string {
+	_, path, found := strings.Cut(fieldError.Namespace(), ".")
+	if !found {
+		return fieldError.Field()
+	}
+	return path
+}
@@ -0,0 +1,250 @@
+package app
+
+import (
+	"RestfulWithEcho/codec"
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/response"
+	"bytes"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// response formats which handlers can be rendered in
+const (
+	formatJSON    = "json"
+	formatXML     = "xml"
+	formatMsgpack = "msgpack"
+	formatCSV     = "csv"
+)
+
+// headerTotalCount => csv has no place for the metadata of a list, so total count is sent as a header
+const headerTotalCount = "X-Total-Count"
+
+// xmlRoot => root element of xml responses
+const xmlRoot = "response"
+
+// errNotAcceptable => none of the accepted media types can be rendered
+var errNotAcceptable = errors.New(errors.NotAcceptable, "not_acceptable", "not acceptable")
+
+// mediaTypes => supported media types in the order of preference, wildcards match the first one
+var mediaTypes = []struct {
+	mediaType string
+	format    string
+}{
+	{echo.MIMEApplicationJSON, formatJSON},
+	{echo.MIMEApplicationXML, formatXML},
+	{echo.MIMETextXML, formatXML},
+	{echo.MIMEApplicationMsgpack, formatMsgpack},
+	{"application/x-msgpack", formatMsgpack},
+	{"application/vnd.msgpack", formatMsgpack},
+	{"text/csv", formatCSV},
+}
+
+// rawRoutes => routes which write files themselves, their format is selected with query params
+var rawRoutes = map[string]bool{
+	"api/books/export":       true,
+	"api/imports/:id/errors": true,
+}
+
+// Negotiate => to render responses of c.JSON in the format of Accept header (json, xml, msgpack or csv for lists)
+// => handlers don't change, context is wrapped and its JSON method renders the selected format
+// => 406 is returned before the handler runs if none of the accepted types is supported
+func Negotiate() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := strings.TrimPrefix(c.Path(), "/")
+			if !strings.HasPrefix(path, "api/") || rawRoutes[path] {
+				return next(c)
+			}
+
+			c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+
+			mediaType, format := negotiate(c.Request().Header.Get(echo.HeaderAccept), c.Request().Method)
+			if format == "" {
+				return errNotAcceptable.Withf("responses can be %v", supportedTypes(c.Request().Method))
+			}
+			if format == formatJSON {
+				return next(c)
+			}
+
+			return next(&negotiatedContext{Context: c, format: format, mediaType: mediaType})
+		}
+	}
+}
+
+// negotiate => the supported media type with the highest q value, json if there is no Accept header
+// => csv can only be used for lists, so it is accepted only for GET requests
+func negotiate(accept string, method string) (string, string) {
+	if strings.TrimSpace(accept) == "" {
+		return echo.MIMEApplicationJSON, formatJSON
+	}
+
+	for _, accepted := range acceptedValues(accept) {
+		for _, supported := range mediaTypes {
+			if supported.format == formatCSV && method != http.MethodGet {
+				continue
+			}
+			if matchesMediaType(accepted, supported.mediaType) {
+				return supported.mediaType, supported.format
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// acceptedValues => values of an Accept style header (Accept, Accept-Language) in the order of their q values
+// => values with the same q value keep the order of the header, values with q=0 are not accepted
+func acceptedValues(header string) []string {
+	type candidate struct {
+		value string
+		q     float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		c := candidate{value: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(key) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					c.q = q
+				}
+			}
+		}
+		if c.value != "" && c.q > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	values := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		values = append(values, c.value)
+	}
+	return values
+}
+
+// matchesMediaType => accepted type can be a wildcard, e.g. */* or text/*
+func matchesMediaType(accepted string, mediaType string) bool {
+	if accepted == "*/*" || accepted == mediaType {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(accepted, "*"))
+	}
+	return false
+}
+
+func supportedTypes(method string) string {
+	var types []string
+	for _, supported := range mediaTypes {
+		if supported.format == formatCSV && method != http.MethodGet {
+			continue
+		}
+		types = append(types, supported.mediaType)
+	}
+	return strings.Join(types, ", ")
+}
+
+// negotiatedContext => c.JSON of the handlers renders the negotiated format
+type negotiatedContext struct {
+	echo.Context
+	format    string
+	mediaType string
+}
+
+func (c *negotiatedContext) JSON(code int, i interface{}) error {
+	var body bytes.Buffer
+	var err error
+
+	switch c.format {
+	case formatXML:
+		err = codec.WriteXML(&body, i, xmlRoot)
+	case formatMsgpack:
+		var data []byte
+		if data, err = codec.MarshalMsgpack(i); err == nil {
+			body.Write(data)
+		}
+	case formatCSV:
+		result, ok := listResult(i)
+		if !ok {
+			return errNotAcceptable.Withf("only lists can be sent as csv")
+		}
+
+		if err = codec.WriteCSV(&body, result.Data); err == codec.ErrNotList {
+			return errNotAcceptable.Withf("%v", err)
+		}
+		c.Response().Header().Set(headerTotalCount, strconv.Itoa(result.TotalItemCount))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	contentType := c.mediaType
+	if c.format != formatMsgpack {
+		contentType += "; charset=UTF-8"
+	}
+	return c.Blob(code, contentType, body.Bytes())
+}
+
+// listResult => list responses are response.JSONSuccessResultData
+func listResult(i interface{}) (response.JSONSuccessResultData, bool) {
+	switch result := i.(type) {
+	case response.JSONSuccessResultData:
+		return result, true
+	case *response.JSONSuccessResultData:
+		if result != nil {
+			return *result, true
+		}
+	}
+	return response.JSONSuccessResultData{}, false
+}
+
+// Binder => echo.DefaultBinder which can also decode msgpack bodies, json & xml are decoded by echo
+type Binder struct {
+	echo.DefaultBinder
+}
+
+func (b *Binder) Bind(i interface{}, c echo.Context) error {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if !isMsgpack(contentType) {
+		return b.DefaultBinder.Bind(i, c)
+	}
+
+	if err := b.BindPathParams(c, i); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	if err := codec.UnmarshalMsgpack(body, i); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+func isMsgpack(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for _, supported := range mediaTypes {
+		if supported.format == formatMsgpack && strings.EqualFold(strings.TrimSpace(mediaType), supported.mediaType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package app
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/response"
+	"RestfulWithEcho/service"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+type ReservationHandler struct {
+	Service service.IReservationService
+	Logger  *logrus.Logger
+}
+
+func NewReservationHandler(e *echo.Echo, service service.IReservationService, log *logrus.Logger) *ReservationHandler {
+	r := &ReservationHandler{Service: service, Logger: log}
+
+	//Routes
+	// reservations hold copies of the stock, so only inventory roles can handle them
+	e.POST("api/books/:id/reservations", r.CreateReservation, Authorize(rbac.ChangeStock))
+	router := e.Group("api/reservations")
+	router.GET("/:id", r.GetReservationById, Authorize(rbac.ReadBooks))
+	router.POST("/:id/confirm", r.ConfirmReservation, Authorize(rbac.ChangeStock))
+	router.POST("/:id/release", r.ReleaseReservation, Authorize(rbac.ChangeStock))
+
+	return r
+}
+
+// CreateReservation => To post request for holding copies of a book for a while
+
+// CreateReservation godoc
+// @Summary hold copies of a book item until they are confirmed, released or expired
+// @ID create-reservation
+// @Accept json
+// @Produce json
+// @Param id path string true "book ID"
+// @Param data body dtos.ReservationRequest true "reservation data, ttlseconds is optional"
+// @Success 201 {object} response.JSONSuccessResultData
+// @Success 400 {object} errors.Problem
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /books/{id}/reservations [post]
+func (h ReservationHandler) CreateReservation(c echo.Context) error {
+	query := c.Param("id")
+
+	var reservationRequest dtos.ReservationRequest
+
+	// we parse the data as json into the struct
+	if err := c.Bind(&reservationRequest); err != nil {
+		return err
+	}
+
+	if err := c.Validate(reservationRequest); err != nil {
+		return err
+	}
+
+	ttl := time.Duration(reservationRequest.TTLSeconds) * time.Second
+	reservation, err := h.Service.Reserve(c.Request().Context(), query, reservationRequest.Quantity, ttl)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toReservationResponse(reservation),
+	}
+
+	h.Logger.Infof("{%v} with id reservation is created for {%v} with id.", reservation.ID, query)
+	return c.JSON(http.StatusCreated, jsonSuccessResultData)
+}
+
+// GetReservationById => To get request find a reservation by id
+
+// GetReservationById godoc
+// @Summary get a reservation by ID
+// @ID get-reservation-by-id
+// @Produce json
+// @Param id path string true "reservation ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /reservations/{id} [get]
+func (h ReservationHandler) GetReservationById(c echo.Context) error {
+	query := c.Param("id")
+
+	reservation, err := h.Service.GetById(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toReservationResponse(reservation),
+	}
+
+	h.Logger.Infof("{%v} with id reservation is listed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// ConfirmReservation => To post request for converting a reservation into a sale
+
+// ConfirmReservation godoc
+// @Summary confirm a reservation, held copies are decremented from the quantity
+// @ID confirm-reservation
+// @Produce json
+// @Param id path string true "reservation ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /reservations/{id}/confirm [post]
+func (h ReservationHandler) ConfirmReservation(c echo.Context) error {
+	query := c.Param("id")
+
+	reservation, err := h.Service.Confirm(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toReservationResponse(reservation),
+	}
+
+	h.Logger.Infof("{%v} with id reservation is confirmed.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// ReleaseReservation => To post request for giving held copies back
+
+// ReleaseReservation godoc
+// @Summary release a reservation, held copies are available again
+// @ID release-reservation
+// @Produce json
+// @Param id path string true "reservation ID"
+// @Success 200 {object} response.JSONSuccessResultData
+// @Success 401 {object} errors.Problem
+// @Success 403 {object} errors.Problem
+// @Success 404 {object} errors.Problem
+// @Success 409 {object} errors.Problem
+// @Success 500 {object} errors.Problem
+// @Security BearerAuth
+// @Router /reservations/{id}/release [post]
+func (h ReservationHandler) ReleaseReservation(c echo.Context) error {
+	query := c.Param("id")
+
+	reservation, err := h.Service.Release(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	jsonSuccessResultData := response.JSONSuccessResultData{
+		TotalItemCount: 1,
+		Data:           toReservationResponse(reservation),
+	}
+
+	h.Logger.Infof("{%v} with id reservation is released.", query)
+	return c.JSON(http.StatusOK, jsonSuccessResultData)
+}
+
+// toReservationResponse => mapping from model to response dto
+func toReservationResponse(reservation models.Reservation) dtos.ReservationResponse {
+	reservationResponse := dtos.ReservationResponse{
+		ID:          reservation.ID,
+		BookID:      reservation.BookID,
+		Quantity:    reservation.Quantity,
+		Status:      reservation.Status,
+		CreatedDate: reservation.CreatedDate.Time(),
+		ExpiresAt:   reservation.ExpiresAt.Time(),
+	}
+	if reservation.FinishedDate != nil {
+		finishedDate := reservation.FinishedDate.Time()
+		reservationResponse.FinishedDate = &finishedDate
+	}
+
+	return reservationResponse
+}
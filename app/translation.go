@@ -0,0 +1,89 @@
+package app
+
+import (
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/tr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	trTranslations "github.com/go-playground/validator/v10/translations/tr"
+	"github.com/labstack/echo/v4"
+	"strings"
+)
+
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+// customTranslations => messages of the tags which have no default translation, {0} is the field & {1} is the param
+// => placeholders must be in order in every language, translator cannot replace them otherwise
+var customTranslations = map[string]map[string]string{
+	"required_without": {
+		"en": "{0} is required if {1} is empty",
+		"tr": "{0} alanı, {1} boş olduğunda zorunludur",
+	},
+}
+
+// registerTranslations => message catalogs of the validation errors in english & turkish, english is the fallback
+func registerTranslations(v *validator.Validate) (*ut.UniversalTranslator, error) {
+	english := en.New()
+	translator := ut.New(english, english, tr.New())
+
+	defaults := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": enTranslations.RegisterDefaultTranslations,
+		"tr": trTranslations.RegisterDefaultTranslations,
+	}
+
+	for locale, register := range defaults {
+		trans, _ := translator.GetTranslator(locale)
+
+		if err := register(v, trans); err != nil {
+			return nil, err
+		}
+
+		for tag, messages := range customTranslations {
+			if err := registerTranslation(v, trans, tag, messages[locale]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return translator, nil
+}
+
+func registerTranslation(v *validator.Validate, trans ut.Translator, tag string, message string) error {
+	return v.RegisterTranslation(tag, trans, func(t ut.Translator) error {
+		return t.Add(tag, message, true)
+	}, func(t ut.Translator, fe validator.FieldError) string {
+		// params are struct field names (e.g. AuthorIDs), json names of the fields are their lower cases
+		translated, err := t.T(fe.Tag(), fe.Field(), strings.ToLower(fe.Param()))
+		if err != nil {
+			return fe.Error()
+		}
+		return translated
+	})
+}
+
+// Translator => translator of the first language of Accept-Language which has a catalog, english if there is none
+// => regional languages use the catalog of their language, e.g. tr-TR => tr
+func (b *BookValidator) Translator(acceptLanguage string) ut.Translator {
+	var locales []string
+	for _, language := range acceptedValues(acceptLanguage) {
+		locales = append(locales, strings.ReplaceAll(language, "-", "_"))
+		if base, _, regional := strings.Cut(language, "-"); regional {
+			locales = append(locales, base)
+		}
+	}
+
+	trans, _ := b.translator.FindTranslator(locales...)
+	return trans
+}
+
+// translatorOf => translator of the validator of echo in the language of the request, nil for other validators
+func translatorOf(c echo.Context) ut.Translator {
+	if v, ok := c.Echo().Validator.(*BookValidator); ok {
+		return v.Translator(c.Request().Header.Get(headerAcceptLanguage))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package codec
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ListSeparator => lists of scalars are written in a single cell, e.g. tags => "go;web"
+const ListSeparator = ";"
+
+// ErrNotList => only lists can be written as csv
+var ErrNotList = errors.New("only lists can be written as csv")
+
+// WriteCSV => csv of a list, columns are the json names of the items in the order they are first seen
+// => nested objects are written as json text, so no value is lost
+func WriteCSV(w io.Writer, items interface{}) error {
+	value, err := toOrdered(items)
+	if err != nil {
+		return err
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return ErrNotList
+	}
+
+	var columns []string
+	index := map[string]int{}
+	for _, item := range list {
+		row, ok := item.(object)
+		if !ok {
+			return ErrNotList
+		}
+		for _, f := range row {
+			if _, ok := index[f.key]; !ok {
+				index[f.key] = len(columns)
+				columns = append(columns, f.key)
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	for _, item := range list {
+		record := make([]string, len(columns))
+		for _, f := range item.(object) {
+			if record[index[f.key]], err = csvCell(f.value); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	case []interface{}:
+		cells := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, nested := item.(object); nested {
+				return jsonText(value)
+			}
+			if _, nested := item.([]interface{}); nested {
+				return jsonText(value)
+			}
+			cell, _ := csvCell(item)
+			cells = append(cells, cell)
+		}
+		return strings.Join(cells, ListSeparator), nil
+	}
+
+	return jsonText(value)
+}
+
+// jsonText => json of an ordered value, keys are written in their order
+func jsonText(value interface{}) (string, error) {
+	var b strings.Builder
+	if err := writeJSON(&b, value); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func writeJSON(b *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case object:
+		b.WriteString("{")
+		for i, f := range v {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			key, _ := json.Marshal(f.key)
+			b.Write(key)
+			b.WriteString(":")
+			if err := writeJSON(b, f.value); err != nil {
+				return err
+			}
+		}
+		b.WriteString("}")
+		return nil
+	case []interface{}:
+		b.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if err := writeJSON(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteString("]")
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	b.Write(data)
+	return nil
+}
@@ -0,0 +1,84 @@
+package codec
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	type row struct {
+		Title string            `json:"title"`
+		Count int               `json:"count,omitempty"`
+		Tags  []string          `json:"tags,omitempty"`
+		Extra map[string]string `json:"extra,omitempty"`
+	}
+
+	tests := []struct {
+		name  string
+		items interface{}
+		text  string
+	}{
+		{name: "plain cells", items: []row{{Title: "Dune", Count: 2}}, text: "title,count\nDune,2\n"},
+		{name: "comma is quoted", items: []row{{Title: "Dune, Messiah"}}, text: "title\n\"Dune, Messiah\"\n"},
+		{name: "quote is doubled", items: []row{{Title: `The "Best" Book`}}, text: "title\n\"The \"\"Best\"\" Book\"\n"},
+		{name: "newline is quoted", items: []row{{Title: "first\nsecond"}}, text: "title\n\"first\nsecond\"\n"},
+		{name: "list in one cell", items: []row{{Title: "Dune", Tags: []string{"scifi", "classic"}}},
+			text: "title,tags\nDune,scifi;classic\n"},
+		{name: "nested object as json", items: []row{{Title: "Dune", Extra: map[string]string{"a": `x,"y"`}}},
+			text: "title,extra\nDune,\"{\"\"a\"\":\"\"x,\\\"\"y\\\"\"\"\"}\"\n"},
+		// columns are in the order they are first seen, missing cells are empty
+		{name: "columns of all rows", items: []row{{Title: "A"}, {Title: "B", Count: 1}}, text: "title,count\nA,\nB,1\n"},
+		{name: "nested list as json", items: []map[string]interface{}{{"a": [][]int{{1, 2}}}}, text: "a\n\"[[1,2]]\"\n"},
+		{name: "empty list", items: []row{}, text: "\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := WriteCSV(&b, test.items); err != nil {
+				t.Fatalf("csv cannot be written: %v", err)
+			}
+			if b.String() != test.text {
+				t.Fatalf("expected %q, got %q", test.text, b.String())
+			}
+		})
+	}
+}
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	type row struct {
+		Title  string `json:"title"`
+		Author string `json:"author"`
+	}
+	rows := []row{
+		{Title: `He said "hi", then left`, Author: "a\r\nb"},
+		{Title: " leading space", Author: `"`},
+		{Title: "", Author: ",,,"},
+	}
+
+	var b strings.Builder
+	if err := WriteCSV(&b, rows); err != nil {
+		t.Fatalf("csv cannot be written: %v", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("csv cannot be read: %v", err)
+	}
+
+	// csv reader gives \r\n in a quoted cell as \n
+	expected := [][]string{{"title", "author"}, {`He said "hi", then left`, "a\nb"}, {" leading space", `"`}, {"", ",,,"}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("expected %q, got %q", expected, records)
+	}
+}
+
+func TestWriteCSVRejectsNonLists(t *testing.T) {
+	for _, items := range []interface{}{map[string]int{"a": 1}, []int{1, 2}, "text"} {
+		if err := WriteCSV(&strings.Builder{}, items); err != ErrNotList {
+			t.Errorf("%v: expected %v, got %v", items, ErrNotList, err)
+		}
+	}
+}
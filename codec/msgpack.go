@@ -0,0 +1,271 @@
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidMsgpack => body is not a MessagePack value which can be converted into json (e.g. non string map keys)
+var ErrInvalidMsgpack = errors.New("invalid msgpack")
+
+// MarshalMsgpack => MessagePack of the value with the json names as map keys
+func MarshalMsgpack(v interface{}) ([]byte, error) {
+	value, err := toOrdered(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendMsgpack(nil, value)
+}
+
+// UnmarshalMsgpack => to decode a MessagePack body into v by the json names, so request dtos are the same for every format
+func UnmarshalMsgpack(data []byte, v interface{}) error {
+	reader := &msgpackReader{data: data}
+
+	value, err := reader.value()
+	if err != nil {
+		return err
+	}
+	if reader.offset != len(data) {
+		return fmt.Errorf("%w: extra bytes after the value", ErrInvalidMsgpack)
+	}
+
+	document, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(document, v)
+}
+
+func appendMsgpack(b []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case string:
+		return appendString(b, v), nil
+	case []interface{}:
+		b = appendHeader(b, len(v), 0x90, 0xdc, 0xdd)
+		for _, item := range v {
+			var err error
+			if b, err = appendMsgpack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case object:
+		b = appendHeader(b, len(v), 0x80, 0xde, 0xdf)
+		for _, f := range v {
+			b = appendString(b, f.key)
+			var err error
+			if b, err = appendMsgpack(b, f.value); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value %T", value)
+}
+
+// appendInt => the smallest int format of the value
+func appendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(i)))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(i)))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+func appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendHeader => header of an array or a map, fix is the format of the lengths up to 15
+func appendHeader(b []byte, n int, fix byte, format16 byte, format32 byte) []byte {
+	switch {
+	case n <= 15:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, format16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, format32), uint32(n))
+}
+
+// msgpackReader => decodes MessagePack into the values of encoding/json (map[string]interface{}, []interface{} ...)
+type msgpackReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidMsgpack)
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b, nil
+}
+
+// uint => a big endian unsigned number of size bytes
+func (r *msgpackReader) uint(size int) (uint64, error) {
+	b, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (r *msgpackReader) value() (interface{}, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := b[0]; {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return r.str(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return r.array(int(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return r.object(int(c & 0x0f))
+	}
+
+	switch c := b[0]; c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := r.uint(1 << (c - 0xcc))
+		return n, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := r.uint(size)
+		// sign extension of the smaller ints
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xca:
+		n, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := r.uint(8)
+		return math.Float64frombits(n), err
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		// bin is accepted as string, some encoders write strings as bin
+		sizes := map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4, 0xc4: 1, 0xc5: 2, 0xc6: 4}
+		n, err := r.uint(sizes[c])
+		if err != nil {
+			return nil, err
+		}
+		return r.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.array(int(n))
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.object(int(n))
+	}
+
+	return nil, fmt.Errorf("%w: unsupported format 0x%x", ErrInvalidMsgpack, b[0])
+}
+
+func (r *msgpackReader) str(n int) (interface{}, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (r *msgpackReader) array(n int) (interface{}, error) {
+	// every item has at least one byte, so the length cannot be more than the remaining bytes
+	if n > len(r.data)-r.offset {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidMsgpack)
+	}
+
+	list := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		value, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+func (r *msgpackReader) object(n int) (interface{}, error) {
+	if n > len(r.data)-r.offset {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidMsgpack)
+	}
+
+	fields := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: map keys must be strings", ErrInvalidMsgpack)
+		}
+		if fields[name], err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,201 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMsgpackIntegers(t *testing.T) {
+	tests := []struct {
+		value   int64
+		encoded string
+	}{
+		{value: 0, encoded: "00"},
+		{value: 127, encoded: "7f"},
+		{value: 128, encoded: "d10080"},
+		{value: -1, encoded: "ff"},
+		{value: -32, encoded: "e0"},
+		{value: -33, encoded: "d0df"},
+		{value: -128, encoded: "d080"},
+		{value: -129, encoded: "d1ff7f"},
+		{value: math.MaxInt16, encoded: "d17fff"},
+		{value: math.MaxInt16 + 1, encoded: "d200008000"},
+		{value: math.MinInt32, encoded: "d280000000"},
+		{value: math.MaxInt32 + 1, encoded: "d30000000080000000"},
+		{value: math.MinInt64, encoded: "d38000000000000000"},
+	}
+
+	for _, test := range tests {
+		data, err := MarshalMsgpack(test.value)
+		if err != nil || hex.EncodeToString(data) != test.encoded {
+			t.Errorf("%d: expected %v, got %x (%v)", test.value, test.encoded, data, err)
+			continue
+		}
+
+		var decoded int64
+		if err := UnmarshalMsgpack(data, &decoded); err != nil || decoded != test.value {
+			t.Errorf("%d: decoded as %d (%v)", test.value, decoded, err)
+		}
+	}
+}
+
+func TestMsgpackStringLengths(t *testing.T) {
+	tests := []struct {
+		length int
+		header string
+	}{
+		{length: 0, header: "a0"},
+		{length: 31, header: "bf"},
+		{length: 32, header: "d920"},
+		{length: math.MaxUint8, header: "d9ff"},
+		{length: math.MaxUint8 + 1, header: "da0100"},
+		{length: math.MaxUint16, header: "daffff"},
+		{length: math.MaxUint16 + 1, header: "db00010000"},
+	}
+
+	for _, test := range tests {
+		text := strings.Repeat("a", test.length)
+
+		data, err := MarshalMsgpack(text)
+		if err != nil {
+			t.Fatalf("%d: %v", test.length, err)
+		}
+		header, _ := hex.DecodeString(test.header)
+		if !bytes.HasPrefix(data, header) || len(data) != len(header)+test.length {
+			t.Errorf("%d: expected %v header, got %x", test.length, test.header, data[:len(header)])
+			continue
+		}
+
+		var decoded string
+		if err := UnmarshalMsgpack(data, &decoded); err != nil || decoded != text {
+			t.Errorf("%d: string is not decoded (%v)", test.length, err)
+		}
+	}
+}
+
+func TestMsgpackContainerLengths(t *testing.T) {
+	tests := []struct {
+		length int
+		object string
+		array  string
+	}{
+		{length: 0, object: "80", array: "90"},
+		{length: 15, object: "8f", array: "9f"},
+		{length: 16, object: "de0010", array: "dc0010"},
+		{length: math.MaxUint16 + 1, object: "df00010000", array: "dd00010000"},
+	}
+
+	for _, test := range tests {
+		fields := make(map[string]int, test.length)
+		list := make([]int, test.length)
+		for i := 0; i < test.length; i++ {
+			fields[fmt.Sprintf("key%d", i)] = i
+			list[i] = i
+		}
+
+		for name, value := range map[string]interface{}{test.object: fields, test.array: list} {
+			data, err := MarshalMsgpack(value)
+			if err != nil {
+				t.Fatalf("%d: %v", test.length, err)
+			}
+			if header, _ := hex.DecodeString(name); !bytes.HasPrefix(data, header) {
+				t.Errorf("%d: expected %v header, got %x", test.length, name, data[:len(header)])
+				continue
+			}
+
+			decoded := reflect.New(reflect.TypeOf(value))
+			if err := UnmarshalMsgpack(data, decoded.Interface()); err != nil || !reflect.DeepEqual(decoded.Elem().Interface(), value) {
+				t.Errorf("%d: %T is not decoded (%v)", test.length, value, err)
+			}
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	type author struct {
+		Name string `json:"name"`
+	}
+	type book struct {
+		Title    string            `json:"title"`
+		Quantity int               `json:"quantity"`
+		Price    float64           `json:"price"`
+		Active   bool              `json:"active"`
+		Tags     []string          `json:"tags"`
+		Authors  []author          `json:"authors"`
+		Extra    map[string]string `json:"extra"`
+		Missing  *string           `json:"missing"`
+	}
+
+	original := book{Title: "Dune ☂", Quantity: -3, Price: 9.75, Active: true, Tags: []string{"a", ""},
+		Authors: []author{{Name: "Frank"}}, Extra: map[string]string{"k": "v"}}
+
+	data, err := MarshalMsgpack(original)
+	if err != nil {
+		t.Fatalf("book cannot be encoded: %v", err)
+	}
+
+	var decoded book
+	if err := UnmarshalMsgpack(data, &decoded); err != nil {
+		t.Fatalf("book cannot be decoded: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Fatalf("expected %+v, got %+v", original, decoded)
+	}
+}
+
+func TestUnmarshalMsgpackFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		value   interface{}
+	}{
+		{name: "uint8", encoded: "ccff", value: float64(255)},
+		{name: "uint64", encoded: "cfffffffffffffffff", value: float64(math.MaxUint64)},
+		{name: "int16", encoded: "d1ff7f", value: float64(-129)},
+		{name: "float32", encoded: "ca3fc00000", value: 1.5},
+		{name: "nil", encoded: "c0", value: nil},
+		{name: "bin as string", encoded: "c4026869", value: "hi"},
+		{name: "map16", encoded: "de0001a16101", value: map[string]interface{}{"a": float64(1)}},
+	}
+
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.encoded)
+
+		var value interface{}
+		if err := UnmarshalMsgpack(data, &value); err != nil || !reflect.DeepEqual(value, test.value) {
+			t.Errorf("%v: expected %v, got %v (%v)", test.name, test.value, value, err)
+		}
+	}
+}
+
+func TestUnmarshalMsgpackRejectsInvalidData(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "empty", encoded: ""},
+		{name: "truncated string", encoded: "a36162"},
+		{name: "truncated int", encoded: "d200"},
+		{name: "extra bytes", encoded: "0101"},
+		{name: "integer map key", encoded: "810101"},
+		{name: "array longer than data", encoded: "ddffffffff01"},
+		{name: "map longer than data", encoded: "dfffffffff"},
+		{name: "string longer than data", encoded: "dbffffffff61"},
+		{name: "unsupported format", encoded: "c1"},
+	}
+
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.encoded)
+
+		var value interface{}
+		if err := UnmarshalMsgpack(data, &value); !errors.Is(err, ErrInvalidMsgpack) {
+			t.Errorf("%v: expected %v, got %v", test.name, ErrInvalidMsgpack, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// every format is produced from the json of a value, so field names and omitempty are the same as in json responses
+// => json is decoded into these values, keys of objects keep their order:
+// nil, bool, json.Number, string, []interface{} and object
+
+// field => a key & value of a json object
+type field struct {
+	key   string
+	value interface{}
+}
+
+// object => a json object with the order of its keys
+type object []field
+
+// toOrdered => json of the value as ordered values
+func toOrdered(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decodeValue(decoder)
+}
+
+func decodeValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		// nil, bool, json.Number or string
+		return token, nil
+	}
+
+	switch delim {
+	case '[':
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+
+	case '{':
+		fields := object{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field{key: key.(string), value: value})
+		}
+		_, err = decoder.Token()
+		return fields, err
+	}
+
+	return nil, fmt.Errorf("unexpected json token %v", delim)
+}
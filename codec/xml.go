@@ -0,0 +1,81 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// XMLItem => element name of the items of a list, the request dtos use the same name, e.g. xml:"tags>item"
+const XMLItem = "item"
+
+// WriteXML => xml of the value with the json names as element names under the root element
+// => lists are written as item elements, null values as empty elements
+func WriteXML(w io.Writer, v interface{}, root string) error {
+	value, err := toOrdered(v)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+	writer.WriteString(xml.Header)
+	if err := writeElement(writer, root, value); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+func writeElement(w *bufio.Writer, name string, value interface{}) error {
+	name = xmlName(name)
+
+	w.WriteString("<" + name + ">")
+
+	switch v := value.(type) {
+	case object:
+		for _, f := range v {
+			if err := writeElement(w, f.key, f.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := writeElement(w, XMLItem, item); err != nil {
+				return err
+			}
+		}
+	case string:
+		if err := xml.EscapeText(w, []byte(v)); err != nil {
+			return err
+		}
+	case json.Number:
+		w.WriteString(v.String())
+	case bool:
+		if v {
+			w.WriteString("true")
+		} else {
+			w.WriteString("false")
+		}
+	}
+
+	_, err := w.WriteString("</" + name + ">")
+	return err
+}
+
+// xmlName => json keys can have characters which cannot be in an element name (e.g. map keys), they are replaced with _
+func xmlName(name string) string {
+	valid := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	if valid == "" || valid[0] >= '0' && valid[0] <= '9' || valid[0] == '-' || valid[0] == '.' {
+		valid = "_" + valid
+	}
+
+	return valid
+}
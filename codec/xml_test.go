@@ -0,0 +1,61 @@
+package codec
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteXML(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		xml   string
+	}{
+		{name: "escaped text", value: map[string]string{"title": `<a & "b" 'c'>`},
+			xml: "<book><title>&lt;a &amp; &#34;b&#34; &#39;c&#39;&gt;</title></book>"},
+		{name: "list items", value: map[string][]int{"tags": {1, 2}}, xml: "<book><tags><item>1</item><item>2</item></tags></book>"},
+		{name: "null is empty", value: map[string]interface{}{"author": nil, "active": false},
+			xml: "<book><active>false</active><author></author></book>"},
+		{name: "invalid names", value: map[string]int{"1st": 1, "a b<c>": 2, "": 3, "-x": 4},
+			xml: "<book><_>3</_><_-x>4</_-x><_1st>1</_1st><a_b_c_>2</a_b_c_></book>"},
+		{name: "list root", value: []string{"a"}, xml: "<book><item>a</item></book>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := WriteXML(&b, test.value, "book"); err != nil {
+				t.Fatalf("xml cannot be written: %v", err)
+			}
+			if expected := xml.Header + test.xml; b.String() != expected {
+				t.Fatalf("expected %q, got %q", expected, b.String())
+			}
+		})
+	}
+}
+
+func TestWriteXMLRoundTrip(t *testing.T) {
+	type book struct {
+		XMLName  xml.Name `xml:"book"`
+		Title    string   `json:"title" xml:"title"`
+		Quantity int      `json:"quantity" xml:"quantity"`
+		Tags     []string `json:"tags" xml:"tags>item"`
+	}
+	original := book{XMLName: xml.Name{Local: "book"}, Title: "Tom & Jerry <\"1\"> ]]>\n", Quantity: 3,
+		Tags: []string{"a&b", "</item>"}}
+
+	var b strings.Builder
+	if err := WriteXML(&b, original, "book"); err != nil {
+		t.Fatalf("xml cannot be written: %v", err)
+	}
+
+	var decoded book
+	if err := xml.Unmarshal([]byte(b.String()), &decoded); err != nil {
+		t.Fatalf("xml cannot be read: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Fatalf("expected %+v, got %+v", original, decoded)
+	}
+}
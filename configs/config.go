@@ -10,6 +10,48 @@ type Config struct {
 		DatabaseName   string
 		CollectionName string
 	}
+	Trash struct {
+		// books in trash are purged after this many days
+		RetentionDays int
+	}
+	Inventory struct {
+		// books with quantity less than or equal to this are listed as low in stock
+		LowStockThreshold int
+	}
+	Reservation struct {
+		DefaultTTLSeconds int
+		MaxTTLSeconds     int
+		// expired reservations give their copies back in this period
+		ReaperIntervalSeconds int
+	}
+	Lending struct {
+		// a loan is overdue after this many days
+		LoanDays int
+		// loan limit of a member without its own limit
+		DefaultMaxLoans int
+	}
+	Hold struct {
+		// a promoted hold keeps its copy this many hours
+		PickupHours int
+		// expired holds give their copies to the next members in this period
+		ReaperIntervalSeconds int
+	}
+	Auth struct {
+		// HMAC key of the access tokens, JWT_SECRET of the environment overrides it
+		// => a random key is generated on boot if both are empty, tokens are invalid after a restart then
+		SigningKey         string
+		Issuer             string
+		AccessTokenMinutes int
+		RefreshTokenDays   int
+	}
+	Tenant struct {
+		// tenant of a request is the tenant of its access token or api key, X-Tenant-ID header or subdomain of BaseDomain
+		// must be the same tenant, requests without credentials cannot choose a tenant
+		// => acme.books.example.com is acme when BaseDomain is books.example.com, subdomains are not used if it is empty
+		BaseDomain string
+		// requests without credentials are rejected instead of using the default tenant
+		Required bool
+	}
 }
 
 var Configs = map[string]Config{
@@ -30,6 +72,56 @@ var Configs = map[string]Config{
 			DatabaseName:   "booksDB",
 			CollectionName: "books",
 		},
+		Trash: struct {
+			RetentionDays int
+		}{
+			RetentionDays: 30,
+		},
+		Inventory: struct {
+			LowStockThreshold int
+		}{
+			LowStockThreshold: 5,
+		},
+		Reservation: struct {
+			DefaultTTLSeconds     int
+			MaxTTLSeconds         int
+			ReaperIntervalSeconds int
+		}{
+			DefaultTTLSeconds:     15 * 60,
+			MaxTTLSeconds:         60 * 60,
+			ReaperIntervalSeconds: 30,
+		},
+		Lending: struct {
+			LoanDays        int
+			DefaultMaxLoans int
+		}{
+			LoanDays:        14,
+			DefaultMaxLoans: 5,
+		},
+		Hold: struct {
+			PickupHours           int
+			ReaperIntervalSeconds int
+		}{
+			PickupHours:           48,
+			ReaperIntervalSeconds: 60,
+		},
+		Auth: struct {
+			SigningKey         string
+			Issuer             string
+			AccessTokenMinutes int
+			RefreshTokenDays   int
+		}{
+			Issuer:             "RestfulWithEcho",
+			AccessTokenMinutes: 15,
+			RefreshTokenDays:   14,
+		},
+		Tenant: struct {
+			BaseDomain string
+			Required   bool
+		}{
+			BaseDomain: "",
+			Required:   false,
+		},
 	},
 	"qa":   {},
 	"prod": {},
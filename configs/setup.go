@@ -16,8 +16,13 @@ func ConnectDB(URI string) *mongo.Client {
 		log.Fatalln(err)
 	}
 	// If don't connect within 20 seconds, give us an error
-	var ctx, _ = context.WithTimeout(context.Background(), 20*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
 	err = client.Connect(ctx)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	err = client.Ping(ctx, nil)
 	if err != nil {
@@ -1,25 +1,271 @@
 package dtos
 
+import (
+	"RestfulWithEcho/errors"
+	"time"
+)
+
 // proje ismi types klasör app
 
+// author can be empty if authorids are given, names of the authors are used then
+// => xml names are the same as json names, lists are item elements, e.g. <tags><item>go</item></tags>
 type BookCreateRequest struct {
-	Title    string `json:"title" validate:"required,min=1,max=100"`
-	Author   string `json:"author" validate:"required,min=1,max=100"`
-	Quantity int    `json:"quantity" validate:"required"`
+	Title     string   `json:"title" xml:"title" validate:"required,min=1,max=100"`
+	Author    string   `json:"author" xml:"author" validate:"required_without=AuthorIDs,max=100"`
+	AuthorIDs []string `json:"authorids" xml:"authorids>item" validate:"omitempty,dive,required"`
+	ISBN10    string   `json:"isbn10" xml:"isbn10" validate:"omitempty,isbn10"`
+	ISBN13    string   `json:"isbn13" xml:"isbn13" validate:"omitempty,isbn13"`
+	Category  string   `json:"category" xml:"category" validate:"max=200"`
+	Tags      []string `json:"tags" xml:"tags>item" validate:"max=20,dive,min=1,max=30"`
+	Quantity  int      `json:"quantity" xml:"quantity" validate:"min=0"`
 }
 
 type BookUpdateRequest struct {
-	ID       string `json:"id" validate:"required"`
-	Title    string `json:"title" validate:"required,min=1,max=100"`
-	Author   string `json:"author" validate:"required,min=1,max=100"`
-	Quantity int    `json:"quantity" validate:"required"`
+	ID        string   `json:"id" xml:"id" validate:"required"`
+	Title     string   `json:"title" xml:"title" validate:"required,min=1,max=100"`
+	Author    string   `json:"author" xml:"author" validate:"required_without=AuthorIDs,max=100"`
+	AuthorIDs []string `json:"authorids" xml:"authorids>item" validate:"omitempty,dive,required"`
+	ISBN10    string   `json:"isbn10" xml:"isbn10" validate:"omitempty,isbn10"`
+	ISBN13    string   `json:"isbn13" xml:"isbn13" validate:"omitempty,isbn13"`
+	Category  string   `json:"category" xml:"category" validate:"max=200"`
+	Tags      []string `json:"tags" xml:"tags>item" validate:"max=20,dive,min=1,max=30"`
+	Quantity  int      `json:"quantity" xml:"quantity" validate:"min=0"`
+}
+
+// BookUpsertRequest => an item of bulk upsert, the book is created with the given id if it doesn't exist
+type BookUpsertRequest struct {
+	ID string `json:"id" validate:"required"`
+	BookCreateRequest
+}
+
+// BulkItemResponse => result of an item of a bulk request, index is its position in the request
+type BulkItemResponse struct {
+	Index   int                 `json:"index"`
+	ID      string              `json:"id,omitempty"`
+	Status  int                 `json:"status"`
+	Code    string              `json:"code,omitempty"` // code of the error, like the code of the problem responses
+	Message string              `json:"message,omitempty"`
+	Errors  []errors.FieldError `json:"errors,omitempty"` // validation errors of the fields
+}
+
+// ImportResponse => status of an import job, report is the url of its error report if some rows failed
+type ImportResponse struct {
+	ID           string     `json:"id"`
+	Status       string     `json:"status"`
+	FileName     string     `json:"filename"`
+	Format       string     `json:"format"`
+	DryRun       bool       `json:"dryrun"`
+	Upsert       bool       `json:"upsert"`
+	Total        int        `json:"total"`
+	Processed    int        `json:"processed"`
+	Created      int        `json:"created"`
+	Updated      int        `json:"updated"`
+	Failed       int        `json:"failed"`
+	Error        string     `json:"error,omitempty"`
+	Report       string     `json:"report,omitempty"`
+	CreatedDate  time.Time  `json:"createddate"`
+	FinishedDate *time.Time `json:"finisheddate,omitempty"`
+}
+
+// BookPatchDocument => patchable fields of a book, PATCH applies the patch to it and validates the result
+type BookPatchDocument struct {
+	Title     string   `json:"title" validate:"required,min=1,max=100"`
+	Author    string   `json:"author" validate:"required_without=AuthorIDs,max=100"`
+	AuthorIDs []string `json:"authorids" validate:"omitempty,dive,required"`
+	ISBN10    string   `json:"isbn10" validate:"omitempty,isbn10"`
+	ISBN13    string   `json:"isbn13" validate:"omitempty,isbn13"`
+	Category  string   `json:"category" validate:"max=200"`
+	Tags      []string `json:"tags" validate:"max=20,dive,min=1,max=30"`
+	Quantity  int      `json:"quantity" validate:"min=0"`
 }
 
 type BookResponse struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	Author   string `json:"author"`
-	Quantity int    `json:"quantity"`
+	ID        string           `json:"id"`
+	Title     string           `json:"title"`
+	Author    string           `json:"author"`
+	AuthorIDs []string         `json:"authorids,omitempty"`
+	Authors   []AuthorResponse `json:"authors,omitempty"` // only with ?expand=authors
+	ISBN10    string           `json:"isbn10,omitempty"`
+	ISBN13    string           `json:"isbn13,omitempty"`
+	Category  string           `json:"category,omitempty"`
+	Tags      []string         `json:"tags,omitempty"`
+	Quantity  int              `json:"quantity"`
+	Version   int              `json:"version"`
+	Reserved  int              `json:"reserved"`
+	Available int              `json:"available"`
+	DeletedAt *time.Time       `json:"deletedat,omitempty"` // only for books in trash
+}
+
+type BookSearchResponse struct {
+	BookResponse
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type AuditRecordResponse struct {
+	ID         string                `json:"id"`
+	Date       time.Time             `json:"date"`
+	EntityType string                `json:"entitytype"`
+	EntityID   string                `json:"entityid"`
+	Action     string                `json:"action"`
+	Actor      string                `json:"actor"`
+	RequestID  string                `json:"requestid,omitempty"`
+	Changes    []FieldChangeResponse `json:"changes"`
+}
+
+type FieldChangeResponse struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type StockMovementRequest struct {
+	Type     string `json:"type" validate:"required,oneof=increment decrement"`
+	Quantity int    `json:"quantity" validate:"required,min=1"`
+	Reason   string `json:"reason" validate:"required,oneof=restock sale damage adjustment"`
+	Note     string `json:"note" validate:"max=250"`
+}
+
+type StockMovementResponse struct {
+	ID            string    `json:"id"`
+	BookID        string    `json:"bookid"`
+	Date          time.Time `json:"date"`
+	Type          string    `json:"type"`
+	Reason        string    `json:"reason"`
+	Quantity      int       `json:"quantity"`
+	QuantityAfter int       `json:"quantityafter"`
+	Note          string    `json:"note,omitempty"`
+	Actor         string    `json:"actor"`
+}
+
+type ReservationRequest struct {
+	Quantity   int `json:"quantity" validate:"required,min=1"`
+	TTLSeconds int `json:"ttlseconds" validate:"min=0"`
+}
+
+type ReservationResponse struct {
+	ID           string     `json:"id"`
+	BookID       string     `json:"bookid"`
+	Quantity     int        `json:"quantity"`
+	Status       string     `json:"status"`
+	CreatedDate  time.Time  `json:"createddate"`
+	ExpiresAt    time.Time  `json:"expiresat"`
+	FinishedDate *time.Time `json:"finisheddate,omitempty"`
+}
+
+type MemberCreateRequest struct {
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	MaxLoans int    `json:"maxloans" validate:"min=0"`
+}
+
+type MemberUpdateRequest struct {
+	ID       string `json:"id" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	MaxLoans int    `json:"maxloans" validate:"min=0"`
+}
+
+type MemberResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	MaxLoans    int    `json:"maxloans"`
+	ActiveLoans int    `json:"activeloans"`
+}
+
+type LoanRequest struct {
+	MemberID string `json:"memberid" validate:"required"`
+	BookID   string `json:"bookid" validate:"required"`
+}
+
+type LoanResponse struct {
+	ID           string     `json:"id"`
+	MemberID     string     `json:"memberid"`
+	BookID       string     `json:"bookid"`
+	LoanDate     time.Time  `json:"loandate"`
+	DueDate      time.Time  `json:"duedate"`
+	ReturnedDate *time.Time `json:"returneddate,omitempty"`
+	Overdue      bool       `json:"overdue"`
+}
+
+type HoldRequest struct {
+	MemberID string `json:"memberid" validate:"required"`
+}
+
+type HoldResponse struct {
+	ID           string     `json:"id"`
+	BookID       string     `json:"bookid"`
+	MemberID     string     `json:"memberid"`
+	Status       string     `json:"status"`
+	Position     int64      `json:"position,omitempty"`
+	CreatedDate  time.Time  `json:"createddate"`
+	ReadyDate    *time.Time `json:"readydate,omitempty"`
+	PickupUntil  *time.Time `json:"pickupuntil,omitempty"`
+	FinishedDate *time.Time `json:"finisheddate,omitempty"`
+}
+
+type AuthorRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+type AuthorUpdateRequest struct {
+	ID   string `json:"id" validate:"required"`
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+type AuthorResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type BookFacetsResponse struct {
+	Categories []FacetCountResponse `json:"categories"`
+	Tags       []FacetCountResponse `json:"tags"`
+	Authors    []FacetCountResponse `json:"authors"`
+}
+
+type FacetCountResponse struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int64  `json:"count"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshtoken" validate:"required"`
+}
+
+// TokenResponse => access token is sent as "Authorization: Bearer <token>", expiresin is its lifetime in seconds
+type TokenResponse struct {
+	AccessToken  string `json:"accesstoken"`
+	TokenType    string `json:"tokentype"`
+	ExpiresIn    int    `json:"expiresin"`
+	RefreshToken string `json:"refreshtoken"`
+}
+
+// APIKeyRequest => scopes are rbac permissions of the catalog, e.g. books:read
+type APIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,min=1,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+}
+
+// APIKeyResponse => key is only sent when the api key is created or rotated, it is used as "X-API-Key: <key>"
+type APIKeyResponse struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	Key          string     `json:"key,omitempty"`
+	Scopes       []string   `json:"scopes"`
+	CreatedBy    string     `json:"createdby"`
+	CreatedDate  time.Time  `json:"createddate"`
+	RevokedAt    *time.Time `json:"revokedat,omitempty"`
+	LastUsedAt   *time.Time `json:"lastusedat,omitempty"`
+	RequestCount int64      `json:"requestcount"`
 }
 
 // instead of this we use response.JSONSuccessResultId
@@ -1,17 +1,144 @@
 package errors
 
-type InternalServerError struct {
-	Message string
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind => category of a domain error, every kind is responded with its own http status
+type Kind int
+
+const (
+	Internal Kind = iota
+	Invalid
+	Unauthorized
+	Forbidden
+	NotFound
+	Conflict
+	PreconditionFailed
+	UnsupportedMediaType
+	NotAcceptable
+)
+
+// kindStatuses => http status of the kinds
+var kindStatuses = map[Kind]int{
+	Internal:             http.StatusInternalServerError,
+	Invalid:              http.StatusBadRequest,
+	Unauthorized:         http.StatusUnauthorized,
+	Forbidden:            http.StatusForbidden,
+	NotFound:             http.StatusNotFound,
+	Conflict:             http.StatusConflict,
+	PreconditionFailed:   http.StatusPreconditionFailed,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	NotAcceptable:        http.StatusNotAcceptable,
 }
 
-type NotFoundError struct {
-	Message string
+// Status => http status of the kind, 500 for an unknown kind
+func (k Kind) Status() int {
+	if status, ok := kindStatuses[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
 }
 
-type BadRequestError struct {
+// codes of the errors which are not special to a domain
+const (
+	CodeInternal         = "internal_error"
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeNotFound         = "not_found"
+)
+
+// Error => error of the service layer, code is stable so clients can depend on it, message is for humans
+// => services declare sentinels (e.g. service.ErrBookNotFound) and return them with details by Withf
+type Error struct {
+	Kind    Kind
+	Code    string
 	Message string
+	// validation errors of the fields, if there are
+	Fields []FieldError
+	// cause of the error, it is logged but never responded
+	Err error
 }
 
-type ClientSideError struct {
-	Message string
+// FieldError => validation error of a field, field is the json path (e.g. tags[0]) and rule is the failed validate tag
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func New(kind Kind, code string, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is => errors with the same code are the same error, so Is(err, sentinel) is true for the copies of Withf
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Withf => copy of the error with a detailed message, e.g. ErrBookNotFound.Withf("{%v} with id book is not found", id)
+func (e *Error) Withf(format string, args ...interface{}) *Error {
+	copied := *e
+	copied.Message = fmt.Sprintf(format, args...)
+	return &copied
+}
+
+// Wrap => copy of the error with its cause
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+// WithFields => copy of the error with validation errors of fields
+func (e *Error) WithFields(fields []FieldError) *Error {
+	copied := *e
+	copied.Fields = fields
+	return &copied
+}
+
+// As => domain error in the chain of err, false if it is not a domain error (an internal error then)
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := stderrors.As(err, &e)
+	return e, ok
+}
+
+// Is => errors.Is of the standard library, so callers don't need both packages
+func Is(err error, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// KindOf => kind of a domain error, Internal for the other errors
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return Internal
+}
+
+// Problem => error response as problem details (RFC 7807), code & errors are the extension members
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
 }
@@ -0,0 +1,150 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formats of export files
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// ErrUnknownFormat => export format is not one of csv, ndjson or xlsx
+var ErrUnknownFormat = errors.New("format must be csv, ndjson or xlsx")
+
+// DateFormat => dates are written in UTC with this layout in every format
+const DateFormat = time.RFC3339
+
+// ListSeparator => separator of list values in a csv or xlsx cell, the same as the import uses
+const ListSeparator = ";"
+
+// contentTypes => content type of every format
+var contentTypes = map[string]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson",
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Writer => rows are written one by one as they come, Close must be called to complete the file
+// => values can be string, int, []string, time.Time or nil
+type Writer interface {
+	Write(values []interface{}) error
+	Close() error
+}
+
+// ContentType => content type of a format, "" if it is not known
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// NewWriter => writer of the format, header (csv & xlsx) is written with the column names
+func NewWriter(w io.Writer, format string, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return &ndjsonWriter{writer: w, columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	}
+	return nil, ErrUnknownFormat
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{writer: writer}, nil
+}
+
+func (c *csvWriter) Write(values []interface{}) error {
+	record := make([]string, 0, len(values))
+	for _, value := range values {
+		record = append(record, formatText(value))
+	}
+
+	return c.writer.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// ndjsonWriter => a json object per line, keys are in the order of the columns
+type ndjsonWriter struct {
+	writer  io.Writer
+	columns []string
+}
+
+func (n *ndjsonWriter) Write(values []interface{}) error {
+	var line strings.Builder
+
+	line.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+
+		// dates are written like in the other formats, lists stay as json arrays
+		if date, ok := value.(time.Time); ok {
+			value = formatText(date)
+		}
+
+		key, err := json.Marshal(n.columns[i])
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		line.Write(key)
+		line.WriteByte(':')
+		line.Write(encoded)
+	}
+	line.WriteString("}\n")
+
+	_, err := io.WriteString(n.writer, line.String())
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// formatText => text of a value for csv & xlsx cells
+func formatText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case []string:
+		return strings.Join(v, ListSeparator)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(DateFormat)
+	}
+	return ""
+}
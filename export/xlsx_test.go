@@ -0,0 +1,131 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sheet => cells of the sheet part, inline strings are in Text and numbers in Value
+type sheet struct {
+	Rows []struct {
+		Reference string `xml:"r,attr"`
+		Cells     []struct {
+			Reference string `xml:"r,attr"`
+			Type      string `xml:"t,attr"`
+			Value     string `xml:"v"`
+			Text      string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var b bytes.Buffer
+
+	writer, err := NewWriter(&b, FormatXLSX, []string{"title", "quantity", "tags", "createdat", "deletedat"})
+	if err != nil {
+		t.Fatalf("xlsx writer cannot be created: %v", err)
+	}
+	date := time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", 3600))
+	rows := [][]interface{}{
+		{`Tom & "Jerry" <1>`, 3, []string{"a<b", "c&d"}, date, nil},
+		{"  spaces  \nnew line", -1, []string(nil), time.Time{}, "x\x01y"},
+	}
+	for _, row := range rows {
+		if err := writer.Write(row); err != nil {
+			t.Fatalf("row cannot be written: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("xlsx cannot be closed: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatalf("xlsx is not a zip file: %v", err)
+	}
+
+	parts := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("%v cannot be opened: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%v cannot be read: %v", file.Name, err)
+		}
+
+		// every part must be well formed xml
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%v is not valid xml: %v", file.Name, err)
+			}
+		}
+		parts[file.Name] = content
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels",
+		"xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("%v part is missing", name)
+		}
+	}
+
+	if !bytes.Contains(parts["xl/worksheets/sheet1.xml"], []byte(`Tom &amp; &#34;Jerry&#34; &lt;1&gt;`)) {
+		t.Fatalf("text is not escaped: %s", parts["xl/worksheets/sheet1.xml"])
+	}
+
+	var decoded sheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &decoded); err != nil {
+		t.Fatalf("sheet cannot be decoded: %v", err)
+	}
+
+	type cell struct{ reference, kind, value string }
+	expected := [][]cell{
+		{{"A1", "inlineStr", "title"}, {"B1", "inlineStr", "quantity"}, {"C1", "inlineStr", "tags"},
+			{"D1", "inlineStr", "createdat"}, {"E1", "inlineStr", "deletedat"}},
+		{{"A2", "inlineStr", `Tom & "Jerry" <1>`}, {"B2", "", "3"}, {"C2", "inlineStr", "a<b;c&d"},
+			{"D2", "inlineStr", "2024-05-06T06:08:09Z"}, {"E2", "inlineStr", ""}},
+		// text which cannot be in xml is replaced, so the sheet can still be opened
+		{{"A3", "inlineStr", "  spaces  \nnew line"}, {"B3", "", "-1"}, {"C3", "inlineStr", ""},
+			{"D3", "inlineStr", ""}, {"E3", "inlineStr", "x�y"}},
+	}
+
+	var cells [][]cell
+	for i, row := range decoded.Rows {
+		if row.Reference != string(rune('1'+i)) {
+			t.Fatalf("row %d has reference %v", i, row.Reference)
+		}
+		var values []cell
+		for _, c := range row.Cells {
+			value := c.Text
+			if c.Type == "" {
+				value = c.Value
+			}
+			values = append(values, cell{c.Reference, c.Type, value})
+		}
+		cells = append(cells, values)
+	}
+	if !reflect.DeepEqual(cells, expected) {
+		t.Fatalf("expected %v, got %v", expected, cells)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+
+	for index, name := range tests {
+		if got := columnName(index); got != name {
+			t.Errorf("%d: expected %v, got %v", index, name, got)
+		}
+	}
+}
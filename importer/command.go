@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/requestinfo"
+	"RestfulWithEcho/service"
+	"RestfulWithEcho/tenant"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const commandUsage = "usage: import [-tenant id] [-dry-run] [-upsert] [-format csv|ndjson] [-report file] file"
+
+// RunCommand => "import [-tenant id] [-dry-run] [-upsert] [-format csv|ndjson] [-report file] file" subcommand of the binary
+// => the import runs in the foreground, its error report is written into the report file if it is given
+func RunCommand(importService service.IImportService, validate func(i interface{}) error, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(out)
+	tenantID := flags.String("tenant", tenant.Default, "tenant which the books are imported into")
+	dryRun := flags.Bool("dry-run", false, "only check the rows, nothing is written")
+	upsert := flags.Bool("upsert", false, "change the books which have the same isbn (or title & author) instead of creating new ones")
+	format := flags.String("format", "", "csv or ndjson, the extension of the file is used if it is empty")
+	report := flags.String("report", "", "csv file to write the rows which are not imported")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(commandUsage)
+	}
+	*tenantID = tenant.Normalize(*tenantID)
+	if err := tenant.Valid(*tenantID); err != nil {
+		return err
+	}
+
+	fileName := flags.Arg(0)
+	if *format == "" {
+		*format = FormatOf(fileName)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := Parse(file, *format, validate)
+	if err != nil {
+		return err
+	}
+
+	// books are matched & written in the tenant like the requests do
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	ctx = requestinfo.With(ctx, requestinfo.Info{Actor: requestinfo.Anonymous, TenantID: *tenantID})
+
+	job, err := importService.Start(ctx, models.ImportJob{FileName: filepath.Base(fileName), Format: *format,
+		DryRun: *dryRun, Upsert: *upsert}, rows, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "import %v is %v: %d rows, %d created, %d updated, %d failed\n",
+		job.ID, job.Status, job.Total, job.Created, job.Updated, job.Failed)
+	if *dryRun {
+		fmt.Fprintln(out, "dry run, nothing is written")
+	}
+
+	if *report != "" && job.Failed > 0 {
+		if err := writeReportFile(*report, job); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "error report is written into %v\n", *report)
+	}
+
+	if job.Status == models.ImportFailed {
+		return errors.New(job.Error)
+	}
+
+	return nil
+}
+
+func writeReportFile(fileName string, job models.ImportJob) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteReport(file, job); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"RestfulWithEcho/dtos"
+	"RestfulWithEcho/models"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// formats of import files
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// ErrUnknownFormat => import file is not a csv or an ndjson file
+var ErrUnknownFormat = errors.New("format must be csv or ndjson")
+
+// listSeparator => separator of the values of list columns (authorids & tags) in a csv cell
+const listSeparator = ";"
+
+// csvColumns => columns of a csv file, they are the json names of dtos.BookCreateRequest
+var csvColumns = map[string]bool{
+	"title": true, "author": true, "authorids": true, "isbn10": true, "isbn13": true, "category": true, "tags": true, "quantity": true,
+}
+
+// maxLineSize => max size of an ndjson line
+const maxLineSize = 1024 * 1024
+
+// FormatOf => format of a file by its extension, "" if it is not known
+func FormatOf(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	}
+	return ""
+}
+
+// Parse => rows of a csv (with a header row) or an ndjson file, every row is validated like dtos.BookCreateRequest
+// => invalid rows are returned with their errors, error is returned only if the file cannot be read
+func Parse(r io.Reader, format string, validate func(i interface{}) error) ([]models.ImportRow, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r, validate)
+	case FormatNDJSON:
+		return parseNDJSON(r, validate)
+	}
+	return nil, ErrUnknownFormat
+}
+
+func parseCSV(r io.Reader, validate func(i interface{}) error) ([]models.ImportRow, error) {
+	var rows []models.ImportRow
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return rows, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// byte order mark of the files which are saved by excel
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !csvColumns[name] {
+			return nil, fmt.Errorf("unknown column {%v}, columns can be title, author, authorids, isbn10, isbn13, category, tags, quantity", name)
+		}
+		columns = append(columns, name)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			// the reader continues with the next record
+			rows = append(rows, models.ImportRow{Line: parseError.StartLine, Errors: []string{parseError.Err.Error()}})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		request, errs := fromRecord(columns, record)
+		rows = append(rows, toRow(line, request, errs, validate))
+	}
+
+	return rows, nil
+}
+
+// fromRecord => to map the cells of a csv record into a request by the columns of the header
+func fromRecord(columns []string, record []string) (dtos.BookCreateRequest, []string) {
+	var request dtos.BookCreateRequest
+	var errs []string
+
+	for i, name := range columns {
+		value := strings.TrimSpace(record[i])
+		switch name {
+		case "title":
+			request.Title = value
+		case "author":
+			request.Author = value
+		case "authorids":
+			request.AuthorIDs = splitList(value)
+		case "isbn10":
+			request.ISBN10 = value
+		case "isbn13":
+			request.ISBN13 = value
+		case "category":
+			request.Category = value
+		case "tags":
+			request.Tags = splitList(value)
+		case "quantity":
+			quantity, err := strconv.Atoi(value)
+			if value != "" && err != nil {
+				errs = append(errs, fmt.Sprintf("quantity {%v} must be a number", value))
+			}
+			request.Quantity = quantity
+		}
+	}
+
+	return request, errs
+}
+
+func parseNDJSON(r io.Reader, validate func(i interface{}) error) ([]models.ImportRow, error) {
+	var rows []models.ImportRow
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var request dtos.BookCreateRequest
+
+		// id, version or unknown fields cannot be imported
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			rows = append(rows, models.ImportRow{Line: line, Errors: []string{fmt.Sprintf("invalid json: %v", err)}})
+			continue
+		}
+
+		rows = append(rows, toRow(line, request, nil, validate))
+	}
+
+	return rows, scanner.Err()
+}
+
+// toRow => a row with the validation errors of the request, its book is mapped only if it is valid
+func toRow(line int, request dtos.BookCreateRequest, errs []string, validate func(i interface{}) error) models.ImportRow {
+	row := models.ImportRow{Line: line, Errors: errs}
+
+	if err := validate(request); err != nil {
+		var fieldErrors validator.ValidationErrors
+		if errors.As(err, &fieldErrors) {
+			for _, fieldError := range fieldErrors {
+				row.Errors = append(row.Errors, fieldError.Error())
+			}
+		} else {
+			row.Errors = append(row.Errors, err.Error())
+		}
+	}
+
+	if len(row.Errors) == 0 {
+		row.Book = toBook(request)
+	}
+
+	return row
+}
+
+// toBook => mapping from request dto to model
+func toBook(request dtos.BookCreateRequest) models.Book {
+	return models.Book{
+		Title:     request.Title,
+		Quantity:  request.Quantity,
+		Author:    request.Author,
+		AuthorIDs: request.AuthorIDs,
+		ISBN10:    request.ISBN10,
+		ISBN13:    request.ISBN13,
+		Category:  request.Category,
+		Tags:      request.Tags,
+	}
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// WriteReport => error report of an import job as csv (line, message), it can be downloaded after the job
+func WriteReport(w io.Writer, job models.ImportJob) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"line", "message"}); err != nil {
+		return err
+	}
+	for _, importError := range job.Errors {
+		if err := writer.Write([]string{strconv.Itoa(importError.Line), importError.Message}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"RestfulWithEcho/isbn"
+	"RestfulWithEcho/models"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validate => book validation of the handlers, isbn tags accept hyphens like the stored values are normalized
+func validate(t *testing.T) func(i interface{}) error {
+	t.Helper()
+
+	v := validator.New()
+	if err := v.RegisterValidation("isbn10", func(fl validator.FieldLevel) bool {
+		return isbn.Valid10(isbn.Normalize(fl.Field().String()))
+	}); err != nil {
+		t.Fatalf("isbn10 cannot be registered: %v", err)
+	}
+	if err := v.RegisterValidation("isbn13", func(fl validator.FieldLevel) bool {
+		return isbn.Valid13(isbn.Normalize(fl.Field().String()))
+	}); err != nil {
+		t.Fatalf("isbn13 cannot be registered: %v", err)
+	}
+
+	return v.Struct
+}
+
+// row => line, book of a valid row or the start of the errors of an invalid one
+type row struct {
+	line   int
+	book   models.Book
+	errors []string
+}
+
+func TestParse(t *testing.T) {
+	dune := models.Book{Title: "Dune", Author: "Frank Herbert", ISBN13: "978-0-441-01359-3", Quantity: 2}
+
+	tests := []struct {
+		name   string
+		format string
+		file   string
+		rows   []row
+		err    string
+	}{
+		{name: "empty csv", format: FormatCSV, file: ""},
+		{name: "header only", format: FormatCSV, file: "title,author\n"},
+		{name: "csv rows", format: FormatCSV,
+			file: "\ufeff Title ,AUTHOR,isbn13,quantity,tags,authorids\n" +
+				"Dune,Frank Herbert,978-0-441-01359-3,2,,\n" +
+				"Emma,Jane Austen,,0, classic ; ;romance ,a-1;a-2\n",
+			rows: []row{{line: 2, book: dune},
+				{line: 3, book: models.Book{Title: "Emma", Author: "Jane Austen", Tags: []string{"classic", "romance"},
+					AuthorIDs: []string{"a-1", "a-2"}}}}},
+		{name: "unknown column", format: FormatCSV, file: "title,author,price\nDune,Frank Herbert,10\n", err: "unknown column {price}"},
+		// columns are the same as the request dto, ids cannot be imported
+		{name: "id column", format: FormatCSV, file: "id,title\n1,Dune\n", err: "unknown column {id}"},
+		{name: "bad isbn", format: FormatCSV, file: "title,author,isbn13,isbn10\nDune,Frank Herbert,9780441013594,0441013597\n",
+			rows: []row{{line: 2, errors: []string{"Key: 'BookCreateRequest.ISBN13'"}}}},
+		{name: "bad quantity", format: FormatCSV, file: "title,author,quantity\nDune,Frank Herbert,two\n",
+			rows: []row{{line: 2, errors: []string{"quantity {two} must be a number"}}}},
+		// duplicates are written by the bulk write, so they are reported by the unique index like any other duplicate
+		{name: "duplicate rows", format: FormatCSV,
+			file: "title,author,isbn13,quantity\nDune,Frank Herbert,978-0-441-01359-3,2\nDune,Frank Herbert,978-0-441-01359-3,2\n",
+			rows: []row{{line: 2, book: dune}, {line: 3, book: dune}}},
+		{name: "rows after a malformed row", format: FormatCSV,
+			file: "title,author\n\"multi\nline\",Someone\nDune\nEmma,Jane Austen\n",
+			rows: []row{{line: 2, book: models.Book{Title: "multi\nline", Author: "Someone"}},
+				{line: 4, errors: []string{"wrong number of fields"}},
+				{line: 5, book: models.Book{Title: "Emma", Author: "Jane Austen"}}}},
+		{name: "ndjson rows", format: FormatNDJSON,
+			file: `{"title":"Dune","author":"Frank Herbert","isbn13":"978-0-441-01359-3","quantity":2}` + "\n\n" +
+				`{"title":"Emma","author":"Jane Austen","id":"1"}` + "\n" +
+				`{"title":"Emma"` + "\n" +
+				`{"title":"","author":"Jane Austen","quantity":-1}`,
+			rows: []row{{line: 1, book: dune},
+				{line: 3, errors: []string{`invalid json: json: unknown field "id"`}},
+				{line: 4, errors: []string{"invalid json: unexpected EOF"}},
+				{line: 5, errors: []string{"Key: 'BookCreateRequest.Title'", "Key: 'BookCreateRequest.Quantity'"}}}},
+		{name: "unknown format", format: "xml", file: "<books/>", err: ErrUnknownFormat.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := Parse(strings.NewReader(test.file), test.format, validate(t))
+
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected %v error, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("file cannot be parsed: %v", err)
+			}
+
+			if len(parsed) != len(test.rows) {
+				t.Fatalf("expected %d rows, got %+v", len(test.rows), parsed)
+			}
+			for i, expected := range test.rows {
+				got := parsed[i]
+				if got.Line != expected.line || !reflect.DeepEqual(got.Book, expected.book) || len(got.Errors) != len(expected.errors) {
+					t.Fatalf("expected %+v, got %+v", expected, got)
+				}
+				// only the start of a message is compared, the rest is the wording of the libraries
+				for j, message := range expected.errors {
+					if !strings.HasPrefix(got.Errors[j], message) {
+						t.Fatalf("expected %q error, got %q", message, got.Errors[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{"books.csv": FormatCSV, "BOOKS.CSV": FormatCSV, "books.ndjson": FormatNDJSON,
+		"books.jsonl": FormatNDJSON, "books.json": "", "books": ""}
+
+	for fileName, format := range tests {
+		if got := FormatOf(fileName); got != format {
+			t.Errorf("%v: expected %q, got %q", fileName, format, got)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	job := models.ImportJob{Errors: []models.ImportError{{Line: 2, Message: "title is required"}, {Line: 7, Message: `a, "b"`}}}
+
+	var b strings.Builder
+	if err := WriteReport(&b, job); err != nil {
+		t.Fatalf("report cannot be written: %v", err)
+	}
+
+	if expected := "line,message\n2,title is required\n7,\"a, \"\"b\"\"\"\n"; b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+}
@@ -0,0 +1,139 @@
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid => text is not an ISBN-10 or ISBN-13 with a correct check digit
+var ErrInvalid = errors.New("invalid isbn")
+
+// ErrNotConvertible => only 978 prefixed ISBN-13s have an ISBN-10
+var ErrNotConvertible = errors.New("isbn-13 has no isbn-10")
+
+// Normalize => hyphens and spaces are removed, "x" check digit is upper case => "0-306-40615-2" => "0306406152"
+func Normalize(text string) string {
+	var normalized strings.Builder
+
+	for _, r := range text {
+		switch {
+		case r == '-' || r == ' ':
+			continue
+		case r == 'x':
+			normalized.WriteRune('X')
+		default:
+			normalized.WriteRune(r)
+		}
+	}
+
+	return normalized.String()
+}
+
+// Valid10 => normalized text is an ISBN-10, its weighted sum (10..1) must be divisible by 11, X is 10 as check digit
+func Valid10(isbn string) bool {
+	if len(isbn) != 10 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var digit int
+		switch {
+		case isbn[i] >= '0' && isbn[i] <= '9':
+			digit = int(isbn[i] - '0')
+		case isbn[i] == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+
+	return sum%11 == 0
+}
+
+// Valid13 => normalized text is an ISBN-13 (978/979 prefix), its weighted sum (1,3,1,3...) must be divisible by 10
+func Valid13(isbn string) bool {
+	if len(isbn) != 13 || !(strings.HasPrefix(isbn, "978") || strings.HasPrefix(isbn, "979")) {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if isbn[i] < '0' || isbn[i] > '9' {
+			return false
+		}
+		sum += int(isbn[i]-'0') * (1 + 2*(i%2))
+	}
+
+	return sum%10 == 0
+}
+
+// To13 => ISBN-10 to ISBN-13, "978" is prefixed and the check digit is calculated again
+func To13(isbn10 string) (string, error) {
+	isbn10 = Normalize(isbn10)
+	if !Valid10(isbn10) {
+		return "", ErrInvalid
+	}
+
+	body := "978" + isbn10[:9]
+
+	return body + checkDigit13(body), nil
+}
+
+// To10 => ISBN-13 to ISBN-10, only for the 978 prefix
+func To10(isbn13 string) (string, error) {
+	isbn13 = Normalize(isbn13)
+	if !Valid13(isbn13) {
+		return "", ErrInvalid
+	}
+	if !strings.HasPrefix(isbn13, "978") {
+		return "", ErrNotConvertible
+	}
+
+	body := isbn13[3:12]
+
+	return body + checkDigit10(body), nil
+}
+
+// Parse => both forms of an ISBN which is given in one of them, isbn10 is empty for 979 prefixed ISBN-13s
+func Parse(text string) (isbn10 string, isbn13 string, err error) {
+	normalized := Normalize(text)
+
+	switch {
+	case Valid10(normalized):
+		isbn13, err = To13(normalized)
+		return normalized, isbn13, err
+	case Valid13(normalized):
+		isbn10, err = To10(normalized)
+		if err == ErrNotConvertible {
+			err = nil
+		}
+		return isbn10, normalized, err
+	}
+
+	return "", "", ErrInvalid
+}
+
+func checkDigit10(body string) string {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return "X"
+	}
+
+	return string(rune('0' + check))
+}
+
+func checkDigit13(body string) string {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		sum += int(body[i]-'0') * (1 + 2*(i%2))
+	}
+
+	return string(rune('0' + (10-sum%10)%10))
+}
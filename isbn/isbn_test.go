@@ -0,0 +1,126 @@
+package isbn
+
+import "testing"
+
+func TestValid10(t *testing.T) {
+	tests := []struct {
+		isbn  string
+		valid bool
+	}{
+		{isbn: "0306406152", valid: true},
+		{isbn: "0596520689", valid: true},
+		{isbn: "080442957X", valid: true},
+		{isbn: "0306406153"},
+		{isbn: "0804429570"},
+		// text must be normalized first
+		{isbn: "080442957x"},
+		{isbn: "0-306-40615-2"},
+		// X is only a check digit
+		{isbn: "X306406152"},
+		{isbn: "030640615"},
+		{isbn: "03064061522"},
+		{isbn: "03064O6152"},
+		{isbn: ""},
+	}
+
+	for _, test := range tests {
+		if valid := Valid10(test.isbn); valid != test.valid {
+			t.Errorf("%q: expected %v, got %v", test.isbn, test.valid, valid)
+		}
+	}
+}
+
+func TestValid13(t *testing.T) {
+	tests := []struct {
+		isbn  string
+		valid bool
+	}{
+		{isbn: "9780306406157", valid: true},
+		{isbn: "9780804429573", valid: true},
+		{isbn: "9791090636071", valid: true},
+		{isbn: "9780306406158"},
+		// EAN-13 with a correct check digit, but not a bookland prefix
+		{isbn: "4006381333931"},
+		{isbn: "978-0-306-40615-7"},
+		{isbn: "978030640615X"},
+		{isbn: "978030640615"},
+		{isbn: ""},
+	}
+
+	for _, test := range tests {
+		if valid := Valid13(test.isbn); valid != test.valid {
+			t.Errorf("%q: expected %v, got %v", test.isbn, test.valid, valid)
+		}
+	}
+}
+
+func TestTo13(t *testing.T) {
+	tests := []struct {
+		isbn10 string
+		isbn13 string
+		err    error
+	}{
+		{isbn10: "0306406152", isbn13: "9780306406157"},
+		{isbn10: "080442957X", isbn13: "9780804429573"},
+		{isbn10: "043942089x", isbn13: "9780439420891"},
+		{isbn10: "0-596-52068-9", isbn13: "9780596520687"},
+		{isbn10: "0 306 40615 2", isbn13: "9780306406157"},
+		{isbn10: "0306406153", err: ErrInvalid},
+		{isbn10: "9780306406157", err: ErrInvalid},
+	}
+
+	for _, test := range tests {
+		isbn13, err := To13(test.isbn10)
+		if isbn13 != test.isbn13 || err != test.err {
+			t.Errorf("%q: expected %q (%v), got %q (%v)", test.isbn10, test.isbn13, test.err, isbn13, err)
+		}
+	}
+}
+
+func TestTo10(t *testing.T) {
+	tests := []struct {
+		isbn13 string
+		isbn10 string
+		err    error
+	}{
+		{isbn13: "9780306406157", isbn10: "0306406152"},
+		{isbn13: "9780804429573", isbn10: "080442957X"},
+		{isbn13: "978-0-439-42089-1", isbn10: "043942089X"},
+		{isbn13: "9791090636071", err: ErrNotConvertible},
+		{isbn13: "9780306406158", err: ErrInvalid},
+		{isbn13: "0306406152", err: ErrInvalid},
+	}
+
+	for _, test := range tests {
+		isbn10, err := To10(test.isbn13)
+		if isbn10 != test.isbn10 || err != test.err {
+			t.Errorf("%q: expected %q (%v), got %q (%v)", test.isbn13, test.isbn10, test.err, isbn10, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text   string
+		isbn10 string
+		isbn13 string
+		err    error
+	}{
+		{text: "0-306-40615-2", isbn10: "0306406152", isbn13: "9780306406157"},
+		{text: "080442957x", isbn10: "080442957X", isbn13: "9780804429573"},
+		{text: "978-0-306-40615-7", isbn10: "0306406152", isbn13: "9780306406157"},
+		// 979 prefixed ISBN-13 has no ISBN-10, it is not an error
+		{text: "979-10-90636-07-1", isbn13: "9791090636071"},
+		{text: "978-0-306-40615-8", err: ErrInvalid},
+		{text: "not an isbn", err: ErrInvalid},
+		{text: "", err: ErrInvalid},
+	}
+
+	for _, test := range tests {
+		isbn10, isbn13, err := Parse(test.text)
+		if isbn10 != test.isbn10 || isbn13 != test.isbn13 || err != test.err {
+			t.Errorf("%q: expected %q %q (%v), got %q %q (%v)",
+				test.text, test.isbn10, test.isbn13, test.err, isbn10, isbn13, err)
+		}
+	}
+}
@@ -4,19 +4,26 @@ import (
 	"RestfulWithEcho/app"
 	"RestfulWithEcho/configs"
 	"RestfulWithEcho/docs"
+	"RestfulWithEcho/importer"
+	"RestfulWithEcho/migrations"
 	"RestfulWithEcho/repository"
 	"RestfulWithEcho/service"
+	"RestfulWithEcho/users"
+	"context"
+	"crypto/rand"
 	"fmt"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/sirupsen/logrus"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"os"
+	"time"
 )
 
 // @title           Echo Restful API
 // @version         1.0
-// @description     This is a sample restful server.
+// @description     This is a sample restful server. Books belong to the tenant (bookstore) of the access token or api key, X-Tenant-ID header or subdomain must be the same tenant.
 // @termsOfService  http://swagger.io/terms/
 
 // @contact.name   API Support
@@ -28,6 +35,16 @@ import (
 
 // @host      localhost:8080
 // @BasePath  /api
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 "Bearer <access token>" of /auth/login
+
+// @securityDefinitions.apikey  APIKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 api key of a machine client from /apikeys, only for /books routes
 func main() {
 	e := echo.New()
 
@@ -45,21 +62,149 @@ func main() {
 	_ = godotenv.Load()
 	var env = os.Getenv("ENV")
 	config := configs.GetConfig(env)
-	mongoCollection := configs.ConnectDB(config.Database.Connection).
-		Database(config.Database.DatabaseName).Collection(config.Database.CollectionName)
+	database := configs.ConnectDB(config.Database.Connection).Database(config.Database.DatabaseName)
+	mongoCollection := database.Collection(config.Database.CollectionName)
+
+	migrator := migrations.NewMigrator(database, migrations.Collections{
+		Books:          config.Database.CollectionName,
+		Audit:          repository.AuditCollection,
+		StockMovements: repository.StockMovementCollection,
+		Reservations:   repository.ReservationCollection,
+		Members:        repository.MemberCollection,
+		Loans:          repository.LoanCollection,
+		Holds:          repository.HoldCollection,
+		Authors:        repository.AuthorCollection,
+		Imports:        repository.ImportCollection,
+		Users:          repository.UserCollection,
+		RefreshTokens:  repository.RefreshTokenCollection,
+		APIKeys:        repository.APIKeyCollection,
+	})
+
+	// => go run . migrate up|down [steps]|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.RunCommand(migrator, os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// pending migrations are applied on every boot, queries expect the fields which they add (e.g. version, tenantid)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	if applied, err := migrator.Up(ctx); err != nil {
+		log.Fatalf("Migrations cannot be applied, the app is not started: %v", err)
+	} else if len(applied) > 0 {
+		log.Infof("Migrations %v are applied.", applied)
+	}
+	// indexes are applied on every boot, existing ones are not created again
+	if err := migrations.EnsureIndexes(ctx, database, migrator.Collections); err != nil {
+		log.Fatal(err)
+	}
+	cancel()
 
 	// to create new repository with singleton pattern
 	BookRepository := repository.GetSingleInstancesRepository(mongoCollection)
+	AuditRepository := repository.GetSingleInstancesAuditRepository(database.Collection(repository.AuditCollection))
+	InventoryRepository := repository.GetSingleInstancesInventoryRepository(mongoCollection,
+		database.Collection(repository.StockMovementCollection))
+	ReservationRepository := repository.GetSingleInstancesReservationRepository(database.Collection(repository.ReservationCollection))
+	MemberRepository := repository.GetSingleInstancesMemberRepository(database.Collection(repository.MemberCollection))
+	LoanRepository := repository.GetSingleInstancesLoanRepository(database.Collection(repository.LoanCollection))
+	HoldRepository := repository.GetSingleInstancesHoldRepository(database.Collection(repository.HoldCollection))
+	AuthorRepository := repository.GetSingleInstancesAuthorRepository(database.Collection(repository.AuthorCollection))
+	ImportRepository := repository.GetSingleInstancesImportRepository(database.Collection(repository.ImportCollection))
+	UserRepository := repository.GetSingleInstancesUserRepository(database.Collection(repository.UserCollection))
+	TokenRepository := repository.GetSingleInstancesTokenRepository(database.Collection(repository.RefreshTokenCollection))
+	APIKeyRepository := repository.GetSingleInstancesAPIKeyRepository(database.Collection(repository.APIKeyCollection))
 
 	// to create new service with singleton pattern
-	BookService := service.GetSingleInstancesService(BookRepository)
+	HoldService := service.GetSingleInstancesHoldService(HoldRepository, MemberRepository, BookRepository, InventoryRepository,
+		time.Duration(config.Hold.PickupHours)*time.Hour)
+	BookService := service.GetSingleInstancesService(BookRepository, AuditRepository, AuthorRepository, HoldService,
+		time.Duration(config.Trash.RetentionDays)*24*time.Hour)
+	AuditService := service.GetSingleInstancesAuditService(AuditRepository)
+	InventoryService := service.GetSingleInstancesInventoryService(InventoryRepository, BookRepository, AuditRepository,
+		HoldService, config.Inventory.LowStockThreshold)
+	ReservationService := service.GetSingleInstancesReservationService(ReservationRepository, InventoryRepository, AuditRepository,
+		time.Duration(config.Reservation.DefaultTTLSeconds)*time.Second, time.Duration(config.Reservation.MaxTTLSeconds)*time.Second)
+	MemberService := service.GetSingleInstancesMemberService(MemberRepository, LoanRepository, AuditRepository)
+	AuthorService := service.GetSingleInstancesAuthorService(AuthorRepository, BookRepository, AuditRepository)
+	LoanService := service.GetSingleInstancesLoanService(LoanRepository, MemberRepository, InventoryRepository, AuditRepository,
+		HoldService, time.Duration(config.Lending.LoanDays)*24*time.Hour, config.Lending.DefaultMaxLoans)
+	ImportService := service.GetSingleInstancesImportService(ImportRepository, BookRepository, BookService)
+	AuthService := service.GetSingleInstancesAuthService(UserRepository, TokenRepository, signingKey(config, log),
+		config.Auth.Issuer, time.Duration(config.Auth.AccessTokenMinutes)*time.Minute,
+		time.Duration(config.Auth.RefreshTokenDays)*24*time.Hour)
+	APIKeyService := service.GetSingleInstancesAPIKeyService(APIKeyRepository)
+
+	// => echo password | go run . user add [-tenant id] [-roles editor,inventory-manager] username
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		if err := users.RunCommand(AuthService, os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// => go run . import [-tenant id] [-dry-run] [-upsert] [-format csv|ndjson] [-report file] file
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		bookValidator, err := app.NewBookValidator()
+		if err == nil {
+			err = importer.RunCommand(ImportService, bookValidator.Validate, os.Args[2:], os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// imports which were running when the app stopped cannot be continued
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	if count, err := ImportService.FailInterrupted(ctx); err != nil {
+		log.Errorf("Interrupted imports cannot be marked as failed: %v", err)
+	} else if count > 0 {
+		log.Warnf("%d interrupted imports are marked as failed.", count)
+	}
+	cancel()
+
+	// expired reservations give their copies back in background
+	go ReservationService.RunReaper(context.Background(), time.Duration(config.Reservation.ReaperIntervalSeconds)*time.Second)
+	// holds which are not picked up in time give their copies to the next members
+	go HoldService.RunReaper(context.Background(), time.Duration(config.Hold.ReaperIntervalSeconds)*time.Second)
 
 	fmt.Println("Book Service address of value", &BookService)
 	fmt.Println("Logger address of value", &log)
 	fmt.Println("Echo context address of value", &e)
 
+	// request id & actor are needed for audit records
+	e.Use(middleware.RequestID(), app.RequestInfo())
+	// responses are rendered in the format of Accept header, msgpack bodies can be bound too
+	e.Use(app.Negotiate())
+	e.Binder = &app.Binder{}
+	// every error of the handlers is responded as problem details (application/problem+json)
+	e.HTTPErrorHandler = app.ProblemHandler(log)
+	// machine clients can use an api key (X-API-Key) for book routes instead of an access token
+	e.Use(app.APIKeyAuth(APIKeyService, "api/books"))
+	// every route except /api/auth needs an access token of /api/auth/login, permissions are declared with the routes
+	tenantRoutes := []string{"api/books", "api/admin", "api/apikeys", "api/audit", "api/authors",
+		"api/members", "api/loans", "api/holds", "api/reservations", "api/imports"}
+	e.Use(app.Authenticate(AuthService, tenantRoutes...))
+	// documents are scoped to the tenant of the credentials (bookstore), so the tenant is resolved after them
+	e.Use(app.ResolveTenant(config.Tenant.BaseDomain, config.Tenant.Required, tenantRoutes...))
+
 	// to create new app
 	app.NewBookHandler(e, BookService, log)
+	app.NewAuditHandler(e, AuditService, log)
+	app.NewInventoryHandler(e, InventoryService, log)
+	app.NewReservationHandler(e, ReservationService, log)
+	app.NewMemberHandler(e, MemberService, log)
+	app.NewLoanHandler(e, LoanService, log)
+	app.NewHoldHandler(e, HoldService, log)
+	app.NewAuthorHandler(e, AuthorService, log)
+	app.NewImportHandler(e, ImportService, log)
+	app.NewAuthHandler(e, AuthService, log)
+	app.NewAPIKeyHandler(e, APIKeyService, log)
 
 	// if we don't use this swagger give an error
 	docs.SwaggerInfo.Host = "localhost:8080"
@@ -71,3 +216,20 @@ func main() {
 	e.Logger.Print(fmt.Sprintf("Listening on port %s", config.Server.Port))
 	e.Logger.Fatal(e.Start(config.Server.Port))
 }
+
+// signingKey => key of the access tokens from JWT_SECRET or the config, a random one if both are empty
+func signingKey(config configs.Config, log *logrus.Logger) []byte {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	if config.Auth.SigningKey != "" {
+		return []byte(config.Auth.SigningKey)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal(err)
+	}
+	log.Warn("JWT_SECRET is not set, a random signing key is generated. Tokens are invalid after a restart.")
+	return key
+}
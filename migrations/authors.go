@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// dedupeAuthors => author names of books are grouped by models.AuthorKey, so "J.R.R. Tolkien" and "JRR Tolkien"
+// become one author, the most used spelling is its name
+// => created authors are marked as migrated, Down removes only them
+func dedupeAuthors(ctx context.Context, db *mongo.Database, c Collections) error {
+	books := db.Collection(c.Books)
+	authors := db.Collection(c.Authors)
+
+	// every spelling with its book count, most used first
+	cursor, err := books.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"authorids": nil, "author": bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$author", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var spellings []struct {
+		Name  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &spellings); err != nil {
+		return err
+	}
+
+	ids := map[string]string{}
+
+	for _, spelling := range spellings {
+		key := models.AuthorKey(spelling.Name)
+		if key == "" {
+			continue
+		}
+
+		id, ok := ids[key]
+		if !ok {
+			if id, err = authorByKey(ctx, authors, key, spelling.Name); err != nil {
+				return err
+			}
+			ids[key] = id
+		}
+
+		if _, err := books.UpdateMany(ctx,
+			bson.M{"authorids": nil, "author": spelling.Name},
+			bson.M{"$set": bson.M{"authorids": bson.A{id}}}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// authorByKey => id of the author with the key, the author is created if it doesn't exist
+func authorByKey(ctx context.Context, authors *mongo.Collection, key string, name string) (string, error) {
+	var existing models.Author
+
+	err := authors.FindOne(ctx, bson.M{"key": key}).Decode(&existing)
+	if err == nil {
+		return existing.ID, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return "", err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	author := bson.M{
+		"_id":         uuid.New().String(),
+		"createddate": now,
+		"updateddate": now,
+		"name":        name,
+		"key":         key,
+		"migrated":    true,
+	}
+
+	if _, err := authors.InsertOne(ctx, author); err != nil {
+		return "", err
+	}
+
+	return author["_id"].(string), nil
+}
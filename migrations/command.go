@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// RunCommand => "migrate up|down [steps]|status" subcommand of the binary
+func RunCommand(m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, version := range done {
+			fmt.Fprintf(out, "applied %d\n", version)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number")
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		for _, version := range done {
+			fmt.Fprintf(out, "reverted %d\n", version)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "no applied migrations")
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedDate.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%4d  %-60s %s\n", status.Version, status.Description, state)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown migrate command {%v}, usage: migrate up|down [steps]|status", args[0])
+}
@@ -0,0 +1,254 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index => declaration of an index, EnsureIndexes creates it or recreates it when the declaration is changed
+type Index struct {
+	Collection string
+	Name       string
+	Keys       bson.D
+	Unique     bool
+	Sparse     bool
+	// only for text indexes
+	Weights bson.D
+	// only for partial indexes, e.g. unique index over the documents which have the field
+	PartialFilter bson.M
+	// only for TTL indexes
+	ExpireAfterSeconds *int32
+}
+
+// expireNow => TTL of the documents which have their own expiry date
+var expireNow int32 = 0
+
+// indexes => every index which the project needs, names are used to detect changed declarations
+func indexes(c Collections) []Index {
+	return []Index{
+		{
+			// GET /api/books/search
+			Collection: c.Books,
+			Name:       "books_text",
+			Keys:       bson.D{{Key: "title", Value: "text"}, {Key: "author", Value: "text"}},
+			Weights:    bson.D{{Key: "title", Value: 2}, {Key: "author", Value: 1}},
+		},
+		{
+			// GET /api/books?cursor= => keyset pagination order in a tenant
+			Collection: c.Books,
+			Name:       "books_createddate_id",
+			Keys:       bson.D{{Key: "tenantid", Value: 1}, {Key: "createddate", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			// GET /api/books/trash and purge, only deleted books are in the index
+			Collection: c.Books,
+			Name:       "books_deletedat",
+			Keys:       bson.D{{Key: "deletedat", Value: 1}},
+			Sparse:     true,
+		},
+		{
+			// GET /api/books/:id/history
+			Collection: c.Audit,
+			Name:       "audit_entity_date",
+			Keys:       bson.D{{Key: "entitytype", Value: 1}, {Key: "entityid", Value: 1}, {Key: "date", Value: -1}},
+		},
+		{
+			// GET /api/audit
+			Collection: c.Audit,
+			Name:       "audit_date",
+			Keys:       bson.D{{Key: "date", Value: -1}},
+		},
+		{
+			// GET /api/books/:id/stock/movements
+			Collection: c.StockMovements,
+			Name:       "stock_movements_book_date",
+			Keys:       bson.D{{Key: "bookid", Value: 1}, {Key: "date", Value: -1}},
+		},
+		{
+			// GET /api/books/low-stock
+			Collection: c.Books,
+			Name:       "books_quantity",
+			Keys:       bson.D{{Key: "quantity", Value: 1}},
+		},
+		{
+			// reaper => pending reservations by expiry
+			Collection: c.Reservations,
+			Name:       "reservations_status_expiresat",
+			Keys:       bson.D{{Key: "status", Value: 1}, {Key: "expiresat", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			// a member per email in a tenant
+			Collection: c.Members,
+			Name:       "members_email",
+			Keys:       bson.D{{Key: "tenantid", Value: 1}, {Key: "email", Value: 1}},
+			Unique:     true,
+		},
+		{
+			// GET /api/members/:id/loans
+			Collection: c.Loans,
+			Name:       "loans_memberid_loandate",
+			Keys:       bson.D{{Key: "memberid", Value: 1}, {Key: "loandate", Value: -1}},
+		},
+		{
+			// GET /api/loans/overdue => active loans by due date
+			Collection: c.Loans,
+			Name:       "loans_returneddate_duedate",
+			Keys:       bson.D{{Key: "returneddate", Value: 1}, {Key: "duedate", Value: 1}},
+		},
+		{
+			// hold queue of a book in FIFO order
+			Collection: c.Holds,
+			Name:       "holds_bookid_status_createddate",
+			Keys:       bson.D{{Key: "bookid", Value: 1}, {Key: "status", Value: 1}, {Key: "createddate", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			// a member can wait only once for a book of its tenant
+			Collection:    c.Holds,
+			Name:          "holds_bookid_memberid_active",
+			Keys:          bson.D{{Key: "tenantid", Value: 1}, {Key: "bookid", Value: 1}, {Key: "memberid", Value: 1}},
+			Unique:        true,
+			PartialFilter: bson.M{"active": true},
+		},
+		{
+			// reaper => ready holds by pickup window
+			Collection: c.Holds,
+			Name:       "holds_status_pickupuntil",
+			Keys:       bson.D{{Key: "status", Value: 1}, {Key: "pickupuntil", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			// an author per normalized name in a tenant
+			Collection: c.Authors,
+			Name:       "authors_key",
+			Keys:       bson.D{{Key: "tenantid", Value: 1}, {Key: "key", Value: 1}},
+			Unique:     true,
+		},
+		{
+			// GET /api/authors/:id/books
+			Collection: c.Books,
+			Name:       "books_authorids",
+			Keys:       bson.D{{Key: "authorids", Value: 1}},
+		},
+		{
+			// GET /api/books/isbn/:isbn, a book per ISBN in a tenant (trash included), books without ISBN are not in the index
+			Collection:    c.Books,
+			Name:          "books_isbn13",
+			Keys:          bson.D{{Key: "tenantid", Value: 1}, {Key: "isbn13", Value: 1}},
+			Unique:        true,
+			PartialFilter: bson.M{"isbn13": bson.M{"$gt": ""}},
+		},
+		{
+			Collection:    c.Books,
+			Name:          "books_isbn10",
+			Keys:          bson.D{{Key: "tenantid", Value: 1}, {Key: "isbn10", Value: 1}},
+			Unique:        true,
+			PartialFilter: bson.M{"isbn10": bson.M{"$gt": ""}},
+		},
+		{
+			// GET /api/books?category=Fiction => books of the category and its sub categories
+			Collection: c.Books,
+			Name:       "books_categorypath",
+			Keys:       bson.D{{Key: "categorypath", Value: 1}},
+		},
+		{
+			// GET /api/books?tag=x
+			Collection: c.Books,
+			Name:       "books_tags",
+			Keys:       bson.D{{Key: "tags", Value: 1}},
+		},
+		{
+			// rows of an upsert import without ISBN are matched by title & author
+			Collection: c.Books,
+			Name:       "books_title_author",
+			Keys:       bson.D{{Key: "title", Value: 1}, {Key: "author", Value: 1}},
+		},
+		{
+			// running imports are marked as failed on boot
+			Collection: c.Imports,
+			Name:       "imports_status",
+			Keys:       bson.D{{Key: "status", Value: 1}},
+		},
+		{
+			// login => a user per username
+			Collection: c.Users,
+			Name:       "users_username",
+			Keys:       bson.D{{Key: "username", Value: 1}},
+			Unique:     true,
+		},
+		{
+			// logout & reuse of a refresh token revoke its family
+			Collection: c.RefreshTokens,
+			Name:       "refreshtokens_family",
+			Keys:       bson.D{{Key: "family", Value: 1}},
+		},
+		{
+			// expired refresh tokens are removed by mongodb
+			Collection:         c.RefreshTokens,
+			Name:               "refreshtokens_expiresat",
+			Keys:               bson.D{{Key: "expiresat", Value: 1}},
+			ExpireAfterSeconds: &expireNow,
+		},
+		{
+			// api key of a request is found by its prefix
+			Collection: c.APIKeys,
+			Name:       "apikeys_prefix",
+			Keys:       bson.D{{Key: "prefix", Value: 1}},
+			Unique:     true,
+		},
+	}
+}
+
+// index options conflict codes of mongodb => IndexOptionsConflict, IndexKeySpecsConflict
+var conflictCodes = map[int32]bool{85: true, 86: true}
+
+// EnsureIndexes => to apply index declarations idempotently, same declaration is a no-op and a changed one is recreated
+func EnsureIndexes(ctx context.Context, db *mongo.Database, c Collections) error {
+	for _, index := range indexes(c) {
+		model := index.model()
+		view := db.Collection(index.Collection).Indexes()
+
+		_, err := view.CreateOne(ctx, model)
+		if err == nil {
+			continue
+		}
+
+		var commandErr mongo.CommandError
+		if !errors.As(err, &commandErr) || !conflictCodes[commandErr.Code] {
+			return err
+		}
+
+		// declaration is changed => drop the old one and create again
+		if _, err := view.DropOne(ctx, index.Name); err != nil {
+			return err
+		}
+		if _, err := view.CreateOne(ctx, model); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i Index) model() mongo.IndexModel {
+	opts := options.Index().SetName(i.Name)
+
+	if i.Unique {
+		opts.SetUnique(true)
+	}
+	if i.Sparse {
+		opts.SetSparse(true)
+	}
+	if i.Weights != nil {
+		opts.SetWeights(i.Weights)
+	}
+	if i.PartialFilter != nil {
+		opts.SetPartialFilterExpression(i.PartialFilter)
+	}
+	if i.ExpireAfterSeconds != nil {
+		opts.SetExpireAfterSeconds(*i.ExpireAfterSeconds)
+	}
+
+	return mongo.IndexModel{Keys: i.Keys, Options: opts}
+}
@@ -0,0 +1,129 @@
+package migrations
+
+import (
+	"RestfulWithEcho/tenant"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// all => every migration of the project, versions must be unique and must not be changed after they are released
+var all = []Migration{
+	{
+		Version:     1,
+		Description: "set updateddate of never updated books to createddate",
+		Up: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			// books were inserted with zero updateddate (1970-01-01)
+			_, err := db.Collection(c.Books).UpdateMany(ctx,
+				bson.M{"updateddate": primitive.DateTime(0)},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"updateddate": "$createddate"}}}})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			_, err := db.Collection(c.Books).UpdateMany(ctx,
+				bson.M{"$expr": bson.M{"$eq": bson.A{"$updateddate", "$createddate"}}},
+				bson.M{"$set": bson.M{"updateddate": primitive.DateTime(0)}})
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "add version field to books for optimistic concurrency",
+		Up: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			_, err := db.Collection(c.Books).UpdateMany(ctx,
+				bson.M{"version": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"version": 1}})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			_, err := db.Collection(c.Books).UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"version": ""}})
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "add reserved field to books for stock reservations",
+		Up: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			_, err := db.Collection(c.Books).UpdateMany(ctx,
+				bson.M{"reserved": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"reserved": 0}})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			_, err := db.Collection(c.Books).UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"reserved": ""}})
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "create authors from author names of books and reference them by authorids",
+		Up:          dedupeAuthors,
+		Down: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			ids, err := db.Collection(c.Authors).Distinct(ctx, "_id", bson.M{"migrated": true})
+			if err != nil {
+				return err
+			}
+			if _, err := db.Collection(c.Books).UpdateMany(ctx,
+				bson.M{"authorids": bson.M{"$in": ids}},
+				bson.M{"$unset": bson.M{"authorids": ""}}); err != nil {
+				return err
+			}
+			_, err = db.Collection(c.Authors).DeleteMany(ctx, bson.M{"migrated": true})
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "move books, their ledgers, audit records & imports, users and api keys into the default tenant",
+		Up: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			return intoDefaultTenant(ctx, db, tenantCollections(c))
+		},
+		Down: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			return outOfDefaultTenant(ctx, db, tenantCollections(c))
+		},
+	},
+	{
+		Version:     6,
+		Description: "move authors, members, loans, holds and reservations into the default tenant",
+		Up: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			return intoDefaultTenant(ctx, db, lendingCollections(c))
+		},
+		Down: func(ctx context.Context, db *mongo.Database, c Collections) error {
+			return outOfDefaultTenant(ctx, db, lendingCollections(c))
+		},
+	},
+}
+
+// tenantCollections => collections whose documents belong to a tenant
+func tenantCollections(c Collections) []string {
+	return []string{c.Books, c.StockMovements, c.Audit, c.Imports, c.Users, c.APIKeys}
+}
+
+// lendingCollections => collections which were scoped to a tenant after the books
+func lendingCollections(c Collections) []string {
+	return []string{c.Authors, c.Members, c.Loans, c.Holds, c.Reservations}
+}
+
+// intoDefaultTenant => documents which were created before tenants belong to the default tenant
+func intoDefaultTenant(ctx context.Context, db *mongo.Database, collections []string) error {
+	for _, collection := range collections {
+		if _, err := db.Collection(collection).UpdateMany(ctx,
+			bson.M{"tenantid": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"tenantid": tenant.Default}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func outOfDefaultTenant(ctx context.Context, db *mongo.Database, collections []string) error {
+	for _, collection := range collections {
+		if _, err := db.Collection(collection).UpdateMany(ctx,
+			bson.M{"tenantid": tenant.Default},
+			bson.M{"$unset": bson.M{"tenantid": ""}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+)
+
+// MigrationCollection => applied migrations are stored here, one document per version
+const MigrationCollection = "schema_migrations"
+
+// Migration => a versioned change of the stored documents, Down has to revert what Up does
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database, c Collections) error
+	Down        func(ctx context.Context, db *mongo.Database, c Collections) error
+}
+
+// Collections => collection names come from configs, migrations should not hard-code them
+type Collections struct {
+	Books          string
+	Audit          string
+	StockMovements string
+	Reservations   string
+	Members        string
+	Loans          string
+	Holds          string
+	Authors        string
+	Imports        string
+	Users          string
+	RefreshTokens  string
+	APIKeys        string
+}
+
+// MigrationStatus => to show a migration with its applied date (zero if it is pending)
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedDate time.Time
+}
+
+// appliedMigration => document in schema_migrations
+type appliedMigration struct {
+	Version     int                `bson:"_id"`
+	Description string             `bson:"description"`
+	AppliedDate primitive.DateTime `bson:"applieddate"`
+}
+
+type Migrator struct {
+	DB          *mongo.Database
+	Collections Collections
+	Migrations  []Migration
+}
+
+// NewMigrator => migrator with every migration of the project, see list.go
+func NewMigrator(db *mongo.Database, collections Collections) *Migrator {
+	migrations := make([]Migration, len(all))
+	copy(migrations, all)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{DB: db, Collections: collections, Migrations: migrations}
+}
+
+// Up => to apply every pending migration in version order, it returns the applied versions
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var done []int
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := migration.Up(ctx, m.DB, m.Collections); err != nil {
+			return done, fmt.Errorf("migration %d (%v) failed: %w", migration.Version, migration.Description, err)
+		}
+
+		record := appliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedDate: primitive.NewDateTimeFromTime(time.Now()),
+		}
+		if _, err := m.DB.Collection(MigrationCollection).InsertOne(ctx, record); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration.Version)
+	}
+
+	return done, nil
+}
+
+// Down => to revert the last "steps" applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var done []int
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if err := migration.Down(ctx, m.DB, m.Collections); err != nil {
+			return done, fmt.Errorf("rollback of migration %d (%v) failed: %w", migration.Version, migration.Description, err)
+		}
+
+		if _, err := m.DB.Collection(MigrationCollection).DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration.Version)
+	}
+
+	return done, nil
+}
+
+// Status => every known migration with applied or pending state
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedDate = record.AppliedDate.Time()
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Pending => count of the migrations which are not applied yet
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	applied := map[int]appliedMigration{}
+
+	cursor, err := m.DB.Collection(MigrationCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record appliedMigration
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		applied[record.Version] = record
+	}
+
+	return applied, cursor.Err()
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey => key of a machine client, only the sha256 of the key is stored and the key is found by its prefix
+// => scopes are rbac permissions, e.g. books:read, a key can only use the catalog of the tenant which created it
+type APIKey struct {
+	ID           string              `json:"_id,omitempty" bson:"_id,omitempty"`
+	CreatedDate  primitive.DateTime  `json:"createddate,omitempty" bson:"createddate"`
+	UpdatedDate  primitive.DateTime  `json:"updateddate" bson:"updateddate"`
+	Name         string              `json:"name" bson:"name"`
+	Prefix       string              `json:"prefix" bson:"prefix"`
+	Hash         string              `json:"-" bson:"hash"`
+	Scopes       []string            `json:"scopes" bson:"scopes"`
+	CreatedBy    string              `json:"createdby" bson:"createdby"`
+	RevokedAt    *primitive.DateTime `json:"revokedat,omitempty" bson:"revokedat,omitempty"`
+	LastUsedAt   *primitive.DateTime `json:"lastusedat,omitempty" bson:"lastusedat,omitempty"`
+	RequestCount int64               `json:"requestcount" bson:"requestcount"`
+	TenantID     string              `json:"tenantid" bson:"tenantid"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// actions of audit records
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditPatch   = "patch"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+	AuditStock   = "stock"
+)
+
+// AuditRecord => a single mutation of an entity, who did it and what changed
+// => TenantID is empty for the mutations outside of requests (e.g. reapers)
+type AuditRecord struct {
+	ID         string             `json:"id" bson:"_id"`
+	Date       primitive.DateTime `json:"date" bson:"date"`
+	EntityType string             `json:"entitytype" bson:"entitytype"`
+	EntityID   string             `json:"entityid" bson:"entityid"`
+	Action     string             `json:"action" bson:"action"`
+	Actor      string             `json:"actor" bson:"actor"`
+	RequestID  string             `json:"requestid,omitempty" bson:"requestid,omitempty"`
+	Changes    []FieldChange      `json:"changes,omitempty" bson:"changes,omitempty"`
+	TenantID   string             `json:"tenantid,omitempty" bson:"tenantid,omitempty"`
+}
+
+// FieldChange => value of a field before and after the mutation (nil if it didn't exist)
+type FieldChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// AuditQuery => filters of audit records, empty fields are not used; records are ordered by date, newest first
+type AuditQuery struct {
+	EntityType string
+	EntityID   string
+	Action     string
+	Actor      string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+	Skip       int64
+	Limit      int64
+}
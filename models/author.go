@@ -0,0 +1,31 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"unicode"
+)
+
+// Author => a person who writes books, books reference authors by id
+// => Key is the normalized name, it is unique in a tenant, so "J.R.R. Tolkien" and "JRR Tolkien" are the same author
+type Author struct {
+	ID          string             `json:"_id,omitempty" bson:"_id,omitempty"`
+	CreatedDate primitive.DateTime `json:"createddate,omitempty" bson:"createddate"`
+	UpdatedDate primitive.DateTime `json:"updateddate" bson:"updateddate"`
+	Name        string             `json:"name" bson:"name"`
+	Key         string             `json:"key" bson:"key"`
+	TenantID    string             `json:"tenantid" bson:"tenantid"`
+}
+
+// AuthorKey => lower case letters and digits of a name, punctuation and spaces are ignored
+func AuthorKey(name string) string {
+	var key strings.Builder
+
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(r)
+		}
+	}
+
+	return key.String()
+}
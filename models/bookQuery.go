@@ -0,0 +1,48 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookQuery => listing parameters (paging, sorting, filtering) which are pushed down to MongoDB
+type BookQuery struct {
+	Skip    int64
+	Limit   int64
+	Sort    []SortField
+	Filters []Filter
+	// true => only deleted books (trash), false => deleted books are excluded
+	Deleted bool
+}
+
+// SortField => a single sort key, e.g. "-createddate" => {Field: "createddate", Descending: true}
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// Filter => a single field condition, e.g. "quantity[gte]=5" => {Field: "quantity", Operator: "gte", Value: 5}
+type Filter struct {
+	Field    string
+	Operator string
+	Value    interface{}
+}
+
+// BookCursor => position of the last listed book for keyset (cursor) pagination, books are ordered by createddate & _id
+type BookCursor struct {
+	CreatedDate primitive.DateTime `json:"c"`
+	ID          string             `json:"i"`
+}
+
+// BookFacets => book counts per value of the catalog facets, most used values first
+type BookFacets struct {
+	Categories []FacetCount `bson:"categories"`
+	Tags       []FacetCount `bson:"tags"`
+	Authors    []FacetCount `bson:"authors"`
+}
+
+// FacetCount => count of the books which have the value, Name is the display name of an id value (authors)
+type FacetCount struct {
+	Value string `bson:"_id"`
+	Name  string `bson:"name,omitempty"`
+	Count int64  `bson:"count"`
+}
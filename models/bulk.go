@@ -0,0 +1,10 @@
+package models
+
+// BulkResult => result of an item of a bulk operation, Index is its position in the request
+// => Err is nil if the item is written, Created is true if an upsert created the book
+type BulkResult struct {
+	Index   int
+	ID      string
+	Created bool
+	Err     error
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+)
+
+// categorySeparator => separator of the levels in a category path, "Fiction/Fantasy"
+const categorySeparator = "/"
+
+// CategoryPath => normalized category ("Fiction / Fantasy" => "Fiction/Fantasy") and its ancestors with itself
+// => ["Fiction", "Fiction/Fantasy"], so books of the sub categories are found by the parent category
+func CategoryPath(category string) (string, []string) {
+	var levels []string
+	for _, level := range strings.Split(category, categorySeparator) {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+
+	var path []string
+	for i := range levels {
+		path = append(path, strings.Join(levels[:i+1], categorySeparator))
+	}
+
+	return strings.Join(levels, categorySeparator), path
+}
+
+// NormalizeTag => tags are compared in lower case without surrounding spaces
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags => normalized tags without empty and repeated ones, order is kept
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// statuses of a hold => waiting in queue, ready (a copy is held until PickupUntil), then one of the final ones
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready"
+	HoldFulfilled = "fulfilled"
+	HoldCancelled = "cancelled"
+	HoldExpired   = "expired"
+)
+
+// Hold => place of a member in the FIFO queue of an out of stock book
+// => Active is true while the hold is waiting or ready, a member can have one active hold per book
+type Hold struct {
+	ID           string              `json:"_id,omitempty" bson:"_id,omitempty"`
+	BookID       string              `json:"bookid" bson:"bookid"`
+	MemberID     string              `json:"memberid" bson:"memberid"`
+	Status       string              `json:"status" bson:"status"`
+	Active       bool                `json:"active" bson:"active"`
+	CreatedDate  primitive.DateTime  `json:"createddate" bson:"createddate"`
+	ReadyDate    *primitive.DateTime `json:"readydate,omitempty" bson:"readydate,omitempty"`
+	PickupUntil  *primitive.DateTime `json:"pickupuntil,omitempty" bson:"pickupuntil,omitempty"`
+	FinishedDate *primitive.DateTime `json:"finisheddate,omitempty" bson:"finisheddate,omitempty"`
+	TenantID     string              `json:"tenantid" bson:"tenantid"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// statuses of an import job
+const (
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+// ImportJob => a catalog import, counts are updated while its rows are written
+// => DryRun jobs only check the rows, Upsert jobs change the books which have the same ISBN (or title & author)
+type ImportJob struct {
+	ID           string              `json:"_id,omitempty" bson:"_id,omitempty"`
+	Status       string              `json:"status" bson:"status"`
+	FileName     string              `json:"filename" bson:"filename"`
+	Format       string              `json:"format" bson:"format"`
+	DryRun       bool                `json:"dryrun" bson:"dryrun"`
+	Upsert       bool                `json:"upsert" bson:"upsert"`
+	Actor        string              `json:"actor" bson:"actor"`
+	Total        int                 `json:"total" bson:"total"`
+	Processed    int                 `json:"processed" bson:"processed"`
+	Created      int                 `json:"created" bson:"created"`
+	Updated      int                 `json:"updated" bson:"updated"`
+	Failed       int                 `json:"failed" bson:"failed"`
+	Errors       []ImportError       `json:"errors,omitempty" bson:"errors,omitempty"`
+	Error        string              `json:"error,omitempty" bson:"error,omitempty"` // why the whole job failed
+	CreatedDate  primitive.DateTime  `json:"createddate" bson:"createddate"`
+	FinishedDate *primitive.DateTime `json:"finisheddate,omitempty" bson:"finisheddate,omitempty"`
+	TenantID     string              `json:"tenantid" bson:"tenantid"`
+}
+
+// ImportError => why a row of an import is not written, Line is the line number in the file
+type ImportError struct {
+	Line    int    `json:"line" bson:"line"`
+	Message string `json:"message" bson:"message"`
+}
+
+// ImportRow => a parsed row of an import file, Errors are the reasons if it is not valid
+type ImportRow struct {
+	Line   int
+	Book   Book
+	Errors []string
+}
+
+// TitleAuthor => key of a book without ISBN for matching the rows of an import
+type TitleAuthor struct {
+	Title  string
+	Author string
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Member => a library member who can borrow books
+// => MaxLoans zero means the configured default limit, ActiveLoans is kept by checkouts and returns
+type Member struct {
+	ID          string             `json:"_id,omitempty" bson:"_id,omitempty"`
+	CreatedDate primitive.DateTime `json:"createddate,omitempty" bson:"createddate"`
+	UpdatedDate primitive.DateTime `json:"updateddate" bson:"updateddate"`
+	Name        string             `json:"name" bson:"name"`
+	Email       string             `json:"email" bson:"email"`
+	MaxLoans    int                `json:"maxloans" bson:"maxloans"`
+	ActiveLoans int                `json:"activeloans" bson:"activeloans"`
+	TenantID    string             `json:"tenantid" bson:"tenantid"`
+}
+
+// Loan => a copy of a book checked out by a member, it is active until ReturnedDate is set
+type Loan struct {
+	ID           string              `json:"_id,omitempty" bson:"_id,omitempty"`
+	MemberID     string              `json:"memberid" bson:"memberid"`
+	BookID       string              `json:"bookid" bson:"bookid"`
+	LoanDate     primitive.DateTime  `json:"loandate" bson:"loandate"`
+	DueDate      primitive.DateTime  `json:"duedate" bson:"duedate"`
+	ReturnedDate *primitive.DateTime `json:"returneddate,omitempty" bson:"returneddate,omitempty"`
+	TenantID     string              `json:"tenantid" bson:"tenantid"`
+}
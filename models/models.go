@@ -7,12 +7,37 @@ import (
 // #1- omitempty => to send the filled data
 // #2- primitive.ObjectId => MongoDB.ObjectId
 // #3- uuid(v4) with MongoDB
+// #4- bson names are written explicitly, queries/indexes/migrations use them => don't rename a field without a migration
+// #5- DeletedAt => soft delete, book is in trash if it is set
+// #6- Reserved => copies held by reservations, they are in Quantity but not available (Quantity - Reserved)
+// #7- AuthorIDs => references to authors collection, Author is kept as the display name
+// #8- ISBN10 & ISBN13 => normalized (no hyphens), both are stored if the ISBN-13 has an ISBN-10
+// #9- Category => path in the category tree ("Fiction/Fantasy"), CategoryPath => the path and its ancestors for filtering
+// #10- TenantID => bookstore of the book, repository stamps it and scopes every query to the tenant of the request
 
 type Book struct {
-	ID          string             `json:"_id,omitempty" bson:"_id,omitempty"`
-	CreatedDate primitive.DateTime `json:"createddate,omitempty"`
-	UpdatedDate primitive.DateTime `json:"updateddate"`
-	Title       string             `json:"title,omitempty"`
-	Author      string             `json:"author,omitempty"`
-	Quantity    int                `json:"quantity,omitempty"`
+	ID           string              `json:"_id,omitempty" bson:"_id,omitempty"`
+	CreatedDate  primitive.DateTime  `json:"createddate,omitempty" bson:"createddate"`
+	UpdatedDate  primitive.DateTime  `json:"updateddate" bson:"updateddate"`
+	Title        string              `json:"title,omitempty" bson:"title"`
+	Author       string              `json:"author,omitempty" bson:"author"`
+	AuthorIDs    []string            `json:"authorids,omitempty" bson:"authorids,omitempty"`
+	ISBN10       string              `json:"isbn10,omitempty" bson:"isbn10,omitempty"`
+	ISBN13       string              `json:"isbn13,omitempty" bson:"isbn13,omitempty"`
+	Category     string              `json:"category,omitempty" bson:"category,omitempty"`
+	CategoryPath []string            `json:"categorypath,omitempty" bson:"categorypath,omitempty"`
+	Tags         []string            `json:"tags,omitempty" bson:"tags,omitempty"`
+	Quantity     int                 `json:"quantity,omitempty" bson:"quantity"`
+	Version      int                 `json:"version" bson:"version"`
+	Reserved     int                 `json:"reserved" bson:"reserved"`
+	DeletedAt    *primitive.DateTime `json:"deletedat,omitempty" bson:"deletedat,omitempty"`
+	TenantID     string              `json:"tenantid" bson:"tenantid"`
+}
+
+// BookSearchResult => book found by full-text search with its relevance score
+// => Highlights is filled by service (field name => text with matched terms in <em></em>)
+type BookSearchResult struct {
+	Book       `bson:",inline"`
+	Score      float64           `json:"score" bson:"score"`
+	Highlights map[string]string `json:"highlights,omitempty" bson:"-"`
 }
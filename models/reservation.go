@@ -0,0 +1,26 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reservation statuses, only a pending reservation holds copies
+const (
+	ReservationPending   = "pending"
+	ReservationConfirmed = "confirmed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Reservation => copies of a book held for a while, e.g. while the customer pays
+type Reservation struct {
+	ID           string              `json:"id" bson:"_id"`
+	BookID       string              `json:"bookid" bson:"bookid"`
+	Quantity     int                 `json:"quantity" bson:"quantity"`
+	Status       string              `json:"status" bson:"status"`
+	CreatedDate  primitive.DateTime  `json:"createddate" bson:"createddate"`
+	ExpiresAt    primitive.DateTime  `json:"expiresat" bson:"expiresat"`
+	FinishedDate *primitive.DateTime `json:"finisheddate,omitempty" bson:"finisheddate,omitempty"`
+	Actor        string              `json:"actor" bson:"actor"`
+	TenantID     string              `json:"tenantid" bson:"tenantid"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// movement types, quantity of a movement is always positive
+const (
+	StockIncrement = "increment"
+	StockDecrement = "decrement"
+)
+
+// movement reasons
+const (
+	ReasonRestock    = "restock"
+	ReasonSale       = "sale"
+	ReasonDamage     = "damage"
+	ReasonAdjustment = "adjustment"
+	// only for the movements of lending
+	ReasonLoan   = "loan"
+	ReasonReturn = "return"
+)
+
+// StockMovement => a single change of the quantity of a book, the ledger of a book is the list of its movements
+type StockMovement struct {
+	ID            string             `json:"id" bson:"_id"`
+	BookID        string             `json:"bookid" bson:"bookid"`
+	Date          primitive.DateTime `json:"date" bson:"date"`
+	Type          string             `json:"type" bson:"type"`
+	Reason        string             `json:"reason" bson:"reason"`
+	Quantity      int                `json:"quantity" bson:"quantity"`
+	QuantityAfter int                `json:"quantityafter" bson:"quantityafter"`
+	Note          string             `json:"note,omitempty" bson:"note,omitempty"`
+	Actor         string             `json:"actor" bson:"actor"`
+	RequestID     string             `json:"requestid,omitempty" bson:"requestid,omitempty"`
+	TenantID      string             `json:"tenantid,omitempty" bson:"tenantid,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User => a user of the api, only the bcrypt hash of the password is stored
+// => roles are the names of rbac roles, e.g. editor, a user can only use the catalog of its tenant
+type User struct {
+	ID           string             `json:"_id,omitempty" bson:"_id,omitempty"`
+	CreatedDate  primitive.DateTime `json:"createddate,omitempty" bson:"createddate"`
+	UpdatedDate  primitive.DateTime `json:"updateddate" bson:"updateddate"`
+	Username     string             `json:"username" bson:"username"`
+	PasswordHash string             `json:"-" bson:"passwordhash"`
+	Roles        []string           `json:"roles" bson:"roles"`
+	TenantID     string             `json:"tenantid" bson:"tenantid"`
+}
+
+// RefreshToken => a refresh token of a user, id is the sha256 of the token so the token itself is never stored
+// => a token can be used only once, it is revoked and replaced by a new one of the same family (login)
+type RefreshToken struct {
+	ID          string              `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID      string              `json:"userid" bson:"userid"`
+	Family      string              `json:"family" bson:"family"`
+	CreatedDate primitive.DateTime  `json:"createddate" bson:"createddate"`
+	ExpiresAt   primitive.DateTime  `json:"expiresat" bson:"expiresat"`
+	RevokedAt   *primitive.DateTime `json:"revokedat,omitempty" bson:"revokedat,omitempty"`
+}
+
+// TokenPair => tokens of a login or a refresh, ExpiresIn is the lifetime of the access token in seconds
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
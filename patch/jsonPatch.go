@@ -0,0 +1,282 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPatch => patch document itself cannot be parsed
+var ErrInvalidPatch = errors.New("invalid patch document")
+
+// ErrTestFailed => "test" operation of a JSON Patch doesn't match, nothing is applied
+var ErrTestFailed = errors.New("test operation failed")
+
+// Operation => single operation of RFC 6902 JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch => RFC 6902 JSON Patch, operations are applied in order and all of them or none is applied
+func ApplyJSONPatch(document []byte, patch []byte) ([]byte, error) {
+	var operations []Operation
+
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, operation := range operations {
+		if target, err = apply(target, operation); err != nil {
+			if err == ErrTestFailed {
+				return nil, err
+			}
+			return nil, fmt.Errorf("operation %d (%v %v): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func apply(target interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("value is required")
+		}
+		value, err := decode(operation.Value)
+		if err != nil {
+			return nil, ErrInvalidPatch
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(target, path, value)
+		case "replace":
+			if _, err := get(target, path); err != nil {
+				return nil, err
+			}
+			// whole document is replaced, it cannot be removed first
+			if len(path) == 0 {
+				return value, nil
+			}
+			if target, err = remove(target, path); err != nil {
+				return nil, err
+			}
+			return add(target, path, value)
+		default:
+			current, err := get(target, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return target, nil
+		}
+
+	case "remove":
+		return remove(target, path)
+
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(target, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if operation.Op == "move" {
+			// a location cannot be moved into one of its children
+			if strings.HasPrefix(operation.Path+"/", operation.From+"/") && operation.Path != operation.From {
+				return nil, fmt.Errorf("cannot move {%v} into its child", operation.From)
+			}
+			if target, err = remove(target, from); err != nil {
+				return nil, err
+			}
+		} else {
+			// copied value must not share maps/slices with the source
+			if value, err = clone(value); err != nil {
+				return nil, err
+			}
+		}
+
+		return add(target, path, value)
+	}
+
+	return nil, fmt.Errorf("unknown operation {%v}", operation.Op)
+}
+
+// parsePointer => RFC 6901 JSON Pointer, "/a/b~1c" => ["a", "b/c"]
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer {%v}", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func get(target interface{}, path []string) (interface{}, error) {
+	current := target
+
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path {%v} does not exist", token)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path {%v} does not exist", token)
+		}
+	}
+
+	return current, nil
+}
+
+// add => it returns the new root, because root can be replaced and arrays can grow
+func add(target interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return target, nil
+	case []interface{}:
+		index := len(node)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		grown := append(node[:index:index], append([]interface{}{value}, node[index:]...)...)
+		return replaceAt(target, path[:len(path)-1], grown)
+	}
+
+	return nil, fmt.Errorf("cannot add {%v}", last)
+}
+
+func remove(target interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("path {%v} does not exist", last)
+		}
+		delete(node, last)
+		return target, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		shrunk := append(node[:index:index], node[index+1:]...)
+		return replaceAt(target, path[:len(path)-1], shrunk)
+	}
+
+	return nil, fmt.Errorf("path {%v} does not exist", last)
+}
+
+// replaceAt => to put a new slice value where the old one was
+func replaceAt(target interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		index, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+
+	return target, nil
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index {%v}", token)
+	}
+	return index, nil
+}
+
+func clone(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+// equal => numbers are compared by value, 1 and 1.0 are equal
+func equal(a interface{}, b interface{}) bool {
+	aNumber, aOk := a.(json.Number)
+	bNumber, bOk := b.(json.Number)
+	if aOk && bOk {
+		aFloat, _ := aNumber.Float64()
+		bFloat, _ := bNumber.Float64()
+		return aFloat == bFloat
+	}
+
+	return reflect.DeepEqual(a, b)
+}
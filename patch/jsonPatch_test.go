@@ -0,0 +1,80 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		result   string
+		err      error
+		invalid  bool
+	}{
+		{name: "add member", document: `{"a":1}`, patch: `[{"op":"add","path":"/b","value":2}]`, result: `{"a":1,"b":2}`},
+		{name: "replace whole document", document: `{"a":1}`, patch: `[{"op":"replace","path":"","value":[1]}]`, result: `[1]`},
+		{name: "~1 is slash", document: `{"a/b":1}`, patch: `[{"op":"replace","path":"/a~1b","value":2}]`, result: `{"a/b":2}`},
+		{name: "~0 is tilde", document: `{"m~n":1}`, patch: `[{"op":"remove","path":"/m~0n"}]`, result: `{}`},
+		// ~01 is "~1" itself, ~0 is unescaped after ~1
+		{name: "~01 is tilde one", document: `{"~1":1,"/":2}`, patch: `[{"op":"remove","path":"/~01"}]`, result: `{"/":2}`},
+		{name: "- appends", document: `{"a":[1,2]}`, patch: `[{"op":"add","path":"/a/-","value":3}]`, result: `{"a":[1,2,3]}`},
+		{name: "index inserts", document: `{"a":[1,2]}`, patch: `[{"op":"add","path":"/a/0","value":0}]`, result: `{"a":[0,1,2]}`},
+		{name: "length index appends", document: `{"a":[1]}`, patch: `[{"op":"add","path":"/a/1","value":2}]`, result: `{"a":[1,2]}`},
+		{name: "index after the end", document: `{"a":[1]}`, patch: `[{"op":"add","path":"/a/2","value":2}]`, invalid: true},
+		{name: "index with leading zero", document: `{"a":[1,2]}`, patch: `[{"op":"replace","path":"/a/01","value":3}]`, invalid: true},
+		{name: "- cannot be removed", document: `{"a":[1]}`, patch: `[{"op":"remove","path":"/a/-"}]`, invalid: true},
+		{name: "remove array item", document: `{"a":[1,2,3]}`, patch: `[{"op":"remove","path":"/a/1"}]`, result: `{"a":[1,3]}`},
+		{name: "remove missing member", document: `{"a":1}`, patch: `[{"op":"remove","path":"/b"}]`, invalid: true},
+		{name: "remove missing parent", document: `{"a":1}`, patch: `[{"op":"remove","path":"/b/c"}]`, invalid: true},
+		{name: "remove whole document", document: `{"a":1}`, patch: `[{"op":"remove","path":""}]`, invalid: true},
+		{name: "replace missing member", document: `{"a":1}`, patch: `[{"op":"replace","path":"/b","value":2}]`, invalid: true},
+		{name: "test passes", document: `{"a":{"b":[1,"x"]}}`, patch: `[{"op":"test","path":"/a","value":{"b":[1,"x"]}}]`,
+			result: `{"a":{"b":[1,"x"]}}`},
+		{name: "numbers are tested by value", document: `{"a":1}`, patch: `[{"op":"test","path":"/a","value":1.0}]`, result: `{"a":1}`},
+		{name: "test fails", document: `{"a":1}`, patch: `[{"op":"add","path":"/b","value":2},{"op":"test","path":"/a","value":"1"}]`,
+			err: ErrTestFailed},
+		{name: "test of missing member", document: `{"a":1}`, patch: `[{"op":"test","path":"/b","value":1}]`, invalid: true},
+		{name: "move", document: `{"a":{"b":1},"c":{}}`, patch: `[{"op":"move","from":"/a/b","path":"/c/d"}]`, result: `{"a":{},"c":{"d":1}}`},
+		{name: "move into itself", document: `{"a":1}`, patch: `[{"op":"move","from":"/a","path":"/a"}]`, result: `{"a":1}`},
+		{name: "move into its child", document: `{"a":{"b":1}}`, patch: `[{"op":"move","from":"/a","path":"/a/c"}]`, invalid: true},
+		{name: "move into sibling with same prefix", document: `{"a":1,"ab":{}}`, patch: `[{"op":"move","from":"/a","path":"/ab/a"}]`,
+			result: `{"ab":{"a":1}}`},
+		// copied value is cloned, so the copy into the child of the source doesn't make a cycle
+		{name: "copy into its child", document: `{"a":{"b":1}}`, patch: `[{"op":"copy","from":"/a","path":"/a/c"}]`,
+			result: `{"a":{"b":1,"c":{"b":1}}}`},
+		{name: "copy is not shared", document: `{"a":{"b":1}}`,
+			patch:  `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			result: `{"a":{"b":1},"c":{"b":2}}`},
+		{name: "copy of missing member", document: `{"a":1}`, patch: `[{"op":"copy","from":"/b","path":"/c"}]`, invalid: true},
+		{name: "large integers are kept", document: `{"a":12345678901234567890}`, patch: `[{"op":"add","path":"/b","value":1}]`,
+			result: `{"a":12345678901234567890,"b":1}`},
+		{name: "pointer without slash", document: `{"a":1}`, patch: `[{"op":"remove","path":"a"}]`, invalid: true},
+		{name: "value is required", document: `{"a":1}`, patch: `[{"op":"add","path":"/b"}]`, invalid: true},
+		{name: "unknown operation", document: `{"a":1}`, patch: `[{"op":"increment","path":"/a"}]`, invalid: true},
+		{name: "patch is not an array", document: `{"a":1}`, patch: `{"op":"remove","path":"/a"}`, err: ErrInvalidPatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ApplyJSONPatch([]byte(test.document), []byte(test.patch))
+
+			switch {
+			case test.err != nil:
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v (%s)", test.err, err, result)
+				}
+			case test.invalid:
+				if err == nil || errors.Is(err, ErrTestFailed) {
+					t.Fatalf("patch must be rejected, got %v (%s)", err, result)
+				}
+			case err != nil:
+				t.Fatalf("patch cannot be applied: %v", err)
+			case string(result) != test.result:
+				t.Fatalf("expected %s, got %s", test.result, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		result   string
+		err      error
+	}{
+		{name: "replace member", document: `{"a":1,"b":2}`, patch: `{"a":3}`, result: `{"a":3,"b":2}`},
+		{name: "null deletes member", document: `{"a":1,"b":2}`, patch: `{"a":null}`, result: `{"b":2}`},
+		{name: "null of missing member", document: `{"a":1}`, patch: `{"b":null}`, result: `{"a":1}`},
+		{name: "objects are merged", document: `{"a":{"b":1,"c":2}}`, patch: `{"a":{"c":null,"d":3}}`, result: `{"a":{"b":1,"d":3}}`},
+		{name: "arrays are replaced", document: `{"a":[1,2]}`, patch: `{"a":[3]}`, result: `{"a":[3]}`},
+		{name: "merge into scalar member", document: `{"a":"x"}`, patch: `{"a":{"b":1}}`, result: `{"a":{"b":1}}`},
+		// nulls of a new object are not kept in it
+		{name: "nulls in new object", document: `{}`, patch: `{"a":{"b":null,"c":1}}`, result: `{"a":{"c":1}}`},
+		{name: "merge into array document", document: `[1,2]`, patch: `{"a":1}`, result: `{"a":1}`},
+		{name: "merge into null document", document: `null`, patch: `{"a":{"b":null}}`, result: `{"a":{}}`},
+		{name: "scalar patch replaces document", document: `{"a":1}`, patch: `"x"`, result: `"x"`},
+		{name: "empty patch", document: `{"a":1}`, patch: `{}`, result: `{"a":1}`},
+		{name: "large integers are kept", document: `{"a":12345678901234567890}`, patch: `{"b":1}`,
+			result: `{"a":12345678901234567890,"b":1}`},
+		{name: "invalid patch", document: `{"a":1}`, patch: `{"a":`, err: ErrInvalidPatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := MergePatch([]byte(test.document), []byte(test.patch))
+
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v (%s)", test.err, err, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patch cannot be applied: %v", err)
+			}
+			if string(result) != test.result {
+				t.Fatalf("expected %s, got %s", test.result, result)
+			}
+		})
+	}
+
+	if _, err := MergePatch([]byte(`{"a":`), []byte(`{}`)); err == nil || errors.Is(err, ErrInvalidPatch) {
+		t.Fatalf("invalid document must not be reported as invalid patch: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package rbac
+
+import (
+	"RestfulWithEcho/errors"
+	"strings"
+)
+
+// Role => role of a user, a user can have many roles and has the permissions of all of them
+type Role = string
+
+const (
+	Viewer           Role = "viewer"
+	Editor           Role = "editor"
+	InventoryManager Role = "inventory-manager"
+	Admin            Role = "admin"
+)
+
+// Permission => an operation of the catalog, routes declare the permissions which they need
+type Permission string
+
+const (
+	// ReadBooks => listing, searching & exporting books
+	ReadBooks Permission = "books:read"
+	// WriteBooks => creating & changing books except their quantities
+	WriteBooks Permission = "books:write"
+	// DeleteBooks => moving books into trash, restoring & purging them
+	DeleteBooks Permission = "books:delete"
+	// ChangeStock => changing quantities of books
+	ChangeStock Permission = "books:stock"
+	// ManageAPIKeys => creating, rotating & revoking api keys, it cannot be a scope of an api key
+	ManageAPIKeys Permission = "apikeys:manage"
+)
+
+// grants => permissions of the roles
+var grants = map[Role][]Permission{
+	Viewer:           {ReadBooks},
+	Editor:           {ReadBooks, WriteBooks},
+	InventoryManager: {ReadBooks, ChangeStock},
+	Admin:            {ReadBooks, WriteBooks, DeleteBooks, ChangeStock, ManageAPIKeys},
+}
+
+// ErrForbidden => user has none of the roles which have the permission
+var ErrForbidden = errors.New(errors.Forbidden, "forbidden", "permission is denied")
+
+// ErrUnknownRole => role is not one of the declared roles
+var ErrUnknownRole = errors.New(errors.Invalid, "unknown_role", "unknown role")
+
+// ErrUnknownScope => scope of an api key is not one of Scopes
+var ErrUnknownScope = errors.New(errors.Invalid, "unknown_scope", "unknown scope")
+
+// Roles => every role, e.g. for usage texts
+func Roles() []Role {
+	return []Role{Viewer, Editor, InventoryManager, Admin}
+}
+
+// Scopes => permissions which an api key can have, keys are for the catalog only
+func Scopes() []Permission {
+	return []Permission{ReadBooks, WriteBooks, DeleteBooks, ChangeStock}
+}
+
+// ValidRoles => ErrUnknownRole for the first role which is not declared
+func ValidRoles(roles []string) error {
+	for _, role := range roles {
+		if _, ok := grants[role]; !ok {
+			return ErrUnknownRole.Withf("{%v} is not a role, roles are %v", role, strings.Join(Roles(), ", "))
+		}
+	}
+	return nil
+}
+
+// ValidScopes => ErrUnknownScope for the first scope which is not one of Scopes
+func ValidScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !hasPermission(Scopes(), Permission(scope)) {
+			return ErrUnknownScope.Withf("{%v} is not a scope, scopes are %v", scope, Scopes())
+		}
+	}
+	return nil
+}
+
+// Allowed => one of the roles has the permission
+func Allowed(roles []string, permission Permission) bool {
+	for _, role := range roles {
+		if hasPermission(grants[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize => ErrForbidden with the first permission which none of the roles has
+func Authorize(roles []string, permissions ...Permission) error {
+	for _, permission := range permissions {
+		if !Allowed(roles, permission) {
+			return ErrForbidden.Withf("{%v} permission is required", permission)
+		}
+	}
+	return nil
+}
+
+// AuthorizeScopes => ErrForbidden with the first permission which is not one of the scopes of an api key
+func AuthorizeScopes(scopes []string, permissions ...Permission) error {
+	for _, permission := range permissions {
+		if !hasPermission(toPermissions(scopes), permission) {
+			return ErrForbidden.Withf("{%v} scope is required", permission)
+		}
+	}
+	return nil
+}
+
+func hasPermission(permissions []Permission, permission Permission) bool {
+	for _, granted := range permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func toPermissions(scopes []string) []Permission {
+	permissions := make([]Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		permissions = append(permissions, Permission(scope))
+	}
+	return permissions
+}
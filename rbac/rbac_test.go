@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"RestfulWithEcho/errors"
+	"testing"
+)
+
+func TestGrants(t *testing.T) {
+	tests := []struct {
+		role    Role
+		allowed []Permission
+		denied  []Permission
+	}{
+		{role: Viewer, allowed: []Permission{ReadBooks},
+			denied: []Permission{WriteBooks, DeleteBooks, ChangeStock, ManageAPIKeys}},
+		{role: Editor, allowed: []Permission{ReadBooks, WriteBooks},
+			denied: []Permission{DeleteBooks, ChangeStock, ManageAPIKeys}},
+		{role: InventoryManager, allowed: []Permission{ReadBooks, ChangeStock},
+			denied: []Permission{WriteBooks, DeleteBooks, ManageAPIKeys}},
+		{role: Admin, allowed: []Permission{ReadBooks, WriteBooks, DeleteBooks, ChangeStock, ManageAPIKeys}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.role, func(t *testing.T) {
+			for _, permission := range test.allowed {
+				if !Allowed([]string{test.role}, permission) {
+					t.Errorf("%v must have %v", test.role, permission)
+				}
+			}
+			for _, permission := range test.denied {
+				if Allowed([]string{test.role}, permission) {
+					t.Errorf("%v must not have %v", test.role, permission)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name        string
+		roles       []string
+		permissions []Permission
+		forbidden   bool
+	}{
+		{name: "no roles", roles: nil, permissions: []Permission{ReadBooks}, forbidden: true},
+		{name: "unknown role", roles: []string{"owner"}, permissions: []Permission{ReadBooks}, forbidden: true},
+		{name: "no permissions", roles: nil},
+		{name: "single role", roles: []string{Editor}, permissions: []Permission{WriteBooks}},
+		{name: "permissions of many roles", roles: []string{Editor, InventoryManager},
+			permissions: []Permission{WriteBooks, ChangeStock}},
+		{name: "all permissions are required", roles: []string{Editor},
+			permissions: []Permission{WriteBooks, ChangeStock}, forbidden: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Authorize(test.roles, test.permissions...)
+			if forbidden := errors.Is(err, ErrForbidden); forbidden != test.forbidden || (!forbidden && err != nil) {
+				t.Fatalf("unexpected result: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeScopes(t *testing.T) {
+	tests := []struct {
+		name        string
+		scopes      []string
+		permissions []Permission
+		forbidden   bool
+	}{
+		{name: "no scopes", scopes: nil, permissions: []Permission{ReadBooks}, forbidden: true},
+		{name: "scope", scopes: []string{"books:read"}, permissions: []Permission{ReadBooks}},
+		{name: "roles are not scopes", scopes: []string{Admin}, permissions: []Permission{ReadBooks}, forbidden: true},
+		{name: "all scopes are required", scopes: []string{"books:write"},
+			permissions: []Permission{WriteBooks, ChangeStock}, forbidden: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := AuthorizeScopes(test.scopes, test.permissions...)
+			if forbidden := errors.Is(err, ErrForbidden); forbidden != test.forbidden || (!forbidden && err != nil) {
+				t.Fatalf("unexpected result: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidRolesAndScopes(t *testing.T) {
+	if err := ValidRoles(Roles()); err != nil {
+		t.Fatalf("declared roles are not valid: %v", err)
+	}
+	if err := ValidRoles([]string{Viewer, "owner"}); !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("unknown role is accepted: %v", err)
+	}
+
+	scopes := make([]string, 0, len(Scopes()))
+	for _, scope := range Scopes() {
+		scopes = append(scopes, string(scope))
+	}
+	if err := ValidScopes(scopes); err != nil {
+		t.Fatalf("declared scopes are not valid: %v", err)
+	}
+	// api keys cannot manage api keys
+	if err := ValidScopes([]string{string(ManageAPIKeys)}); !errors.Is(err, ErrUnknownScope) {
+		t.Fatalf("apikeys:manage is accepted as a scope: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// APIKeyCollection => api keys of the machine clients
+const APIKeyCollection = "apikeys"
+
+type APIKeyRepository struct {
+	APIKeyCollection *mongo.Collection
+}
+
+var singleInstanceAPIKeyRepo *APIKeyRepository
+
+func GetSingleInstancesAPIKeyRepository(mongoCollection *mongo.Collection) *APIKeyRepository {
+	if singleInstanceAPIKeyRepo == nil {
+		fmt.Println("Creating single api key repository instance now.")
+		singleInstanceAPIKeyRepo = &APIKeyRepository{APIKeyCollection: mongoCollection}
+	} else {
+		fmt.Println("Single api key repository instance already created.")
+	}
+
+	return singleInstanceAPIKeyRepo
+}
+
+type IAPIKeyRepository interface {
+	Insert(ctx context.Context, key models.APIKey) error
+	GetAll(ctx context.Context, skip int64, limit int64) ([]models.APIKey, int64, error)
+	GetById(ctx context.Context, id string) (models.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	Rotate(ctx context.Context, id string, prefix string, hash string) error
+	Revoke(ctx context.Context, id string) error
+	Track(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// Insert method => to save a new api key of the request tenant, ErrDuplicateKey if the prefix is taken
+func (a APIKeyRepository) Insert(ctx context.Context, key models.APIKey) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	key.TenantID = tenantOf(ctx)
+
+	_, err := a.APIKeyCollection.InsertOne(ctx, key)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+
+	return err
+}
+
+// GetAll method => to list api keys with revoked ones, newest first
+func (a APIKeyRepository) GetAll(ctx context.Context, skip int64, limit int64) ([]models.APIKey, int64, error) {
+	var keys []models.APIKey
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{})
+
+	total, err := a.APIKeyCollection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(skip).SetLimit(limit).SetSort(bson.D{{Key: "createddate", Value: -1}, {Key: "_id", Value: 1}})
+
+	result, err := a.APIKeyCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var key models.APIKey
+		if err := result.Decode(&key); err != nil {
+			return nil, 0, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, total, result.Err()
+}
+
+// GetById method => to find a single api key with id
+func (a APIKeyRepository) GetById(ctx context.Context, id string) (models.APIKey, error) {
+	var key models.APIKey
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := a.APIKeyCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&key)
+
+	return key, err
+}
+
+// GetByPrefix method => to find the api key of a request, prefix is the public part of the key
+// => it is not scoped, tenant of the request is the tenant of its key
+func (a APIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	var key models.APIKey
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := a.APIKeyCollection.FindOne(ctx, bson.M{"prefix": prefix}).Decode(&key)
+
+	return key, err
+}
+
+// Rotate method => to replace the key of an api key which is not revoked, mongo.ErrNoDocuments if there is no such key
+// => ErrDuplicateKey if the new prefix is taken
+func (a APIKeyRepository) Rotate(ctx context.Context, id string, prefix string, hash string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "revokedat": bson.M{"$exists": false}})
+	update := bson.M{"$set": bson.M{"prefix": prefix, "hash": hash, "updateddate": primitive.NewDateTimeFromTime(time.Now())}}
+
+	result, err := a.APIKeyCollection.UpdateOne(ctx, filter, update)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Revoke method => to revoke an api key which is not revoked yet, mongo.ErrNoDocuments if there is no such key
+func (a APIKeyRepository) Revoke(ctx context.Context, id string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	filter := scoped(ctx, bson.M{"_id": id, "revokedat": bson.M{"$exists": false}})
+	update := bson.M{"$set": bson.M{"revokedat": now, "updateddate": now}}
+
+	result, err := a.APIKeyCollection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Track method => to count a request of an api key and set its last used time
+func (a APIKeyRepository) Track(ctx context.Context, id string, usedAt time.Time) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$inc": bson.M{"requestcount": 1},
+		"$max": bson.M{"lastusedat": primitive.NewDateTimeFromTime(usedAt)},
+	}
+
+	_, err := a.APIKeyCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+
+	return err
+}
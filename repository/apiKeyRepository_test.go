@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+	"time"
+)
+
+func TestGetAllKeepsActiveKeysActive(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("GetAll", func(mt *mtest.T) {
+		now := primitive.NewDateTimeFromTime(time.Now())
+		revoked := bson.D{{Key: "_id", Value: "1"}, {Key: "name", Value: "old"}, {Key: "revokedat", Value: now}, {Key: "lastusedat", Value: now}}
+		active := bson.D{{Key: "_id", Value: "2"}, {Key: "name", Value: "new"}}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.apikeys", mtest.FirstBatch, bson.D{{Key: "n", Value: 2}}),
+			mtest.CreateCursorResponse(0, "test.apikeys", mtest.FirstBatch, revoked, active),
+		)
+
+		keys, _, err := APIKeyRepository{APIKeyCollection: mt.Coll}.GetAll(context.Background(), 0, 10)
+
+		if err != nil || len(keys) != 2 {
+			mt.Fatalf("expected 2 keys, got %d (%v)", len(keys), err)
+		}
+		if keys[0].RevokedAt == nil {
+			mt.Fatalf("revoked key is not decoded: %+v", keys[0])
+		}
+		if keys[1].RevokedAt != nil || keys[1].LastUsedAt != nil {
+			mt.Fatalf("active key has the dates of the revoked one: %+v", keys[1])
+		}
+	})
+}
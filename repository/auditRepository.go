@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// AuditCollection => audit records are kept apart from the entities
+const AuditCollection = "audit"
+
+type AuditRepository struct {
+	AuditCollection *mongo.Collection
+}
+
+var singleInstanceAuditRepo *AuditRepository
+
+func GetSingleInstancesAuditRepository(mongoCollection *mongo.Collection) *AuditRepository {
+	if singleInstanceAuditRepo == nil {
+		fmt.Println("Creating single audit repository instance now.")
+		singleInstanceAuditRepo = &AuditRepository{AuditCollection: mongoCollection}
+	} else {
+		fmt.Println("Single audit repository instance already created.")
+	}
+
+	return singleInstanceAuditRepo
+}
+
+type IAuditRepository interface {
+	Insert(ctx context.Context, record models.AuditRecord) error
+	Find(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, int64, error)
+}
+
+// Insert method => to write a new audit record of the request tenant, records are never changed
+func (a AuditRepository) Insert(ctx context.Context, record models.AuditRecord) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	record.TenantID = requestinfo.From(ctx).TenantID
+
+	_, err := a.AuditCollection.InsertOne(ctx, record)
+
+	return err
+}
+
+// Find method => to list audit records by filters page by page, it returns total count of the filtered records too
+func (a AuditRepository) Find(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, int64, error) {
+	var records []models.AuditRecord
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{})
+	for field, value := range map[string]string{
+		"entitytype": query.EntityType,
+		"entityid":   query.EntityID,
+		"action":     query.Action,
+		"actor":      query.Actor,
+		"requestid":  query.RequestID,
+	} {
+		if value != "" {
+			filter[field] = value
+		}
+	}
+
+	date := bson.M{}
+	if query.From != nil {
+		date["$gte"] = primitive.NewDateTimeFromTime(*query.From)
+	}
+	if query.To != nil {
+		date["$lt"] = primitive.NewDateTimeFromTime(*query.To)
+	}
+	if len(date) > 0 {
+		filter["date"] = date
+	}
+
+	total, err := a.AuditCollection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(query.Skip).SetLimit(query.Limit).
+		SetSort(bson.D{{Key: "date", Value: -1}, {Key: "_id", Value: -1}})
+
+	result, err := a.AuditCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		// changes of the previous record must not be kept
+		var record models.AuditRecord
+		if err := result.Decode(&record); err != nil {
+			return nil, 0, err
+		}
+		records = append(records, record)
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// AuthorCollection => authors which books reference
+const AuthorCollection = "authors"
+
+type AuthorRepository struct {
+	AuthorCollection *mongo.Collection
+}
+
+var singleInstanceAuthorRepo *AuthorRepository
+
+func GetSingleInstancesAuthorRepository(mongoCollection *mongo.Collection) *AuthorRepository {
+	if singleInstanceAuthorRepo == nil {
+		fmt.Println("Creating single author repository instance now.")
+		singleInstanceAuthorRepo = &AuthorRepository{AuthorCollection: mongoCollection}
+	} else {
+		fmt.Println("Single author repository instance already created.")
+	}
+
+	return singleInstanceAuthorRepo
+}
+
+type IAuthorRepository interface {
+	Insert(ctx context.Context, author models.Author) error
+	GetAll(ctx context.Context, skip int64, limit int64) ([]models.Author, int64, error)
+	GetById(ctx context.Context, id string) (models.Author, error)
+	GetByIds(ctx context.Context, ids []string) ([]models.Author, error)
+	Update(ctx context.Context, author models.Author) (models.Author, error)
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// Insert method => to create new author in the request tenant, ErrDuplicateKey if an author with the same key exists
+func (a AuthorRepository) Insert(ctx context.Context, author models.Author) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	author.TenantID = tenantOf(ctx)
+	_, err := a.AuthorCollection.InsertOne(ctx, author)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+
+	return err
+}
+
+// GetAll method => to list authors page by page ordered by name
+func (a AuthorRepository) GetAll(ctx context.Context, skip int64, limit int64) ([]models.Author, int64, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	total, err := a.AuthorCollection.CountDocuments(ctx, scoped(ctx, bson.M{}))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(skip).SetLimit(limit).SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	authors, err := a.find(ctx, scoped(ctx, bson.M{}), opts)
+
+	return authors, total, err
+}
+
+// GetById method => to find a single author with id
+func (a AuthorRepository) GetById(ctx context.Context, id string) (models.Author, error) {
+	var author models.Author
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := a.AuthorCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&author)
+
+	return author, err
+}
+
+// GetByIds method => authors with the given ids, unknown ids are skipped
+func (a AuthorRepository) GetByIds(ctx context.Context, ids []string) ([]models.Author, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return a.find(ctx, scoped(ctx, bson.M{"_id": bson.M{"$in": ids}}), options.Find())
+}
+
+// Update method => to rename an author, it returns the updated author
+func (a AuthorRepository) Update(ctx context.Context, author models.Author) (models.Author, error) {
+	var updated models.Author
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"name": author.Name, "key": author.Key, "updateddate": author.UpdatedDate}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := a.AuthorCollection.FindOneAndUpdate(ctx, scoped(ctx, bson.M{"_id": author.ID}), update, opts).Decode(&updated)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return updated, ErrDuplicateKey
+	}
+
+	return updated, err
+}
+
+// Delete method => to delete an author by id
+func (a AuthorRepository) Delete(ctx context.Context, id string) (bool, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := a.AuthorCollection.DeleteOne(ctx, scoped(ctx, bson.M{"_id": id}))
+
+	if err != nil || result.DeletedCount <= 0 {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (a AuthorRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Author, error) {
+	var authors []models.Author
+
+	result, err := a.AuthorCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var author models.Author
+		if err := result.Decode(&author); err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+
+	return authors, result.Err()
+}
@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
 	"time"
 )
 
@@ -16,6 +19,9 @@ type BookRepository struct {
 
 var singleInstanceRepo *BookRepository
 
+// ErrVersionConflict => book is changed by somebody else after the given version was read
+var ErrVersionConflict = errors.New("version conflict")
+
 func GetSingleInstancesRepository(mongoCollection *mongo.Collection) *BookRepository {
 	if singleInstanceRepo == nil {
 		fmt.Println("Creating single repository instance now.")
@@ -29,38 +35,58 @@ func GetSingleInstancesRepository(mongoCollection *mongo.Collection) *BookReposi
 
 // IBookRepository to use for test or
 type IBookRepository interface {
-	Insert(book models.Book) (bool, error)
-	GetAll() ([]models.Book, error)
-	GetBookById(id string) (models.Book, error)
-	Update(book models.Book) (bool, error)
-	Delete(id string) (bool, error)
+	Insert(ctx context.Context, book models.Book) (bool, error)
+	GetAll(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error)
+	GetAllAfter(ctx context.Context, query models.BookQuery, after *models.BookCursor) ([]models.Book, error)
+	Export(ctx context.Context, query models.BookQuery, each func(book models.Book) error) error
+	GetBookById(ctx context.Context, id string) (models.Book, error)
+	GetBookByISBN(ctx context.Context, isbn13 string) (models.Book, error)
+	GetBooksByIds(ctx context.Context, ids []string) ([]models.Book, error)
+	GetBooksByKeys(ctx context.Context, isbn13s []string, titleAuthors []models.TitleAuthor) ([]models.Book, error)
+	Search(ctx context.Context, text string, limit int64) ([]models.BookSearchResult, error)
+	Facets(ctx context.Context, query models.BookQuery, limit int64) (models.BookFacets, error)
+	Update(ctx context.Context, book models.Book) (models.Book, error)
+	UpdateFields(ctx context.Context, id string, version int, fields map[string]interface{}) (models.Book, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	Restore(ctx context.Context, id string) (bool, error)
+	Purge(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	BulkInsert(ctx context.Context, books []models.Book) (map[int]error, error)
+	BulkUpsert(ctx context.Context, books []models.Book) (map[int]bool, map[int]error, error)
+	BulkDelete(ctx context.Context, ids []string) ([]string, error)
 }
 
-// Insert method => to create new book
-func (b BookRepository) Insert(book models.Book) (bool, error) {
+// Insert method => to create new book in the tenant of the request
+func (b BookRepository) Insert(ctx context.Context, book models.Book) (bool, error) {
 	// to open connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	book.TenantID = tenantOf(ctx)
+
 	// mongodb.driver
 	result, err := b.BookCollection.InsertOne(ctx, book)
 
-	if result.InsertedID == nil || err != nil {
+	if mongo.IsDuplicateKeyError(err) {
+		return false, ErrDuplicateKey
+	}
+
+	if err != nil || result.InsertedID == nil {
 		return false, errors.New("failed to add")
 	}
 
 	return true, nil
 }
 
-// Update method => to change exist book
-func (b BookRepository) Update(book models.Book) (bool, error) {
+// Update method => to change exist book, it returns the updated book
+// => if book.Version is not zero, the book is updated only when its stored version is the same (optimistic concurrency)
+// => quantity cannot be less than held copies, ErrInsufficientStock is returned then
+func (b BookRepository) Update(ctx context.Context, book models.Book) (models.Book, error) {
 	// to open connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	// => Update => update + insert = upsert => default value false
 	// opt := options.Update().SetUpsert(true)
-	filter := bson.D{{"_id", book.ID}}
 
 	// => if we use this CreatedDate and id value will be null, so we have to use "UpdateOne"
 	//replacement := models.Book{Title: book.Title, Quantity: book.Quantity, Author: book.Author, UpdatedDate: book.UpdatedDate}
@@ -69,57 +95,355 @@ func (b BookRepository) Update(book models.Book) (bool, error) {
 	//update := bson.D{{"$set", bson.D{{"title", book.Title}}}}
 
 	// => if we have to chance more than one parameter we have to write like this
-	update := bson.D{{"$set", bson.D{{"title", book.Title},
-		{"author", book.Author}, {"quantity", book.Quantity}, {"updateddate", book.UpdatedDate}}}}
+	set := bson.D{{Key: "title", Value: book.Title}, {Key: "author", Value: book.Author},
+		{Key: "authorids", Value: book.AuthorIDs}, {Key: "isbn10", Value: book.ISBN10}, {Key: "isbn13", Value: book.ISBN13},
+		{Key: "category", Value: book.Category}, {Key: "categorypath", Value: book.CategoryPath}, {Key: "tags", Value: book.Tags},
+		{Key: "quantity", Value: book.Quantity}, {Key: "updateddate", Value: book.UpdatedDate}}
 
-	// mongodb.driver
-	result, err := b.BookCollection.UpdateOne(ctx, filter, update)
+	return b.updateVersioned(ctx, book.ID, book.Version, set)
+}
 
-	if result.ModifiedCount <= 0 || err != nil {
-		return false, errors.New("failed modify")
+// UpdateFields method => to change only the given fields (bson name => value) of a book, version works like in Update
+func (b BookRepository) UpdateFields(ctx context.Context, id string, version int, fields map[string]interface{}) (models.Book, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	set := bson.D{}
+	for field, value := range fields {
+		set = append(set, bson.E{Key: field, Value: value})
 	}
 
-	return true, nil
+	return b.updateVersioned(ctx, id, version, set)
 }
 
-// GetAll Method => to list every books
-func (b BookRepository) GetAll() ([]models.Book, error) {
-	var book models.Book
+// updateVersioned => $set + version increment, the version check is done in the same filter, so it is atomic
+func (b BookRepository) updateVersioned(ctx context.Context, id string, version int, set bson.D) (models.Book, error) {
+	var updated models.Book
+
+	// books in trash cannot be changed
+	filter := scoped(ctx, bson.M{"_id": id, "deletedat": nil})
+	if version > 0 {
+		filter["version"] = version
+	}
+
+	// quantity cannot be less than held copies (reservations & holds), the check is in the same filter
+	// => so a copy which is held concurrently cannot be lost by an update
+	var quantity interface{}
+	changesQuantity := false
+	for _, field := range set {
+		if field.Key == "quantity" {
+			quantity, changesQuantity = field.Value, true
+		}
+	}
+	if changesQuantity {
+		filter["reserved"] = bson.M{"$not": bson.M{"$gt": quantity}}
+	}
+
+	update := bson.D{{Key: "$set", Value: set}, {Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}}
+
+	// mongodb.driver => FindOneAndUpdate gives us the new version
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := b.BookCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return updated, ErrDuplicateKey
+	}
+
+	if err == mongo.ErrNoDocuments && (version > 0 || changesQuantity) {
+		var current models.Book
+		if findErr := b.BookCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id, "deletedat": nil})).Decode(&current); findErr == nil {
+			// book exists but with another version => somebody else has changed it
+			if version > 0 && current.Version != version {
+				return updated, ErrVersionConflict
+			}
+			return updated, ErrInsufficientStock
+		}
+	}
+
+	if err != nil {
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+// GetAll Method => to list books page by page with sorting and filtering, it returns total count of the filtered books too
+func (b BookRepository) GetAll(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error) {
 	var books []models.Book
 
 	// to open connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	filter := buildFilter(ctx, query)
+
+	total, err := b.BookCollection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(query.Skip).SetLimit(query.Limit).SetSort(buildSort(query.Sort))
+
 	//We can think of "Cursor" like a request. We pull the data from the database with the "Next" command. (C# => IQueryable)
-	result, err := b.BookCollection.Find(ctx, bson.M{})
+	result, err := b.BookCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var book models.Book
+		if err := result.Decode(&book); err != nil {
+			return nil, 0, err
+		}
+		// for appending book to books
+		books = append(books, book)
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return books, total, nil
+
+}
+
+// GetAllAfter Method => keyset pagination, to list books coming after the given cursor ordered by createddate & _id
+// => deep pages don't need to skip documents, and new books are appended to the end so the walk stays consistent
+func (b BookRepository) GetAllAfter(ctx context.Context, query models.BookQuery, after *models.BookCursor) ([]models.Book, error) {
+	var books []models.Book
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := buildFilter(ctx, query)
+
+	if after != nil {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{"createddate": bson.M{"$gt": after.CreatedDate}},
+			bson.M{"createddate": after.CreatedDate, "_id": bson.M{"$gt": after.ID}},
+		}}}}
+	}
+
+	opts := options.Find().SetLimit(query.Limit).
+		SetSort(bson.D{{Key: "createddate", Value: 1}, {Key: "_id", Value: 1}})
+
+	result, err := b.BookCollection.Find(ctx, filter, opts)
 
 	if err != nil {
 		return nil, err
 	}
+	defer result.Close(ctx)
 
 	for result.Next(ctx) {
+		var book models.Book
+		if err := result.Decode(&book); err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// exportTimeout => an export streams the whole catalog, so it can take longer than the other queries
+const exportTimeout = 10 * time.Minute
+
+// Export Method => to pass the books of the query to each one by one while they are read from the cursor
+// => unlike GetAll, the books are not collected in memory; paging of the query is not used, each can stop it with an error
+func (b BookRepository) Export(ctx context.Context, query models.BookQuery, each func(book models.Book) error) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(buildSort(query.Sort)).SetBatchSize(500)
+
+	cursor, err := b.BookCollection.Find(ctx, buildFilter(ctx, query), opts)
+
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var book models.Book
+		if err := cursor.Decode(&book); err != nil {
+			return err
+		}
+		if err := each(book); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// Search Method => full-text search over title and author by using text index, results are ordered by relevance score
+func (b BookRepository) Search(ctx context.Context, text string, limit int64) ([]models.BookSearchResult, error) {
+	var books []models.BookSearchResult
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"$text": bson.M{"$search": text}, "deletedat": nil})
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+
+	opts := options.Find().SetProjection(score).SetSort(score).SetLimit(limit)
+
+	result, err := b.BookCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var book models.BookSearchResult
 		if err := result.Decode(&book); err != nil {
 			return nil, err
 		}
-		// for appending book to books
 		books = append(books, book)
 	}
 
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
 	return books, nil
+}
+
+// Facets Method => counts of the filtered books per category (ancestors included), tag and author in one aggregation
+// => every facet has at most limit values, most used ones first
+func (b BookRepository) Facets(ctx context.Context, query models.BookQuery, limit int64) (models.BookFacets, error) {
+	var facets []models.BookFacets
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// $unwind => a book is counted once for every value of the array
+	countBy := func(field string) bson.A {
+		return bson.A{
+			bson.M{"$unwind": "$" + field},
+			bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			bson.M{"$limit": limit},
+		}
+	}
+
+	// author ids are counted, then their names are joined from authors collection
+	authors := append(countBy("authorids"),
+		bson.M{"$lookup": bson.M{"from": AuthorCollection, "localField": "_id", "foreignField": "_id", "as": "author"}},
+		bson.M{"$set": bson.M{"name": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$author.name", 0}}, ""}}}},
+		bson.M{"$unset": "author"},
+	)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: buildFilter(ctx, query)}},
+		{{Key: "$facet", Value: bson.M{
+			"categories": countBy("categorypath"),
+			"tags":       countBy("tags"),
+			"authors":    authors,
+		}}},
+	}
+
+	result, err := b.BookCollection.Aggregate(ctx, pipeline)
+
+	if err != nil {
+		return models.BookFacets{}, err
+	}
 
+	// $facet returns a single document
+	if err := result.All(ctx, &facets); err != nil || len(facets) == 0 {
+		return models.BookFacets{}, err
+	}
+
+	return facets[0], nil
+}
+
+// buildFilter => to convert query filters into mongodb filter => quantity[gte]=5 => {"quantity": {"$gte": 5}}
+// => deleted books are excluded, or only they are listed for trash; books of the other tenants are excluded too
+func buildFilter(ctx context.Context, query models.BookQuery) bson.M {
+	filter := bson.M{}
+
+	for _, f := range query.Filters {
+		var condition bson.M
+		switch f.Operator {
+		case "like":
+			// case-insensitive "contains", user input is escaped
+			condition = bson.M{"$regex": primitive.Regex{Pattern: regexp.QuoteMeta(f.Value.(string)), Options: "i"}}
+		default:
+			condition = bson.M{"$" + f.Operator: f.Value}
+		}
+
+		// more than one condition for the same field => quantity[gte]=1&quantity[lte]=10
+		if existing, ok := filter[f.Field].(bson.M); ok {
+			// tag=a&tag=b => books which have all of the values
+			if previous, ok := existing["$eq"]; ok && f.Operator == "eq" {
+				delete(existing, "$eq")
+				existing["$all"] = bson.A{previous, f.Value}
+				continue
+			}
+			if all, ok := existing["$all"].(bson.A); ok && f.Operator == "eq" {
+				existing["$all"] = append(all, f.Value)
+				continue
+			}
+			for key, value := range condition {
+				existing[key] = value
+			}
+			continue
+		}
+		filter[f.Field] = condition
+	}
+
+	// a filter on deletedat (e.g. deletedat[gte]= in trash) narrows the trash condition, it cannot replace it
+	trash := bson.M{"deletedat": nil}
+	if query.Deleted {
+		trash["deletedat"] = bson.M{"$ne": nil}
+	}
+	if _, ok := filter["deletedat"]; ok {
+		filter = bson.M{"$and": bson.A{filter, trash}}
+	} else {
+		filter["deletedat"] = trash["deletedat"]
+	}
+
+	return scoped(ctx, filter)
+}
+
+// buildSort => to convert sort fields into mongodb sort document, "_id" is added as the last key to have a stable order
+func buildSort(fields []models.SortField) bson.D {
+	sort := bson.D{}
+
+	for _, field := range fields {
+		direction := 1
+		if field.Descending {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: field.Field, Value: direction})
+	}
+
+	return append(sort, bson.E{Key: "_id", Value: 1})
 }
 
 // GetBookById Method => to find a single book with id
-func (b BookRepository) GetBookById(id string) (models.Book, error) {
+func (b BookRepository) GetBookById(ctx context.Context, id string) (models.Book, error) {
 	var book models.Book
 
 	// to open connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	// to find book by id
-	err := b.BookCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&book)
+	err := b.BookCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id, "deletedat": nil})).Decode(&book)
 
 	if err != nil {
 		return book, err
@@ -128,18 +452,316 @@ func (b BookRepository) GetBookById(id string) (models.Book, error) {
 	return book, nil
 }
 
-// Delete Method => to delete a book from books by id
-func (b BookRepository) Delete(id string) (bool, error) {
+// GetBooksByIds Method => to find the books with the given ids, unknown ids and books in trash are skipped
+func (b BookRepository) GetBooksByIds(ctx context.Context, ids []string) ([]models.Book, error) {
+	var books []models.Book
+
 	// to open connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// delete by id column
-	result, err := b.BookCollection.DeleteOne(ctx, bson.M{"_id": id})
+	result, err := b.BookCollection.Find(ctx, scoped(ctx, bson.M{"_id": bson.M{"$in": ids}, "deletedat": nil}))
 
-	if err != nil || result.DeletedCount <= 0 {
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.All(ctx, &books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// GetBooksByKeys Method => to find the books which have one of the ISBN-13s or one of the title & author pairs
+// => books in trash are skipped, it is used to match the rows of an import with the existing books
+func (b BookRepository) GetBooksByKeys(ctx context.Context, isbn13s []string, titleAuthors []models.TitleAuthor) ([]models.Book, error) {
+	var books []models.Book
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	keys := bson.A{}
+	if len(isbn13s) > 0 {
+		keys = append(keys, bson.M{"isbn13": bson.M{"$in": isbn13s}})
+	}
+	for _, key := range titleAuthors {
+		keys = append(keys, bson.M{"title": key.Title, "author": key.Author})
+	}
+	if len(keys) == 0 {
+		return books, nil
+	}
+
+	result, err := b.BookCollection.Find(ctx, scoped(ctx, bson.M{"$or": keys, "deletedat": nil}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.All(ctx, &books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// GetBookByISBN Method => to find a book by its normalized ISBN-13, books in trash are not found
+func (b BookRepository) GetBookByISBN(ctx context.Context, isbn13 string) (models.Book, error) {
+	var book models.Book
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := b.BookCollection.FindOne(ctx, scoped(ctx, bson.M{"isbn13": isbn13, "deletedat": nil})).Decode(&book)
+
+	return book, err
+}
+
+// Delete Method => to move a book into trash by id (soft delete), it can be restored until it is purged
+func (b BookRepository) Delete(ctx context.Context, id string) (bool, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	update := bson.M{"$set": bson.M{"deletedat": now, "updateddate": now}, "$inc": bson.M{"version": 1}}
+
+	// mark by id column
+	result, err := b.BookCollection.UpdateOne(ctx, scoped(ctx, bson.M{"_id": id, "deletedat": nil}), update)
+
+	if err != nil || result.ModifiedCount <= 0 {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Restore Method => to take a book back from trash by id
+func (b BookRepository) Restore(ctx context.Context, id string) (bool, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"deletedat": ""},
+		"$set": bson.M{"updateddate": primitive.NewDateTimeFromTime(time.Now())}, "$inc": bson.M{"version": 1}}
+
+	result, err := b.BookCollection.UpdateOne(ctx, scoped(ctx, bson.M{"_id": id, "deletedat": bson.M{"$ne": nil}}), update)
+
+	if err != nil || result.ModifiedCount <= 0 {
 		return false, err
 	}
 
 	return true, nil
 }
+
+// Purge Method => to delete the books in trash permanently which were deleted before the given time, it returns their ids
+func (b BookRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	var ids []string
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"deletedat": bson.M{"$ne": nil, "$lt": primitive.NewDateTimeFromTime(deletedBefore)}})
+
+	values, err := b.BookCollection.Distinct(ctx, "_id", filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	// filter is used again, so a book restored in the meantime is not deleted
+	filter["_id"] = bson.M{"$in": ids}
+	result, err := b.BookCollection.DeleteMany(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// some of them could be restored in the meantime
+	if result.DeletedCount < int64(len(ids)) {
+		return b.purged(ctx, ids)
+	}
+
+	return ids, nil
+}
+
+// purged => which of the given ids don't exist anymore
+func (b BookRepository) purged(ctx context.Context, ids []string) ([]string, error) {
+	var purged []string
+
+	values, err := b.BookCollection.Distinct(ctx, "_id", scoped(ctx, bson.M{"_id": bson.M{"$in": ids}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := map[interface{}]bool{}
+	for _, value := range values {
+		remaining[value] = true
+	}
+
+	for _, id := range ids {
+		if !remaining[id] {
+			purged = append(purged, id)
+		}
+	}
+
+	return purged, nil
+}
+
+// BulkInsert Method => to create books with one BulkWrite, a failed book doesn't stop the others
+// => errors of the failed books are returned by their index, ErrDuplicateKey for unique index violations
+func (b BookRepository) BulkInsert(ctx context.Context, books []models.Book) (map[int]error, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	writes := make([]mongo.WriteModel, 0, len(books))
+	for _, book := range books {
+		book.TenantID = tenantOf(ctx)
+		writes = append(writes, mongo.NewInsertOneModel().SetDocument(book))
+	}
+
+	// unordered => the books after a failed one are written too
+	_, err := b.BookCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+
+	return bulkWriteErrors(err)
+}
+
+// BulkUpsert Method => to create or change books by id with one BulkWrite, it returns the indexes of the created books
+// => version is not checked, the given values are written whatever the stored version is
+// => a book in trash or a book which has more held copies than the new quantity cannot be changed,
+// ErrDuplicateKey is returned for them because the upsert tries to create a book with the same id
+// => mongo.ErrNoDocuments is returned for the ids of other tenants, like they don't exist
+func (b BookRepository) BulkUpsert(ctx context.Context, books []models.Book) (map[int]bool, map[int]error, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	writes := make([]mongo.WriteModel, 0, len(books))
+	for _, book := range books {
+		filter := scoped(ctx, bson.M{"_id": book.ID, "deletedat": nil, "reserved": bson.M{"$not": bson.M{"$gt": book.Quantity}}})
+		set := bson.M{"title": book.Title, "author": book.Author, "authorids": book.AuthorIDs,
+			"isbn10": book.ISBN10, "isbn13": book.ISBN13, "category": book.Category, "categorypath": book.CategoryPath,
+			"tags": book.Tags, "quantity": book.Quantity, "updateddate": book.UpdatedDate}
+
+		// version starts from 1 for created books, because $inc creates the missing field
+		update := bson.M{"$set": set, "$setOnInsert": bson.M{"createddate": book.CreatedDate, "tenantid": tenantOf(ctx)},
+			"$inc": bson.M{"version": 1}}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+
+	result, err := b.BookCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+
+	failed, err := bulkWriteErrors(err)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var conflicts []string
+	for index, err := range failed {
+		if err == ErrDuplicateKey {
+			conflicts = append(conflicts, books[index].ID)
+		}
+	}
+
+	taken, err := foreign(ctx, b.BookCollection, conflicts)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for index, err := range failed {
+		if err == ErrDuplicateKey && taken[books[index].ID] {
+			failed[index] = mongo.ErrNoDocuments
+		}
+	}
+
+	created := map[int]bool{}
+	if result != nil {
+		for index := range result.UpsertedIDs {
+			created[int(index)] = true
+		}
+	}
+
+	return created, failed, nil
+}
+
+// BulkDelete Method => to move books into trash with one BulkWrite (soft delete), it returns the ids which are moved
+// => unknown ids and books which are already in trash are not returned
+func (b BookRepository) BulkDelete(ctx context.Context, ids []string) ([]string, error) {
+	var deleted []string
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	values, err := b.BookCollection.Distinct(ctx, "_id", scoped(ctx, bson.M{"_id": bson.M{"$in": ids}, "deletedat": nil}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	update := bson.M{"$set": bson.M{"deletedat": now, "updateddate": now}, "$inc": bson.M{"version": 1}}
+
+	writes := make([]mongo.WriteModel, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			deleted = append(deleted, id)
+			writes = append(writes, mongo.NewUpdateOneModel().SetFilter(scoped(ctx, bson.M{"_id": id, "deletedat": nil})).SetUpdate(update))
+		}
+	}
+
+	if len(writes) == 0 {
+		return deleted, nil
+	}
+
+	if _, err := b.BookCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// duplicateKeyCode => mongodb error code of a unique index violation
+const duplicateKeyCode = 11000
+
+// bulkWriteErrors => errors of the failed writes of a BulkWrite by their index
+// => error is returned only if the whole batch failed (e.g. connection or write concern error)
+func bulkWriteErrors(err error) (map[int]error, error) {
+	failed := map[int]error{}
+
+	if err == nil {
+		return failed, nil
+	}
+
+	var exception mongo.BulkWriteException
+	if !errors.As(err, &exception) || exception.WriteConcernError != nil {
+		return nil, err
+	}
+
+	for _, writeError := range exception.WriteErrors {
+		if writeError.Code == duplicateKeyCode {
+			failed[writeError.Index] = ErrDuplicateKey
+		} else {
+			failed[writeError.Index] = writeError
+		}
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+)
+
+// fullBook & bareBook => only the first document has the omitempty fields, the second one must not inherit them
+var fullBook = bson.D{
+	{Key: "_id", Value: "1"}, {Key: "title", Value: "Dune"}, {Key: "quantity", Value: 3},
+	{Key: "authorids", Value: bson.A{"author-1"}}, {Key: "isbn10", Value: "0441013597"}, {Key: "isbn13", Value: "9780441013593"},
+	{Key: "category", Value: "Fiction/SciFi"}, {Key: "categorypath", Value: bson.A{"Fiction", "Fiction/SciFi"}},
+	{Key: "tags", Value: bson.A{"classic"}},
+}
+
+var bareBook = bson.D{{Key: "_id", Value: "2"}, {Key: "title", Value: "Untitled"}, {Key: "quantity", Value: 1}}
+
+func TestListsDecodeEveryBookFromScratch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	ns := "test.books"
+
+	mt.Run("GetAll", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 2}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, fullBook, bareBook),
+		)
+
+		result, _, err := BookRepository{BookCollection: mt.Coll}.GetAll(context.Background(), models.BookQuery{Limit: 10})
+		requireBareSecond(mt.T, result, err)
+	})
+
+	mt.Run("GetAllAfter", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, fullBook, bareBook))
+
+		result, err := BookRepository{BookCollection: mt.Coll}.GetAllAfter(context.Background(), models.BookQuery{Limit: 10}, nil)
+		requireBareSecond(mt.T, result, err)
+	})
+
+	mt.Run("Search", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, fullBook, bareBook))
+
+		found, err := BookRepository{BookCollection: mt.Coll}.Search(context.Background(), "dune", 10)
+		result := make([]models.Book, 0, len(found))
+		for _, book := range found {
+			result = append(result, book.Book)
+		}
+		requireBareSecond(mt.T, result, err)
+	})
+}
+
+func requireBareSecond(t *testing.T, books []models.Book, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("books cannot be listed: %v", err)
+	}
+	if len(books) != 2 {
+		t.Fatalf("expected 2 books, got %d", len(books))
+	}
+	if first := books[0]; len(first.Tags) != 1 || first.ISBN13 == "" || len(first.AuthorIDs) != 1 {
+		t.Fatalf("first book is not decoded: %+v", first)
+	}
+
+	second := books[1]
+	if second.ISBN10 != "" || second.ISBN13 != "" || second.Category != "" || second.CategoryPath != nil ||
+		second.Tags != nil || second.AuthorIDs != nil {
+		t.Fatalf("second book has the fields of the first one: %+v", second)
+	}
+}
+
+func TestBulkUpsertHidesIdsOfOtherTenants(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("BulkUpsert", func(mt *mtest.T) {
+		// own book in trash & book of another tenant fail with the same duplicate key of _id
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(
+				mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"},
+				mtest.WriteError{Index: 2, Code: 11000, Message: "E11000 duplicate key error"},
+			),
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"foreign"}}),
+		)
+
+		ctx := requestinfo.With(context.Background(), requestinfo.Info{TenantID: "acme"})
+		books := []models.Book{{ID: "trashed", Title: "A"}, {ID: "new", Title: "B"}, {ID: "foreign", Title: "C"}}
+
+		_, failed, err := BookRepository{BookCollection: mt.Coll}.BulkUpsert(ctx, books)
+
+		if err != nil {
+			mt.Fatalf("books cannot be upserted: %v", err)
+		}
+		if len(failed) != 2 || failed[0] != ErrDuplicateKey || failed[2] != mongo.ErrNoDocuments {
+			mt.Fatalf("expected duplicate key for own book & no documents for foreign book, got %v", failed)
+		}
+	})
+}
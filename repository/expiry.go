@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ExpiryCursor => position of a record in the expiry order (date & id), the next batch of a reaper starts after it
+// => so records which cannot be reaped are not read again and again in the same run
+type ExpiryCursor struct {
+	Date primitive.DateTime
+	ID   string
+}
+
+// expiredFilter => records whose date field is not after now, coming after the cursor in (date, _id) order
+func expiredFilter(field string, now time.Time, after *ExpiryCursor) bson.M {
+	filter := bson.M{field: bson.M{"$lte": primitive.NewDateTimeFromTime(now)}}
+
+	if after != nil {
+		filter["$or"] = bson.A{
+			bson.M{field: bson.M{"$gt": after.Date}},
+			bson.M{field: after.Date, "_id": bson.M{"$gt": after.ID}},
+		}
+	}
+
+	return filter
+}
+
+// expiryOrder => oldest first, _id makes the order total for the cursor
+func expiryOrder(field string) bson.D {
+	return bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}}
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// HoldCollection => hold queues of the books, finished holds are kept as history
+const HoldCollection = "holds"
+
+// ErrHoldNotActive => hold cannot move to another status, it is already finished (or not in the expected status)
+var ErrHoldNotActive = errors.New("hold is not active")
+
+// queueOrder => FIFO order of a hold queue
+var queueOrder = bson.D{{Key: "createddate", Value: 1}, {Key: "_id", Value: 1}}
+
+type HoldRepository struct {
+	HoldCollection *mongo.Collection
+}
+
+var singleInstanceHoldRepo *HoldRepository
+
+func GetSingleInstancesHoldRepository(mongoCollection *mongo.Collection) *HoldRepository {
+	if singleInstanceHoldRepo == nil {
+		fmt.Println("Creating single hold repository instance now.")
+		singleInstanceHoldRepo = &HoldRepository{HoldCollection: mongoCollection}
+	} else {
+		fmt.Println("Single hold repository instance already created.")
+	}
+
+	return singleInstanceHoldRepo
+}
+
+type IHoldRepository interface {
+	Insert(ctx context.Context, hold models.Hold) error
+	GetById(ctx context.Context, id string) (models.Hold, error)
+	GetReady(ctx context.Context, bookID string, memberID string) (models.Hold, error)
+	GetQueue(ctx context.Context, bookID string, skip int64, limit int64) ([]models.Hold, int64, error)
+	Position(ctx context.Context, hold models.Hold) (int64, error)
+	HasWaiting(ctx context.Context, bookID string) (bool, error)
+	PromoteFirst(ctx context.Context, bookID string, readyDate time.Time, pickupUntil time.Time) (models.Hold, error)
+	Finish(ctx context.Context, id string, from []string, status string, notExpiredAt *time.Time) (models.Hold, error)
+	GetExpired(ctx context.Context, now time.Time, after *ExpiryCursor, limit int64) ([]models.Hold, error)
+	GetWaitingBooks(ctx context.Context) ([]string, error)
+	Reopen(ctx context.Context, id string, status string, to string) error
+}
+
+// Insert method => to create new hold, ErrDuplicateKey if the member has already an active hold for the book
+func (h HoldRepository) Insert(ctx context.Context, hold models.Hold) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	hold.TenantID = tenantOf(ctx)
+	_, err := h.HoldCollection.InsertOne(ctx, hold)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+
+	return err
+}
+
+// GetById method => to find a single hold with id
+func (h HoldRepository) GetById(ctx context.Context, id string) (models.Hold, error) {
+	var hold models.Hold
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := h.HoldCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&hold)
+
+	return hold, err
+}
+
+// GetReady method => ready hold of a member for a book, mongo.ErrNoDocuments if there is not
+func (h HoldRepository) GetReady(ctx context.Context, bookID string, memberID string) (models.Hold, error) {
+	var hold models.Hold
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"bookid": bookID, "memberid": memberID, "status": models.HoldReady})
+	err := h.HoldCollection.FindOne(ctx, filter).Decode(&hold)
+
+	return hold, err
+}
+
+// GetQueue method => active holds of a book, ready ones first and then the waiting ones in FIFO order
+func (h HoldRepository) GetQueue(ctx context.Context, bookID string, skip int64, limit int64) ([]models.Hold, int64, error) {
+	var holds []models.Hold
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"bookid": bookID, "active": true})
+
+	total, err := h.HoldCollection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// "ready" < "waiting"
+	sort := append(bson.D{{Key: "status", Value: 1}}, queueOrder...)
+	opts := options.Find().SetSkip(skip).SetLimit(limit).SetSort(sort)
+
+	result, err := h.HoldCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var hold models.Hold
+		if err := result.Decode(&hold); err != nil {
+			return nil, 0, err
+		}
+		holds = append(holds, hold)
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return holds, total, nil
+}
+
+// Position method => 1-based position of a waiting hold in its queue, zero for the holds which are not waiting
+func (h HoldRepository) Position(ctx context.Context, hold models.Hold) (int64, error) {
+	if hold.Status != models.HoldWaiting {
+		return 0, nil
+	}
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// waiting holds before this one in FIFO order
+	filter := scoped(ctx, bson.M{"bookid": hold.BookID, "status": models.HoldWaiting, "$or": bson.A{
+		bson.M{"createddate": bson.M{"$lt": hold.CreatedDate}},
+		bson.M{"createddate": hold.CreatedDate, "_id": bson.M{"$lt": hold.ID}},
+	}})
+
+	before, err := h.HoldCollection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return before + 1, nil
+}
+
+// HasWaiting method => whether anybody is waiting for the book
+func (h HoldRepository) HasWaiting(ctx context.Context, bookID string) (bool, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	count, err := h.HoldCollection.CountDocuments(ctx, scoped(ctx, bson.M{"bookid": bookID, "status": models.HoldWaiting}),
+		options.Count().SetLimit(1))
+
+	return count > 0, err
+}
+
+// PromoteFirst method => first waiting hold of the book becomes ready atomically, mongo.ErrNoDocuments if the queue is empty
+func (h HoldRepository) PromoteFirst(ctx context.Context, bookID string, readyDate time.Time, pickupUntil time.Time) (models.Hold, error) {
+	var hold models.Hold
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"bookid": bookID, "status": models.HoldWaiting})
+	update := bson.M{"$set": bson.M{
+		"status":      models.HoldReady,
+		"readydate":   primitive.NewDateTimeFromTime(readyDate),
+		"pickupuntil": primitive.NewDateTimeFromTime(pickupUntil),
+	}}
+
+	opts := options.FindOneAndUpdate().SetSort(queueOrder).SetReturnDocument(options.After)
+	err := h.HoldCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&hold)
+
+	return hold, err
+}
+
+// Finish method => hold in one of the from statuses moves to the given final status atomically, so its copy is handled only once
+// => if notExpiredAt is given, a ready hold must not be expired at that time (pickup)
+// => ReadyDate of the finished hold shows whether it was holding a copy
+func (h HoldRepository) Finish(ctx context.Context, id string, from []string, status string, notExpiredAt *time.Time) (models.Hold, error) {
+	var hold models.Hold
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "status": bson.M{"$in": from}})
+	if notExpiredAt != nil {
+		filter["pickupuntil"] = bson.M{"$gt": primitive.NewDateTimeFromTime(*notExpiredAt)}
+	}
+
+	update := bson.M{"$set": bson.M{"status": status, "active": false,
+		"finisheddate": primitive.NewDateTimeFromTime(time.Now())}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := h.HoldCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&hold)
+
+	if err == mongo.ErrNoDocuments {
+		if count, countErr := h.HoldCollection.CountDocuments(ctx, scoped(ctx, bson.M{"_id": id})); countErr == nil && count > 0 {
+			return hold, ErrHoldNotActive
+		}
+	}
+
+	return hold, err
+}
+
+// Reopen method => finished hold moves back to the status which it had, e.g. its copy cannot be handled after Finish
+// => only a hold which is still in the given status is reopened
+func (h HoldRepository) Reopen(ctx context.Context, id string, status string, to string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "status": status})
+	update := bson.M{"$set": bson.M{"status": to, "active": true}, "$unset": bson.M{"finisheddate": ""}}
+
+	result, err := h.HoldCollection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// GetExpired method => ready holds whose pickup window is over, oldest first, after the cursor if it is given
+func (h HoldRepository) GetExpired(ctx context.Context, now time.Time, after *ExpiryCursor, limit int64) ([]models.Hold, error) {
+	var holds []models.Hold
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, expiredFilter("pickupuntil", now, after))
+	filter["status"] = models.HoldReady
+	opts := options.Find().SetSort(expiryOrder("pickupuntil")).SetLimit(limit)
+
+	result, err := h.HoldCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var hold models.Hold
+		if err := result.Decode(&hold); err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+
+	return holds, result.Err()
+}
+
+// GetWaitingBooks method => ids of the books which have waiting holds
+func (h HoldRepository) GetWaitingBooks(ctx context.Context) ([]string, error) {
+	var ids []string
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	values, err := h.HoldCollection.Distinct(ctx, "bookid", scoped(ctx, bson.M{"status": models.HoldWaiting}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
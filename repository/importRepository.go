@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ImportCollection => catalog import jobs with their error reports
+const ImportCollection = "imports"
+
+type ImportRepository struct {
+	ImportCollection *mongo.Collection
+}
+
+var singleInstanceImportRepo *ImportRepository
+
+func GetSingleInstancesImportRepository(mongoCollection *mongo.Collection) *ImportRepository {
+	if singleInstanceImportRepo == nil {
+		fmt.Println("Creating single import repository instance now.")
+		singleInstanceImportRepo = &ImportRepository{ImportCollection: mongoCollection}
+	} else {
+		fmt.Println("Single import repository instance already created.")
+	}
+
+	return singleInstanceImportRepo
+}
+
+type IImportRepository interface {
+	Insert(ctx context.Context, job models.ImportJob) error
+	GetById(ctx context.Context, id string) (models.ImportJob, error)
+	Update(ctx context.Context, job models.ImportJob) error
+	FailRunning(ctx context.Context, message string) (int64, error)
+}
+
+// Insert method => to create new import job in the tenant of the request
+func (i ImportRepository) Insert(ctx context.Context, job models.ImportJob) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	job.TenantID = tenantOf(ctx)
+
+	_, err := i.ImportCollection.InsertOne(ctx, job)
+
+	return err
+}
+
+// GetById method => to find a single import job with id
+func (i ImportRepository) GetById(ctx context.Context, id string) (models.ImportJob, error) {
+	var job models.ImportJob
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := i.ImportCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&job)
+
+	return job, err
+}
+
+// Update method => to write the progress of an import job, only its runner changes it (with the context of the request)
+func (i ImportRepository) Update(ctx context.Context, job models.ImportJob) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	job.TenantID = tenantOf(ctx)
+	_, err := i.ImportCollection.ReplaceOne(ctx, scoped(ctx, bson.M{"_id": job.ID}), job)
+
+	return err
+}
+
+// FailRunning method => jobs which are still running when the app starts were interrupted, they are marked as failed
+func (i ImportRepository) FailRunning(ctx context.Context, message string) (int64, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"status": models.ImportFailed, "error": message,
+		"finisheddate": primitive.NewDateTimeFromTime(time.Now())}}
+
+	result, err := i.ImportCollection.UpdateMany(ctx, bson.M{"status": models.ImportRunning}, update)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// StockMovementCollection => ledger of the quantity changes
+const StockMovementCollection = "stock_movements"
+
+// ErrInsufficientStock => book doesn't have enough copies which are not reserved
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+type InventoryRepository struct {
+	BookCollection     *mongo.Collection
+	MovementCollection *mongo.Collection
+}
+
+var singleInstanceInventoryRepo *InventoryRepository
+
+func GetSingleInstancesInventoryRepository(bookCollection *mongo.Collection, movementCollection *mongo.Collection) *InventoryRepository {
+	if singleInstanceInventoryRepo == nil {
+		fmt.Println("Creating single inventory repository instance now.")
+		singleInstanceInventoryRepo = &InventoryRepository{BookCollection: bookCollection, MovementCollection: movementCollection}
+	} else {
+		fmt.Println("Single inventory repository instance already created.")
+	}
+
+	return singleInstanceInventoryRepo
+}
+
+type IInventoryRepository interface {
+	ChangeQuantity(ctx context.Context, bookID string, delta int) (models.Book, error)
+	Reserve(ctx context.Context, bookID string, quantity int) (models.Book, error)
+	Unreserve(ctx context.Context, bookID string, quantity int) (models.Book, error)
+	ConsumeReserved(ctx context.Context, bookID string, quantity int) (models.Book, error)
+	Restock(ctx context.Context, bookID string, quantity int) (models.Book, error)
+	InsertMovement(ctx context.Context, movement models.StockMovement) error
+	GetMovements(ctx context.Context, bookID string, skip int64, limit int64) ([]models.StockMovement, int64, error)
+}
+
+// ChangeQuantity method => to add delta (negative for decrement) to the quantity of a book atomically, it returns the updated book
+// => reserved copies cannot be decremented, the check is in the same filter with $inc, so concurrent sales cannot oversell
+func (i InventoryRepository) ChangeQuantity(ctx context.Context, bookID string, delta int) (models.Book, error) {
+	var condition bson.M
+	if delta < 0 {
+		condition = available(-delta)
+	}
+
+	return i.incBook(ctx, bookID, false, condition, bson.M{"quantity": delta})
+}
+
+// Reserve method => to hold copies of a book, they are still in quantity but not available anymore
+func (i InventoryRepository) Reserve(ctx context.Context, bookID string, quantity int) (models.Book, error) {
+	return i.incBook(ctx, bookID, false, available(quantity), bson.M{"reserved": quantity})
+}
+
+// Unreserve method => to give held copies back, e.g. a reservation is released or expired
+// => trashed books are included, otherwise their copies would stay held after a restore
+func (i InventoryRepository) Unreserve(ctx context.Context, bookID string, quantity int) (models.Book, error) {
+	return i.incBook(ctx, bookID, true, bson.M{"reserved": bson.M{"$gte": quantity}}, bson.M{"reserved": -quantity})
+}
+
+// ConsumeReserved method => held copies leave the stock, e.g. a reservation is confirmed
+func (i InventoryRepository) ConsumeReserved(ctx context.Context, bookID string, quantity int) (models.Book, error) {
+	return i.incBook(ctx, bookID, false, bson.M{"reserved": bson.M{"$gte": quantity}}, bson.M{"quantity": -quantity, "reserved": -quantity})
+}
+
+// Restock method => copies which were out come back into the stock, e.g. a loan is returned
+// => trashed books are included, the copy is in the library anyway
+func (i InventoryRepository) Restock(ctx context.Context, bookID string, quantity int) (models.Book, error) {
+	return i.incBook(ctx, bookID, true, nil, bson.M{"quantity": quantity})
+}
+
+// available => condition of a book which has at least the given count of copies which are not reserved
+func available(quantity int) bson.M {
+	return bson.M{"$expr": bson.M{"$gte": bson.A{
+		bson.M{"$subtract": bson.A{"$quantity", bson.M{"$ifNull": bson.A{"$reserved", 0}}}}, quantity}}}
+}
+
+// incBook => $inc on a book with a condition, ErrInsufficientStock is returned if the book exists but the condition doesn't match
+// => trashed books are matched only if withTrashed is true
+func (i InventoryRepository) incBook(ctx context.Context, bookID string, withTrashed bool, condition bson.M, inc bson.M) (models.Book, error) {
+	var updated models.Book
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	base := bson.M{"_id": bookID}
+	if !withTrashed {
+		base["deletedat"] = nil
+	}
+
+	filter := scoped(ctx, bson.M{})
+	for key, value := range base {
+		filter[key] = value
+	}
+	for key, value := range condition {
+		filter[key] = value
+	}
+
+	inc["version"] = 1
+	update := bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updateddate": primitive.NewDateTimeFromTime(time.Now())},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := i.BookCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+
+	if err == mongo.ErrNoDocuments && len(condition) > 0 {
+		// book exists, so there are not enough copies
+		if count, countErr := i.BookCollection.CountDocuments(ctx, scoped(ctx, base)); countErr == nil && count > 0 {
+			return updated, ErrInsufficientStock
+		}
+	}
+
+	if err != nil {
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+// InsertMovement method => to append a movement to the ledger of the request tenant
+func (i InventoryRepository) InsertMovement(ctx context.Context, movement models.StockMovement) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	movement.TenantID = requestinfo.From(ctx).TenantID
+
+	_, err := i.MovementCollection.InsertOne(ctx, movement)
+
+	return err
+}
+
+// GetMovements method => ledger of a book page by page, newest first
+func (i InventoryRepository) GetMovements(ctx context.Context, bookID string, skip int64, limit int64) ([]models.StockMovement, int64, error) {
+	var movements []models.StockMovement
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"bookid": bookID})
+
+	total, err := i.MovementCollection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(skip).SetLimit(limit).SetSort(bson.D{{Key: "date", Value: -1}, {Key: "_id", Value: -1}})
+
+	result, err := i.MovementCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var movement models.StockMovement
+		if err := result.Decode(&movement); err != nil {
+			return nil, 0, err
+		}
+		movements = append(movements, movement)
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return movements, total, nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// LoanCollection => loans of members, returned ones are kept as history
+const LoanCollection = "loans"
+
+// ErrLoanReturned => loan is already returned
+var ErrLoanReturned = errors.New("loan is already returned")
+
+type LoanRepository struct {
+	LoanCollection *mongo.Collection
+}
+
+var singleInstanceLoanRepo *LoanRepository
+
+func GetSingleInstancesLoanRepository(mongoCollection *mongo.Collection) *LoanRepository {
+	if singleInstanceLoanRepo == nil {
+		fmt.Println("Creating single loan repository instance now.")
+		singleInstanceLoanRepo = &LoanRepository{LoanCollection: mongoCollection}
+	} else {
+		fmt.Println("Single loan repository instance already created.")
+	}
+
+	return singleInstanceLoanRepo
+}
+
+type ILoanRepository interface {
+	Insert(ctx context.Context, loan models.Loan) error
+	GetById(ctx context.Context, id string) (models.Loan, error)
+	MarkReturned(ctx context.Context, id string, returnedDate time.Time) (models.Loan, error)
+	Reopen(ctx context.Context, id string) error
+	GetByMember(ctx context.Context, memberID string, skip int64, limit int64) ([]models.Loan, int64, error)
+	GetOverdue(ctx context.Context, now time.Time, skip int64, limit int64) ([]models.Loan, int64, error)
+}
+
+// Insert method => to create new loan in the request tenant
+func (l LoanRepository) Insert(ctx context.Context, loan models.Loan) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	loan.TenantID = tenantOf(ctx)
+	_, err := l.LoanCollection.InsertOne(ctx, loan)
+
+	return err
+}
+
+// GetById method => to find a single loan with id
+func (l LoanRepository) GetById(ctx context.Context, id string) (models.Loan, error) {
+	var loan models.Loan
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := l.LoanCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&loan)
+
+	return loan, err
+}
+
+// MarkReturned method => active loan is returned atomically, so the copy is given back only once
+func (l LoanRepository) MarkReturned(ctx context.Context, id string, returnedDate time.Time) (models.Loan, error) {
+	var loan models.Loan
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "returneddate": nil})
+	update := bson.M{"$set": bson.M{"returneddate": primitive.NewDateTimeFromTime(returnedDate)}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := l.LoanCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&loan)
+
+	if err == mongo.ErrNoDocuments {
+		if count, countErr := l.LoanCollection.CountDocuments(ctx, scoped(ctx, bson.M{"_id": id})); countErr == nil && count > 0 {
+			return loan, ErrLoanReturned
+		}
+	}
+
+	return loan, err
+}
+
+// Reopen method => returned loan is active again, e.g. its copy cannot be added back after MarkReturned
+func (l LoanRepository) Reopen(ctx context.Context, id string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "returneddate": bson.M{"$ne": nil}})
+	update := bson.M{"$unset": bson.M{"returneddate": ""}}
+
+	result, err := l.LoanCollection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// GetByMember method => loan history of a member, newest first
+func (l LoanRepository) GetByMember(ctx context.Context, memberID string, skip int64, limit int64) ([]models.Loan, int64, error) {
+	return l.find(ctx, scoped(ctx, bson.M{"memberid": memberID}), bson.D{{Key: "loandate", Value: -1}}, skip, limit)
+}
+
+// GetOverdue method => active loans whose due date is passed, most overdue first
+func (l LoanRepository) GetOverdue(ctx context.Context, now time.Time, skip int64, limit int64) ([]models.Loan, int64, error) {
+	filter := scoped(ctx, bson.M{"returneddate": nil, "duedate": bson.M{"$lt": primitive.NewDateTimeFromTime(now)}})
+
+	return l.find(ctx, filter, bson.D{{Key: "duedate", Value: 1}}, skip, limit)
+}
+
+func (l LoanRepository) find(ctx context.Context, filter bson.M, sort bson.D, skip int64, limit int64) ([]models.Loan, int64, error) {
+	var loans []models.Loan
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	total, err := l.LoanCollection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(skip).SetLimit(limit).SetSort(append(sort, bson.E{Key: "_id", Value: 1}))
+
+	result, err := l.LoanCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		// returneddate of the previous loan must not be kept
+		var loan models.Loan
+		if err := result.Decode(&loan); err != nil {
+			return nil, 0, err
+		}
+		loans = append(loans, loan)
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return loans, total, nil
+}
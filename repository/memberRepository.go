@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// MemberCollection => library members
+const MemberCollection = "members"
+
+// ErrLoanLimitReached => member has as many active loans as the limit
+var ErrLoanLimitReached = errors.New("loan limit reached")
+
+// ErrDuplicateKey => a unique index doesn't allow the document
+var ErrDuplicateKey = errors.New("duplicate key")
+
+type MemberRepository struct {
+	MemberCollection *mongo.Collection
+}
+
+var singleInstanceMemberRepo *MemberRepository
+
+func GetSingleInstancesMemberRepository(mongoCollection *mongo.Collection) *MemberRepository {
+	if singleInstanceMemberRepo == nil {
+		fmt.Println("Creating single member repository instance now.")
+		singleInstanceMemberRepo = &MemberRepository{MemberCollection: mongoCollection}
+	} else {
+		fmt.Println("Single member repository instance already created.")
+	}
+
+	return singleInstanceMemberRepo
+}
+
+type IMemberRepository interface {
+	Insert(ctx context.Context, member models.Member) error
+	GetAll(ctx context.Context, skip int64, limit int64) ([]models.Member, int64, error)
+	GetById(ctx context.Context, id string) (models.Member, error)
+	Update(ctx context.Context, member models.Member) (models.Member, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	IncActiveLoans(ctx context.Context, id string, maxLoans int) error
+	DecActiveLoans(ctx context.Context, id string) error
+}
+
+// Insert method => to create new member in the request tenant
+func (m MemberRepository) Insert(ctx context.Context, member models.Member) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	member.TenantID = tenantOf(ctx)
+	_, err := m.MemberCollection.InsertOne(ctx, member)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+
+	return err
+}
+
+// GetAll method => to list members page by page ordered by name
+func (m MemberRepository) GetAll(ctx context.Context, skip int64, limit int64) ([]models.Member, int64, error) {
+	var members []models.Member
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	total, err := m.MemberCollection.CountDocuments(ctx, scoped(ctx, bson.M{}))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(skip).SetLimit(limit).SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	result, err := m.MemberCollection.Find(ctx, scoped(ctx, bson.M{}), opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var member models.Member
+		if err := result.Decode(&member); err != nil {
+			return nil, 0, err
+		}
+		members = append(members, member)
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return members, total, nil
+}
+
+// GetById method => to find a single member with id
+func (m MemberRepository) GetById(ctx context.Context, id string) (models.Member, error) {
+	var member models.Member
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := m.MemberCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&member)
+
+	return member, err
+}
+
+// Update method => to change name, email and loan limit of a member, it returns the updated member
+func (m MemberRepository) Update(ctx context.Context, member models.Member) (models.Member, error) {
+	var updated models.Member
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"name": member.Name, "email": member.Email, "maxloans": member.MaxLoans,
+		"updateddate": member.UpdatedDate}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := m.MemberCollection.FindOneAndUpdate(ctx, scoped(ctx, bson.M{"_id": member.ID}), update, opts).Decode(&updated)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return updated, ErrDuplicateKey
+	}
+
+	return updated, err
+}
+
+// Delete method => to delete a member by id, members with active loans cannot be deleted
+func (m MemberRepository) Delete(ctx context.Context, id string) (bool, error) {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := m.MemberCollection.DeleteOne(ctx, scoped(ctx, bson.M{"_id": id, "activeloans": bson.M{"$lte": 0}}))
+
+	if err != nil || result.DeletedCount <= 0 {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// IncActiveLoans method => to count a new loan of a member atomically, ErrLoanLimitReached if the member is at the limit
+func (m MemberRepository) IncActiveLoans(ctx context.Context, id string, maxLoans int) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "activeloans": bson.M{"$lt": maxLoans}})
+	update := bson.M{"$inc": bson.M{"activeloans": 1}}
+
+	result, err := m.MemberCollection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		if count, countErr := m.MemberCollection.CountDocuments(ctx, scoped(ctx, bson.M{"_id": id})); countErr == nil && count > 0 {
+			return ErrLoanLimitReached
+		}
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// DecActiveLoans method => a loan of the member is returned (or its checkout failed)
+func (m MemberRepository) DecActiveLoans(ctx context.Context, id string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "activeloans": bson.M{"$gt": 0}})
+	update := bson.M{"$inc": bson.M{"activeloans": -1},
+		"$set": bson.M{"updateddate": primitive.NewDateTimeFromTime(time.Now())}}
+
+	_, err := m.MemberCollection.UpdateOne(ctx, filter, update)
+
+	return err
+}
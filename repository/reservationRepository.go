@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// ReservationCollection => stock reservations of books
+const ReservationCollection = "reservations"
+
+// ErrReservationNotPending => reservation is already confirmed, released or expired
+var ErrReservationNotPending = errors.New("reservation is not pending")
+
+type ReservationRepository struct {
+	ReservationCollection *mongo.Collection
+}
+
+var singleInstanceReservationRepo *ReservationRepository
+
+func GetSingleInstancesReservationRepository(mongoCollection *mongo.Collection) *ReservationRepository {
+	if singleInstanceReservationRepo == nil {
+		fmt.Println("Creating single reservation repository instance now.")
+		singleInstanceReservationRepo = &ReservationRepository{ReservationCollection: mongoCollection}
+	} else {
+		fmt.Println("Single reservation repository instance already created.")
+	}
+
+	return singleInstanceReservationRepo
+}
+
+type IReservationRepository interface {
+	Insert(ctx context.Context, reservation models.Reservation) error
+	GetById(ctx context.Context, id string) (models.Reservation, error)
+	Finish(ctx context.Context, id string, status string, notExpiredAt *time.Time) (models.Reservation, error)
+	GetExpired(ctx context.Context, now time.Time, after *ExpiryCursor, limit int64) ([]models.Reservation, error)
+	Reopen(ctx context.Context, id string, status string) error
+}
+
+// Insert method => to create new reservation
+func (r ReservationRepository) Insert(ctx context.Context, reservation models.Reservation) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	reservation.TenantID = tenantOf(ctx)
+	_, err := r.ReservationCollection.InsertOne(ctx, reservation)
+
+	return err
+}
+
+// GetById method => to find a single reservation with id
+func (r ReservationRepository) GetById(ctx context.Context, id string) (models.Reservation, error) {
+	var reservation models.Reservation
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := r.ReservationCollection.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&reservation)
+
+	return reservation, err
+}
+
+// Finish method => pending reservation moves to the given status atomically, so its copies are handled only once
+// => if notExpiredAt is given, the reservation must not be expired at that time (confirm)
+func (r ReservationRepository) Finish(ctx context.Context, id string, status string, notExpiredAt *time.Time) (models.Reservation, error) {
+	var reservation models.Reservation
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "status": models.ReservationPending})
+	if notExpiredAt != nil {
+		filter["expiresat"] = bson.M{"$gt": primitive.NewDateTimeFromTime(*notExpiredAt)}
+	}
+
+	update := bson.M{"$set": bson.M{"status": status, "finisheddate": primitive.NewDateTimeFromTime(time.Now())}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.ReservationCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&reservation)
+
+	if err == mongo.ErrNoDocuments {
+		// reservation exists but it is not pending (or expired) anymore
+		if count, countErr := r.ReservationCollection.CountDocuments(ctx, scoped(ctx, bson.M{"_id": id})); countErr == nil && count > 0 {
+			return reservation, ErrReservationNotPending
+		}
+	}
+
+	return reservation, err
+}
+
+// Reopen method => finished reservation moves back to pending, e.g. its copies cannot be handled after Finish
+// => only a reservation which is still in the given status is reopened
+func (r ReservationRepository) Reopen(ctx context.Context, id string, status string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, bson.M{"_id": id, "status": status})
+	update := bson.M{"$set": bson.M{"status": models.ReservationPending}, "$unset": bson.M{"finisheddate": ""}}
+
+	result, err := r.ReservationCollection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// GetExpired method => pending reservations whose time is over, oldest first, after the cursor if it is given
+func (r ReservationRepository) GetExpired(ctx context.Context, now time.Time, after *ExpiryCursor, limit int64) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := scoped(ctx, expiredFilter("expiresat", now, after))
+	filter["status"] = models.ReservationPending
+	opts := options.Find().SetSort(expiryOrder("expiresat")).SetLimit(limit)
+
+	result, err := r.ReservationCollection.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close(ctx)
+
+	for result.Next(ctx) {
+		var reservation models.Reservation
+		if err := result.Decode(&reservation); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, result.Err()
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"RestfulWithEcho/requestinfo"
+	"RestfulWithEcho/tenant"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// scoped => to limit a filter to the documents of the request tenant, so other tenants cannot be read or changed
+// => contexts without a tenant (e.g. reapers) are not scoped, they reach the documents by ids which they already have
+func scoped(ctx context.Context, filter bson.M) bson.M {
+	if id := requestinfo.From(ctx).TenantID; id != "" {
+		filter["tenantid"] = id
+	}
+	return filter
+}
+
+// tenantOf => tenant which new documents are stamped with
+func tenantOf(ctx context.Context) string {
+	return tenant.OrDefault(requestinfo.From(ctx).TenantID)
+}
+
+// foreign => which of the ids are taken by the documents of other tenants, ids are unique across tenants
+// => writes of them are reported like missing documents, so other tenants are not revealed
+func foreign(ctx context.Context, collection *mongo.Collection, ids []string) (map[string]bool, error) {
+	taken := map[string]bool{}
+
+	tenantID := requestinfo.From(ctx).TenantID
+	if tenantID == "" || len(ids) == 0 {
+		return taken, nil
+	}
+
+	values, err := collection.Distinct(ctx, "_id", bson.M{"_id": bson.M{"$in": ids}, "tenantid": bson.M{"$ne": tenantID}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			taken[id] = true
+		}
+	}
+
+	return taken, nil
+}
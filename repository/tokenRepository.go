@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// RefreshTokenCollection => refresh tokens of the users, expired ones are removed by a TTL index
+const RefreshTokenCollection = "refreshtokens"
+
+type TokenRepository struct {
+	TokenCollection *mongo.Collection
+}
+
+var singleInstanceTokenRepo *TokenRepository
+
+func GetSingleInstancesTokenRepository(mongoCollection *mongo.Collection) *TokenRepository {
+	if singleInstanceTokenRepo == nil {
+		fmt.Println("Creating single token repository instance now.")
+		singleInstanceTokenRepo = &TokenRepository{TokenCollection: mongoCollection}
+	} else {
+		fmt.Println("Single token repository instance already created.")
+	}
+
+	return singleInstanceTokenRepo
+}
+
+type ITokenRepository interface {
+	Insert(ctx context.Context, token models.RefreshToken) error
+	GetById(ctx context.Context, id string) (models.RefreshToken, error)
+	Revoke(ctx context.Context, id string) error
+	RevokeFamily(ctx context.Context, family string) error
+}
+
+// Insert method => to save a new refresh token
+func (t TokenRepository) Insert(ctx context.Context, token models.RefreshToken) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := t.TokenCollection.InsertOne(ctx, token)
+
+	return err
+}
+
+// GetById method => to find a refresh token by its hash
+func (t TokenRepository) GetById(ctx context.Context, id string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := t.TokenCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&token)
+
+	return token, err
+}
+
+// Revoke method => to revoke a token which is not revoked yet, mongo.ErrNoDocuments if it is already revoked
+// => only one of the concurrent refreshes with the same token can revoke it
+func (t TokenRepository) Revoke(ctx context.Context, id string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "revokedat": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedat": primitive.NewDateTimeFromTime(time.Now())}}
+
+	result, err := t.TokenCollection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RevokeFamily method => to revoke every token of a login, e.g. logout or reuse of a revoked token
+func (t TokenRepository) RevokeFamily(ctx context.Context, family string) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"family": family, "revokedat": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedat": primitive.NewDateTimeFromTime(time.Now())}}
+
+	_, err := t.TokenCollection.UpdateMany(ctx, filter, update)
+
+	return err
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"RestfulWithEcho/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// UserCollection => users of the api
+const UserCollection = "users"
+
+type UserRepository struct {
+	UserCollection *mongo.Collection
+}
+
+var singleInstanceUserRepo *UserRepository
+
+func GetSingleInstancesUserRepository(mongoCollection *mongo.Collection) *UserRepository {
+	if singleInstanceUserRepo == nil {
+		fmt.Println("Creating single user repository instance now.")
+		singleInstanceUserRepo = &UserRepository{UserCollection: mongoCollection}
+	} else {
+		fmt.Println("Single user repository instance already created.")
+	}
+
+	return singleInstanceUserRepo
+}
+
+type IUserRepository interface {
+	Insert(ctx context.Context, user models.User) error
+	GetById(ctx context.Context, id string) (models.User, error)
+	GetByUsername(ctx context.Context, username string) (models.User, error)
+}
+
+// Insert method => to create new user, ErrDuplicateKey if the username is taken
+func (u UserRepository) Insert(ctx context.Context, user models.User) error {
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := u.UserCollection.InsertOne(ctx, user)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+
+	return err
+}
+
+// GetById method => to find a single user with id
+func (u UserRepository) GetById(ctx context.Context, id string) (models.User, error) {
+	var user models.User
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := u.UserCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+
+	return user, err
+}
+
+// GetByUsername method => to find a single user with username
+func (u UserRepository) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+
+	// to open connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := u.UserCollection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+
+	return user, err
+}
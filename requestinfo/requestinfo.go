@@ -0,0 +1,35 @@
+package requestinfo
+
+import "context"
+
+// Info => who makes the request and its id, handlers put it into the request context and service layer reads it
+// => UserID is empty if the request has no access token, roles are the roles of its user
+// => APIKeyID is set instead of UserID if the request has an api key, scopes are the permissions of the key
+// => TenantID is the bookstore of the request, it is empty only outside of requests (e.g. reapers)
+type Info struct {
+	Actor     string
+	UserID    string
+	Roles     []string
+	APIKeyID  string
+	Scopes    []string
+	TenantID  string
+	RequestID string
+}
+
+// Anonymous => actor of the requests without a user
+const Anonymous = "anonymous"
+
+type contextKey struct{}
+
+// With => to put request info into context
+func With(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// From => request info of the context, actor is Anonymous if there is no info (e.g. CLI, background jobs)
+func From(ctx context.Context) Info {
+	if info, ok := ctx.Value(contextKey{}).(Info); ok {
+		return info
+	}
+	return Info{Actor: Anonymous}
+}
@@ -3,9 +3,27 @@ package response
 type JSONSuccessResultData struct {
 	TotalItemCount int         `json:"totalitemcount"`
 	Data           interface{} `json:"data"`
+	Pagination     *Pagination `json:"pagination,omitempty"`
+	Cursor         *Cursor     `json:"cursor,omitempty"`
 }
 
 type JSONSuccessResultId struct {
 	ID      string `json:"id"`
 	Success bool   `json:"success"`
 }
+
+// Pagination => metadata of a paged list response
+type Pagination struct {
+	Page       int  `json:"page"`
+	PageSize   int  `json:"pagesize"`
+	Offset     int  `json:"offset"`
+	TotalPages int  `json:"totalpages"`
+	HasNext    bool `json:"hasnext"`
+}
+
+// Cursor => metadata of a keyset (cursor) paged list response, next_cursor is empty on the last page
+type Cursor struct {
+	PageSize   int    `json:"pagesize"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasNext    bool   `json:"hasnext"`
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/repository"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAPIKey => api key is unknown, malformed or revoked
+var ErrInvalidAPIKey = errors.New(errors.Unauthorized, "invalid_api_key", "api key is invalid")
+
+// ErrAPIKeyNotFound => api key doesn't exist
+var ErrAPIKeyNotFound = errors.New(errors.NotFound, "apikey_not_found", "api key is not found")
+
+// ErrAPIKeyRevoked => revoked api keys cannot be rotated or revoked again
+var ErrAPIKeyRevoked = errors.New(errors.Conflict, "apikey_revoked", "api key is revoked")
+
+const (
+	// apiKeyPrefix => keys look like rwe_<prefix>_<secret>, so they can be found by secret scanners
+	apiKeyPrefix = "rwe_"
+	// apiKeyPrefixBytes & apiKeySecretBytes => random bytes of the prefix (hex) & secret (base64url) of a key
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+	// apiKeyAttempts => a new prefix is generated when the random one is taken
+	apiKeyAttempts = 3
+)
+
+type APIKeyService struct {
+	Repository repository.IAPIKeyRepository
+}
+
+var singleInstanceAPIKeyService *APIKeyService
+
+func GetSingleInstancesAPIKeyService(repository repository.IAPIKeyRepository) *APIKeyService {
+	if singleInstanceAPIKeyService == nil {
+		fmt.Println("Creating single api key service instance now.")
+		singleInstanceAPIKeyService = &APIKeyService{Repository: repository}
+	} else {
+		fmt.Println("Single api key service instance already created.")
+	}
+
+	return singleInstanceAPIKeyService
+}
+
+type IAPIKeyService interface {
+	Create(ctx context.Context, name string, scopes []string) (models.APIKey, string, error)
+	GetAll(ctx context.Context, skip int64, limit int64) ([]models.APIKey, int64, error)
+	GetById(ctx context.Context, id string) (models.APIKey, error)
+	Rotate(ctx context.Context, id string) (models.APIKey, string, error)
+	Revoke(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, key string) (models.APIKey, error)
+}
+
+// Create => the key is returned only here, it cannot be read again
+func (a APIKeyService) Create(ctx context.Context, name string, scopes []string) (models.APIKey, string, error) {
+	if err := rbac.ValidScopes(scopes); err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	apiKey := models.APIKey{
+		ID:          uuid.New().String(),
+		CreatedDate: primitive.NewDateTimeFromTime(time.Now()),
+		Name:        name,
+		Scopes:      scopes,
+		CreatedBy:   requestinfo.From(ctx).UserID,
+	}
+	apiKey.UpdatedDate = apiKey.CreatedDate
+
+	for attempt := 1; ; attempt++ {
+		key, prefix, err := newAPIKey()
+		if err != nil {
+			return apiKey, "", err
+		}
+		apiKey.Prefix, apiKey.Hash = prefix, hashToken(key)
+
+		err = a.Repository.Insert(ctx, apiKey)
+		if err == repository.ErrDuplicateKey && attempt < apiKeyAttempts {
+			continue
+		}
+		if err != nil {
+			return apiKey, "", err
+		}
+
+		return apiKey, key, nil
+	}
+}
+
+func (a APIKeyService) GetAll(ctx context.Context, skip int64, limit int64) ([]models.APIKey, int64, error) {
+	return a.Repository.GetAll(ctx, skip, limit)
+}
+
+func (a APIKeyService) GetById(ctx context.Context, id string) (models.APIKey, error) {
+	apiKey, err := a.Repository.GetById(ctx, id)
+
+	if err == mongo.ErrNoDocuments {
+		return apiKey, ErrAPIKeyNotFound.Withf("{%v} api key is not found", id)
+	}
+
+	return apiKey, err
+}
+
+// Rotate => the old key stops working at once, name, scopes & usage of the api key are kept
+func (a APIKeyService) Rotate(ctx context.Context, id string) (models.APIKey, string, error) {
+	apiKey, err := a.GetById(ctx, id)
+
+	if err != nil {
+		return apiKey, "", err
+	}
+
+	for attempt := 1; ; attempt++ {
+		key, prefix, err := newAPIKey()
+		if err != nil {
+			return apiKey, "", err
+		}
+
+		err = a.Repository.Rotate(ctx, id, prefix, hashToken(key))
+		if err == repository.ErrDuplicateKey && attempt < apiKeyAttempts {
+			continue
+		}
+		if err == mongo.ErrNoDocuments {
+			return apiKey, "", ErrAPIKeyRevoked.Withf("{%v} api key is revoked", id)
+		}
+		if err != nil {
+			return apiKey, "", err
+		}
+
+		apiKey.Prefix = prefix
+		apiKey.UpdatedDate = primitive.NewDateTimeFromTime(time.Now())
+		return apiKey, key, nil
+	}
+}
+
+// Revoke => revoked api keys are kept with their usage, they cannot be used again
+func (a APIKeyService) Revoke(ctx context.Context, id string) error {
+	if _, err := a.GetById(ctx, id); err != nil {
+		return err
+	}
+
+	if err := a.Repository.Revoke(ctx, id); err == mongo.ErrNoDocuments {
+		return ErrAPIKeyRevoked.Withf("{%v} api key is already revoked", id)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate => api key of a request, its request count & last used time are updated
+// => the request is not failed when the usage cannot be written, it is logged
+func (a APIKeyService) Authenticate(ctx context.Context, key string) (models.APIKey, error) {
+	prefix, ok := apiKeyPrefixOf(key)
+	if !ok {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	apiKey, err := a.Repository.GetByPrefix(ctx, prefix)
+
+	if err == mongo.ErrNoDocuments {
+		return apiKey, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return apiKey, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hashToken(key))) != 1 {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+	if apiKey.RevokedAt != nil {
+		return models.APIKey{}, ErrInvalidAPIKey.Withf("api key is revoked")
+	}
+
+	if err := a.Repository.Track(ctx, apiKey.ID, time.Now()); err != nil {
+		logrus.Errorf("Usage of {%v} api key cannot be written: %v", apiKey.ID, err)
+	}
+
+	return apiKey, nil
+}
+
+// newAPIKey => a random key and its prefix
+func newAPIKey() (string, string, error) {
+	random := make([]byte, apiKeyPrefixBytes+apiKeySecretBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+
+	prefix := hex.EncodeToString(random[:apiKeyPrefixBytes])
+	secret := base64.RawURLEncoding.EncodeToString(random[apiKeyPrefixBytes:])
+
+	return apiKeyPrefix + prefix + "_" + secret, prefix, nil
+}
+
+// apiKeyPrefixOf => prefix of a key, false if the key is not like rwe_<prefix>_<secret>
+func apiKeyPrefixOf(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", false
+	}
+
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !ok || len(prefix) != 2*apiKeyPrefixBytes || secret == "" {
+		return "", false
+	}
+
+	return prefix, true
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// entityBook => entity type of book audit records
+const entityBook = "book"
+
+// fields which change on every mutation, they are not written into audit changes
+var auditIgnoredFields = map[string]bool{"_id": true, "version": true, "updateddate": true}
+
+// writeAudit => to record a mutation with its actor and request id, changes can be calculated by diff
+// => the mutation is already done, so a failure is logged instead of being returned
+func writeAudit(ctx context.Context, auditRepository repository.IAuditRepository, entityType string, id string, action string, changes []models.FieldChange) {
+	if auditRepository == nil {
+		return
+	}
+
+	info := requestinfo.From(ctx)
+
+	record := models.AuditRecord{
+		ID:         uuid.New().String(),
+		Date:       primitive.NewDateTimeFromTime(time.Now()),
+		EntityType: entityType,
+		EntityID:   id,
+		Action:     action,
+		Actor:      info.Actor,
+		RequestID:  info.RequestID,
+		Changes:    changes,
+	}
+
+	if err := auditRepository.Insert(ctx, record); err != nil {
+		logrus.Errorf("Audit record cannot be written for {%v} %v %v: %v", id, entityType, action, err)
+	}
+}
+
+// diff => changed fields by bson names, nil documents are treated as empty (e.g. before of create)
+func diff(before interface{}, after interface{}) []models.FieldChange {
+	var changes []models.FieldChange
+
+	beforeFields, afterFields := toFields(before), toFields(after)
+
+	names := map[string]bool{}
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+
+	for name := range names {
+		if auditIgnoredFields[name] {
+			continue
+		}
+		if !reflect.DeepEqual(beforeFields[name], afterFields[name]) {
+			changes = append(changes, models.FieldChange{Field: name, Before: beforeFields[name], After: afterFields[name]})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	return changes
+}
+
+func toFields(document interface{}) bson.M {
+	fields := bson.M{}
+
+	if document == nil {
+		return fields
+	}
+	if value := reflect.ValueOf(document); value.Kind() == reflect.Ptr && value.IsNil() {
+		return fields
+	}
+
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return fields
+	}
+	_ = bson.Unmarshal(data, &fields)
+
+	return fields
+}
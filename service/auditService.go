@@ -0,0 +1,45 @@
+package service
+
+import (
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"context"
+	"fmt"
+)
+
+type AuditService struct {
+	Repository repository.IAuditRepository
+}
+
+var singleInstanceAuditService *AuditService
+
+func GetSingleInstancesAuditService(repository repository.IAuditRepository) *AuditService {
+	if singleInstanceAuditService == nil {
+		fmt.Println("Creating single audit service instance now.")
+		singleInstanceAuditService = &AuditService{Repository: repository}
+	} else {
+		fmt.Println("Single audit service instance already created.")
+	}
+
+	return singleInstanceAuditService
+}
+
+type IAuditService interface {
+	GetBookHistory(ctx context.Context, id string, skip int64, limit int64) ([]models.AuditRecord, int64, error)
+	Find(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, int64, error)
+}
+
+// GetBookHistory => audit records of a single book, newest first
+func (a AuditService) GetBookHistory(ctx context.Context, id string, skip int64, limit int64) ([]models.AuditRecord, int64, error) {
+	return a.Find(ctx, models.AuditQuery{EntityType: entityBook, EntityID: id, Skip: skip, Limit: limit})
+}
+
+func (a AuditService) Find(ctx context.Context, query models.AuditQuery) ([]models.AuditRecord, int64, error) {
+	result, total, err := a.Repository.Find(ctx, query)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
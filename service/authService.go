@@ -0,0 +1,284 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/repository"
+	"RestfulWithEcho/requestinfo"
+	"RestfulWithEcho/tenant"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCredentials => username or password is wrong, which one is not told
+var ErrInvalidCredentials = errors.New(errors.Unauthorized, "invalid_credentials", "username or password is wrong")
+
+// ErrInvalidToken => access token is missing, expired or not signed by this api
+var ErrInvalidToken = errors.New(errors.Unauthorized, "invalid_token", "access token is invalid")
+
+// ErrInvalidRefreshToken => refresh token is unknown, expired or revoked
+var ErrInvalidRefreshToken = errors.New(errors.Unauthorized, "invalid_refresh_token", "refresh token is invalid")
+
+// ErrWeakPassword => password of a user must have at least minPasswordLength characters
+var ErrWeakPassword = errors.New(errors.Invalid, "weak_password",
+	fmt.Sprintf("password must have at least %d characters", minPasswordLength))
+
+// ErrDuplicateUsername => username is taken by another user
+var ErrDuplicateUsername = errors.New(errors.Conflict, "duplicate_username", "username is taken")
+
+const (
+	minPasswordLength = 8
+	// refreshTokenBytes => random bytes of a refresh token, it is sent as base64url
+	refreshTokenBytes = 32
+)
+
+// dummyHash => compared when the user is unknown, so a login takes the same time for known and unknown usernames
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// AccessClaims => claims of an access token, subject is the id of the user
+// => roles are read on login & refresh, so a change of the roles is seen by the next refresh
+// => tenant is empty in the tokens of the users which were created before tenants
+type AccessClaims struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	Tenant   string   `json:"tenant,omitempty"`
+	jwt.StandardClaims
+}
+
+type AuthService struct {
+	UserRepository  repository.IUserRepository
+	TokenRepository repository.ITokenRepository
+	// HMAC key of the access tokens (HS256)
+	SigningKey []byte
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+var singleInstanceAuthService *AuthService
+
+func GetSingleInstancesAuthService(userRepository repository.IUserRepository, tokenRepository repository.ITokenRepository,
+	signingKey []byte, issuer string, accessTTL time.Duration, refreshTTL time.Duration) *AuthService {
+	if singleInstanceAuthService == nil {
+		fmt.Println("Creating single auth service instance now.")
+		singleInstanceAuthService = &AuthService{UserRepository: userRepository, TokenRepository: tokenRepository,
+			SigningKey: signingKey, Issuer: issuer, AccessTTL: accessTTL, RefreshTTL: refreshTTL}
+	} else {
+		fmt.Println("Single auth service instance already created.")
+	}
+
+	return singleInstanceAuthService
+}
+
+type IAuthService interface {
+	CreateUser(ctx context.Context, username string, password string, roles []string) (models.User, error)
+	Login(ctx context.Context, username string, password string) (models.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error)
+	Logout(ctx context.Context, refreshToken string) error
+	Authenticate(accessToken string) (AccessClaims, error)
+}
+
+// CreateUser => password is stored as bcrypt hash, usernames are case insensitive, a user without roles is a viewer
+// => user belongs to the tenant of the context, usernames are unique across tenants
+func (a AuthService) CreateUser(ctx context.Context, username string, password string, roles []string) (models.User, error) {
+	if len(password) < minPasswordLength {
+		return models.User{}, ErrWeakPassword
+	}
+	if err := rbac.ValidRoles(roles); err != nil {
+		return models.User{}, err
+	}
+	if len(roles) == 0 {
+		roles = []string{rbac.Viewer}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		ID:           uuid.New().String(),
+		CreatedDate:  primitive.NewDateTimeFromTime(time.Now()),
+		Username:     normalizeUsername(username),
+		PasswordHash: string(hash),
+		Roles:        roles,
+		TenantID:     tenant.OrDefault(requestinfo.From(ctx).TenantID),
+	}
+	user.UpdatedDate = user.CreatedDate
+
+	if err := a.UserRepository.Insert(ctx, user); err != nil {
+		if err == repository.ErrDuplicateKey {
+			return user, ErrDuplicateUsername.Withf("{%v} username is taken", user.Username)
+		}
+		return user, err
+	}
+
+	return user, nil
+}
+
+// Login => a new family of refresh tokens is started for every login
+func (a AuthService) Login(ctx context.Context, username string, password string) (models.TokenPair, error) {
+	user, err := a.UserRepository.GetByUsername(ctx, normalizeUsername(username))
+
+	if err == mongo.ErrNoDocuments {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return models.TokenPair{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return models.TokenPair{}, ErrInvalidCredentials
+	}
+
+	return a.issue(ctx, user, uuid.New().String())
+}
+
+// Refresh => refresh token is rotated, it is revoked and a new one of the same family is returned
+// => a revoked token which is used again is a stolen one, so every token of its family is revoked
+func (a AuthService) Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error) {
+	stored, err := a.TokenRepository.GetById(ctx, hashToken(refreshToken))
+
+	if err == mongo.ErrNoDocuments {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	if stored.RevokedAt != nil {
+		return models.TokenPair{}, a.revokeFamily(ctx, stored.Family)
+	}
+	if stored.ExpiresAt.Time().Before(time.Now()) {
+		return models.TokenPair{}, ErrInvalidRefreshToken.Withf("refresh token is expired")
+	}
+
+	// another refresh with the same token revoked it in between
+	if err := a.TokenRepository.Revoke(ctx, stored.ID); err == mongo.ErrNoDocuments {
+		return models.TokenPair{}, a.revokeFamily(ctx, stored.Family)
+	} else if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	user, err := a.UserRepository.GetById(ctx, stored.UserID)
+
+	if err == mongo.ErrNoDocuments {
+		return models.TokenPair{}, ErrInvalidRefreshToken.Withf("user of the refresh token doesn't exist")
+	}
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return a.issue(ctx, user, stored.Family)
+}
+
+// Logout => every refresh token of the login is revoked, access tokens are valid until they expire
+func (a AuthService) Logout(ctx context.Context, refreshToken string) error {
+	stored, err := a.TokenRepository.GetById(ctx, hashToken(refreshToken))
+
+	if err == mongo.ErrNoDocuments {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.TokenRepository.RevokeFamily(ctx, stored.Family)
+}
+
+// Authenticate => claims of a valid access token, only HS256 tokens of this issuer are accepted
+func (a AuthService) Authenticate(accessToken string) (AccessClaims, error) {
+	var claims AccessClaims
+
+	token, err := jwt.ParseWithClaims(accessToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return a.SigningKey, nil
+	})
+
+	if err != nil || !token.Valid {
+		return claims, ErrInvalidToken.Wrap(err)
+	}
+	if !claims.VerifyIssuer(a.Issuer, true) || claims.Subject == "" {
+		return claims, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// issue => a new access token and a new refresh token of the family
+func (a AuthService) issue(ctx context.Context, user models.User, family string) (models.TokenPair, error) {
+	now := time.Now()
+
+	claims := AccessClaims{
+		Username: user.Username,
+		Roles:    user.Roles,
+		Tenant:   user.TenantID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			Subject:   user.ID,
+			Issuer:    a.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(a.AccessTTL).Unix(),
+		},
+	}
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.SigningKey)
+
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	random := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return models.TokenPair{}, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(random)
+
+	err = a.TokenRepository.Insert(ctx, models.RefreshToken{
+		ID:          hashToken(refreshToken),
+		UserID:      user.ID,
+		Family:      family,
+		CreatedDate: primitive.NewDateTimeFromTime(now),
+		ExpiresAt:   primitive.NewDateTimeFromTime(now.Add(a.RefreshTTL)),
+	})
+
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: int(a.AccessTTL.Seconds())}, nil
+}
+
+// revokeFamily => reuse of a revoked refresh token ends its login
+func (a AuthService) revokeFamily(ctx context.Context, family string) error {
+	if err := a.TokenRepository.RevokeFamily(ctx, family); err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken.Withf("refresh token is used again, the login is revoked")
+}
+
+// hashToken => refresh tokens are stored as their sha256
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/repository"
+	"context"
+	"crypto/rand"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUserRepository => users in memory, usernames are unique like the index of the collection
+type fakeUserRepository struct {
+	mu    sync.Mutex
+	users map[string]models.User
+}
+
+func (f *fakeUserRepository) Insert(ctx context.Context, user models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, existing := range f.users {
+		if existing.Username == user.Username {
+			return repository.ErrDuplicateKey
+		}
+	}
+	f.users[user.ID] = user
+	return nil
+}
+
+func (f *fakeUserRepository) GetById(ctx context.Context, id string) (models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[id]
+	if !ok {
+		return user, mongo.ErrNoDocuments
+	}
+	return user, nil
+}
+
+func (f *fakeUserRepository) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, user := range f.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return models.User{}, mongo.ErrNoDocuments
+}
+
+// fakeTokenRepository => refresh tokens in memory, Revoke behaves like the filter on revokedat
+type fakeTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]models.RefreshToken
+}
+
+func (f *fakeTokenRepository) Insert(ctx context.Context, token models.RefreshToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tokens[token.ID] = token
+	return nil
+}
+
+func (f *fakeTokenRepository) GetById(ctx context.Context, id string) (models.RefreshToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token, ok := f.tokens[id]
+	if !ok {
+		return token, mongo.ErrNoDocuments
+	}
+	return token, nil
+}
+
+func (f *fakeTokenRepository) Revoke(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token, ok := f.tokens[id]
+	if !ok || token.RevokedAt != nil {
+		return mongo.ErrNoDocuments
+	}
+	now := primitive.NewDateTimeFromTime(time.Now())
+	token.RevokedAt = &now
+	f.tokens[id] = token
+	return nil
+}
+
+func (f *fakeTokenRepository) RevokeFamily(ctx context.Context, family string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for id, token := range f.tokens {
+		if token.Family == family && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			f.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+// newTestAuthService => auth service with in memory repositories and a generated HS256 key
+func newTestAuthService(t *testing.T) AuthService {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("signing key cannot be generated: %v", err)
+	}
+
+	return AuthService{
+		UserRepository:  &fakeUserRepository{users: map[string]models.User{}},
+		TokenRepository: &fakeTokenRepository{tokens: map[string]models.RefreshToken{}},
+		SigningKey:      key,
+		Issuer:          "test",
+		AccessTTL:       time.Minute,
+		RefreshTTL:      time.Hour,
+	}
+}
+
+func createTestUser(t *testing.T, auth AuthService) models.User {
+	t.Helper()
+
+	user, err := auth.CreateUser(context.Background(), " Alice ", "correct horse", []string{rbac.Editor})
+	if err != nil {
+		t.Fatalf("user cannot be created: %v", err)
+	}
+	return user
+}
+
+// requireCode => err must be a domain error with the code
+func requireCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	e, ok := errors.As(err)
+	if !ok || e.Code != code {
+		t.Fatalf("expected %v error, got %v", code, err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	auth := newTestAuthService(t)
+	user := createTestUser(t, auth)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		code     string
+	}{
+		{name: "valid credentials", username: "alice", password: "correct horse"},
+		{name: "username is case insensitive", username: "ALICE ", password: "correct horse"},
+		{name: "wrong password", username: "alice", password: "wrong horse", code: "invalid_credentials"},
+		{name: "unknown user", username: "bob", password: "correct horse", code: "invalid_credentials"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pair, err := auth.Login(context.Background(), test.username, test.password)
+
+			if test.code != "" {
+				requireCode(t, err, test.code)
+				return
+			}
+			if err != nil {
+				t.Fatalf("login failed: %v", err)
+			}
+			if pair.RefreshToken == "" || pair.ExpiresIn != int(auth.AccessTTL.Seconds()) {
+				t.Fatalf("unexpected token pair: %+v", pair)
+			}
+
+			claims, err := auth.Authenticate(pair.AccessToken)
+			if err != nil {
+				t.Fatalf("access token is not accepted: %v", err)
+			}
+			if claims.Subject != user.ID || claims.Username != "alice" || len(claims.Roles) != 1 || claims.Roles[0] != rbac.Editor {
+				t.Fatalf("unexpected claims: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRejectsForeignTokens(t *testing.T) {
+	auth := newTestAuthService(t)
+	createTestUser(t, auth)
+
+	pair, err := auth.Login(context.Background(), "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	other := newTestAuthService(t)
+	if _, err := other.Authenticate(pair.AccessToken); err == nil {
+		t.Fatal("token signed with another key is accepted")
+	}
+
+	other.SigningKey, other.Issuer = auth.SigningKey, "another issuer"
+	if _, err := other.Authenticate(pair.AccessToken); err == nil {
+		t.Fatal("token of another issuer is accepted")
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	auth := newTestAuthService(t)
+	createTestUser(t, auth)
+	ctx := context.Background()
+
+	pair, err := auth.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	rotated, err := auth.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if rotated.RefreshToken == pair.RefreshToken {
+		t.Fatal("refresh token is not rotated")
+	}
+	if _, err := auth.Authenticate(rotated.AccessToken); err != nil {
+		t.Fatalf("access token of the refresh is not accepted: %v", err)
+	}
+
+	// new token of the same family can be used once more
+	if _, err := auth.Refresh(ctx, rotated.RefreshToken); err != nil {
+		t.Fatalf("rotated token cannot be refreshed: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	auth := newTestAuthService(t)
+	createTestUser(t, auth)
+	ctx := context.Background()
+
+	pair, err := auth.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	rotated, err := auth.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	// stolen token is used again => whole login is revoked
+	_, err = auth.Refresh(ctx, pair.RefreshToken)
+	requireCode(t, err, "invalid_refresh_token")
+
+	_, err = auth.Refresh(ctx, rotated.RefreshToken)
+	requireCode(t, err, "invalid_refresh_token")
+
+	// other logins of the user are not affected
+	other, err := auth.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if _, err := auth.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("token of another login is revoked: %v", err)
+	}
+}
+
+func TestRefreshRejectsUnknownAndExpiredTokens(t *testing.T) {
+	auth := newTestAuthService(t)
+	createTestUser(t, auth)
+	ctx := context.Background()
+
+	_, err := auth.Refresh(ctx, "unknown")
+	requireCode(t, err, "invalid_refresh_token")
+
+	auth.RefreshTTL = -time.Minute
+	pair, err := auth.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	_, err = auth.Refresh(ctx, pair.RefreshToken)
+	requireCode(t, err, "invalid_refresh_token")
+}
+
+func TestLogout(t *testing.T) {
+	auth := newTestAuthService(t)
+	createTestUser(t, auth)
+	ctx := context.Background()
+
+	pair, err := auth.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	rotated, err := auth.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	if err := auth.Logout(ctx, rotated.RefreshToken); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	_, err = auth.Refresh(ctx, rotated.RefreshToken)
+	requireCode(t, err, "invalid_refresh_token")
+
+	requireCode(t, auth.Logout(ctx, "unknown"), "invalid_refresh_token")
+}
+
+func TestCreateUser(t *testing.T) {
+	auth := newTestAuthService(t)
+	ctx := context.Background()
+
+	user, err := auth.CreateUser(ctx, "viewer", "long enough", nil)
+	if err != nil {
+		t.Fatalf("user cannot be created: %v", err)
+	}
+	if len(user.Roles) != 1 || user.Roles[0] != rbac.Viewer || user.PasswordHash == "long enough" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+
+	_, err = auth.CreateUser(ctx, "VIEWER", "long enough", nil)
+	requireCode(t, err, "duplicate_username")
+
+	_, err = auth.CreateUser(ctx, "short", "short", nil)
+	requireCode(t, err, "weak_password")
+
+	_, err = auth.CreateUser(ctx, "owner", "long enough", []string{"owner"})
+	if err == nil {
+		t.Fatal("unknown role is accepted")
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ErrAuthorHasBooks => author cannot be deleted while books reference it
+var ErrAuthorHasBooks = errors.New(errors.Conflict, "author_has_books", "author has books")
+
+// ErrInvalidAuthorName => name of an author must have at least one letter or digit
+var ErrInvalidAuthorName = errors.New(errors.Invalid, "invalid_author_name", "author name must have a letter or digit")
+
+// ErrAuthorNotFound => author doesn't exist
+var ErrAuthorNotFound = errors.New(errors.NotFound, "author_not_found", "author is not found")
+
+// ErrDuplicateAuthor => the same author (by normalized name) exists
+var ErrDuplicateAuthor = errors.New(errors.Conflict, "duplicate_author", "author exists")
+
+// entityAuthor => entity type of author audit records
+const entityAuthor = "author"
+
+type AuthorService struct {
+	Repository      repository.IAuthorRepository
+	BookRepository  repository.IBookRepository
+	AuditRepository repository.IAuditRepository
+}
+
+var singleInstanceAuthorService *AuthorService
+
+func GetSingleInstancesAuthorService(repository repository.IAuthorRepository, bookRepository repository.IBookRepository,
+	auditRepository repository.IAuditRepository) *AuthorService {
+	if singleInstanceAuthorService == nil {
+		fmt.Println("Creating single author service instance now.")
+		singleInstanceAuthorService = &AuthorService{Repository: repository, BookRepository: bookRepository,
+			AuditRepository: auditRepository}
+	} else {
+		fmt.Println("Single author service instance already created.")
+	}
+
+	return singleInstanceAuthorService
+}
+
+type IAuthorService interface {
+	Insert(ctx context.Context, author models.Author) (models.Author, error)
+	GetAll(ctx context.Context, skip int64, limit int64) ([]models.Author, int64, error)
+	GetById(ctx context.Context, id string) (models.Author, error)
+	Update(ctx context.Context, author models.Author) (models.Author, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	GetBooks(ctx context.Context, id string, query models.BookQuery) ([]models.Book, int64, error)
+}
+
+// Insert => ErrDuplicateAuthor is returned if the same author (by normalized name) exists
+func (a AuthorService) Insert(ctx context.Context, author models.Author) (models.Author, error) {
+	// to create id, key and created date value
+	author.ID = uuid.New().String()
+	author.Key = models.AuthorKey(author.Name)
+	author.CreatedDate = primitive.NewDateTimeFromTime(time.Now())
+	author.UpdatedDate = author.CreatedDate
+
+	if author.Key == "" {
+		return author, ErrInvalidAuthorName
+	}
+
+	if err := a.Repository.Insert(ctx, author); err != nil {
+		return author, authorError(err, author.Name)
+	}
+
+	writeAudit(ctx, a.AuditRepository, entityAuthor, author.ID, models.AuditCreate, diff(nil, &author))
+
+	return author, nil
+}
+
+func (a AuthorService) GetAll(ctx context.Context, skip int64, limit int64) ([]models.Author, int64, error) {
+	result, total, err := a.Repository.GetAll(ctx, skip, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (a AuthorService) GetById(ctx context.Context, id string) (models.Author, error) {
+	result, err := a.Repository.GetById(ctx, id)
+
+	if err != nil {
+		return result, authorError(err, id)
+	}
+
+	return result, nil
+}
+
+func (a AuthorService) Update(ctx context.Context, author models.Author) (models.Author, error) {
+	// to create key and updated date value
+	author.Key = models.AuthorKey(author.Name)
+	author.UpdatedDate = primitive.NewDateTimeFromTime(time.Now())
+
+	if author.Key == "" {
+		return author, ErrInvalidAuthorName
+	}
+
+	// to keep the old values for audit
+	before, err := a.Repository.GetById(ctx, author.ID)
+
+	if err != nil {
+		return before, authorError(err, author.ID)
+	}
+
+	result, err := a.Repository.Update(ctx, author)
+
+	if err != nil {
+		return result, authorError(err, author.ID)
+	}
+
+	writeAudit(ctx, a.AuditRepository, entityAuthor, result.ID, models.AuditUpdate, diff(&before, &result))
+
+	return result, nil
+}
+
+// Delete => authors which are referenced by books (trash included) are kept, ErrAuthorHasBooks is returned for them
+func (a AuthorService) Delete(ctx context.Context, id string) (bool, error) {
+	if _, err := a.Repository.GetById(ctx, id); err != nil {
+		return false, authorError(err, id)
+	}
+
+	for _, deleted := range []bool{false, true} {
+		_, total, err := a.BookRepository.GetAll(ctx, a.booksQuery(id, models.BookQuery{Limit: 1, Deleted: deleted}))
+
+		if err != nil {
+			return false, err
+		}
+		if total > 0 {
+			return false, ErrAuthorHasBooks.Withf("{%v} with id author has books", id)
+		}
+	}
+
+	result, err := a.Repository.Delete(ctx, id)
+
+	if err != nil {
+		return false, err
+	}
+	if result == false {
+		return false, ErrAuthorNotFound.Withf("{%v} with id author is not found", id)
+	}
+
+	writeAudit(ctx, a.AuditRepository, entityAuthor, id, models.AuditDelete, nil)
+
+	return true, nil
+}
+
+// GetBooks => books of an author, query works like in GET /api/books
+func (a AuthorService) GetBooks(ctx context.Context, id string, query models.BookQuery) ([]models.Book, int64, error) {
+	if _, err := a.Repository.GetById(ctx, id); err != nil {
+		return nil, 0, authorError(err, id)
+	}
+
+	result, total, err := a.BookRepository.GetAll(ctx, a.booksQuery(id, query))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// booksQuery => query with the filter of the books which reference the author
+func (a AuthorService) booksQuery(id string, query models.BookQuery) models.BookQuery {
+	query.Filters = append(query.Filters, models.Filter{Field: "authorids", Operator: "eq", Value: id})
+	return query
+}
+
+// authorError => errors of the author repository as domain errors, key is the id or the name of the author
+func authorError(err error, key string) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return ErrAuthorNotFound.Withf("{%v} with id author is not found", key)
+	case repository.ErrDuplicateKey:
+		return ErrDuplicateAuthor.Withf("{%v} author exists", key)
+	}
+
+	return err
+}
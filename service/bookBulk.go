@@ -0,0 +1,204 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ErrUpsertConflict => the unique index of ids doesn't tell why an upsert cannot write a book, one of these is the reason
+var ErrUpsertConflict = errors.New(errors.Conflict, "upsert_conflict",
+	"the book is in trash, has more held copies than the quantity or another book has the same isbn")
+
+// BulkInsert => to create books with one write, results are in the order of books
+// => a failed book doesn't stop the others, its error is in its result
+func (b BookService) BulkInsert(ctx context.Context, books []models.Book) ([]models.BulkResult, error) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for i := range books {
+		books[i].ID = uuid.New().String()
+		books[i].CreatedDate = now
+		books[i].UpdatedDate = now
+		books[i].Version = 1
+	}
+
+	results, valid, positions, err := b.prepareBulk(ctx, books)
+
+	if err != nil || len(valid) == 0 {
+		return results, err
+	}
+
+	failed, err := b.Repository.BulkInsert(ctx, valid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, book := range valid {
+		result := &results[positions[i]]
+		if result.Err = bookError(failed[i], book.ID); result.Err != nil {
+			continue
+		}
+		result.Created = true
+		writeAudit(ctx, b.AuditRepository, entityBook, book.ID, models.AuditCreate, diff(nil, &book))
+	}
+
+	return results, nil
+}
+
+// BulkUpsert => to create or change books by their ids with one write, results are in the order of books
+// => versions are not checked, the given values are written
+func (b BookService) BulkUpsert(ctx context.Context, books []models.Book) ([]models.BulkResult, error) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for i := range books {
+		books[i].CreatedDate = now
+		books[i].UpdatedDate = now
+	}
+
+	results, valid, positions, err := b.prepareBulk(ctx, books)
+
+	if err != nil || len(valid) == 0 {
+		return results, err
+	}
+
+	// to keep the old values for audit
+	ids := make([]string, 0, len(valid))
+	for _, book := range valid {
+		ids = append(ids, book.ID)
+	}
+
+	stored, err := b.Repository.GetBooksByIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	befores := map[string]models.Book{}
+	for _, book := range stored {
+		befores[book.ID] = book
+	}
+
+	created, failed, err := b.Repository.BulkUpsert(ctx, valid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, book := range valid {
+		result := &results[positions[i]]
+		if result.Err = failed[i]; result.Err == repository.ErrDuplicateKey {
+			result.Err = ErrUpsertConflict.Withf("{%v} with id book cannot be written, %v", book.ID, ErrUpsertConflict.Message)
+		} else {
+			// ids of other tenants are not found, never an ISBN duplicate
+			result.Err = bookError(result.Err, book.ID)
+		}
+		if result.Err != nil {
+			continue
+		}
+
+		before, exists := befores[book.ID]
+		if result.Created = created[i]; result.Created || !exists {
+			book.Version = 1
+			writeAudit(ctx, b.AuditRepository, entityBook, book.ID, models.AuditCreate, diff(nil, &book))
+			continue
+		}
+
+		after := upserted(before, book)
+		writeAudit(ctx, b.AuditRepository, entityBook, book.ID, models.AuditUpdate, diff(&before, &after))
+
+		if after.Quantity > before.Quantity {
+			promoteHolds(ctx, b.HoldService, book.ID)
+		}
+	}
+
+	return results, nil
+}
+
+// BulkDelete => to move books into trash with one write, results are in the order of ids
+// => ErrBookNotFound is the error of an id which doesn't exist or is already in trash
+func (b BookService) BulkDelete(ctx context.Context, ids []string) ([]models.BulkResult, error) {
+	deleted, err := b.Repository.BulkDelete(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	isDeleted := map[string]bool{}
+	for _, id := range deleted {
+		isDeleted[id] = true
+		writeAudit(ctx, b.AuditRepository, entityBook, id, models.AuditDelete, nil)
+	}
+
+	results := make([]models.BulkResult, 0, len(ids))
+	for i, id := range ids {
+		result := models.BulkResult{Index: i, ID: id}
+		if !isDeleted[id] {
+			result.Err = ErrBookNotFound.Withf("{%v} with id book is not found", id)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// BulkCheck => results of a bulk write without writing, e.g. for dry runs
+// => only the rules of the service are checked, unique ISBNs are checked by the database while writing
+func (b BookService) BulkCheck(ctx context.Context, books []models.Book) ([]models.BulkResult, error) {
+	results, _, _, err := b.prepareBulk(ctx, books)
+
+	return results, err
+}
+
+// prepareBulk => books are normalized like in Insert, authors of all books are read at once
+// => books which cannot be written have an error in results, the others are returned with their positions in books
+func (b BookService) prepareBulk(ctx context.Context, books []models.Book) ([]models.BulkResult, []models.Book, []int, error) {
+	var ids []string
+	for _, book := range books {
+		ids = append(ids, book.AuthorIDs...)
+	}
+
+	authors, err := b.getAuthors(ctx, ids)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	results := make([]models.BulkResult, 0, len(books))
+	valid := make([]models.Book, 0, len(books))
+	var positions []int
+
+	for i, book := range books {
+		result := models.BulkResult{Index: i, ID: book.ID}
+
+		book.ISBN10, book.ISBN13, result.Err = normalizeISBN(book.ISBN10, book.ISBN13)
+		if result.Err == nil {
+			book.Category, book.CategoryPath = models.CategoryPath(book.Category)
+			book.Tags = models.NormalizeTags(book.Tags)
+			result.Err = setAuthorNames(&book, authors)
+		}
+		result.Err = bookError(result.Err, book.ID)
+
+		if result.Err == nil {
+			valid = append(valid, book)
+			positions = append(positions, i)
+		}
+		results = append(results, result)
+	}
+
+	return results, valid, positions, nil
+}
+
+// upserted => the stored book after an upsert, only the fields which are written by the upsert are changed
+func upserted(before models.Book, book models.Book) models.Book {
+	after := before
+
+	after.Title, after.Author, after.AuthorIDs = book.Title, book.Author, book.AuthorIDs
+	after.ISBN10, after.ISBN13 = book.ISBN10, book.ISBN13
+	after.Category, after.CategoryPath, after.Tags = book.Category, book.CategoryPath, book.Tags
+	after.Quantity, after.UpdatedDate = book.Quantity, book.UpdatedDate
+
+	return after
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBulkUpsertReportsBooksOfOtherTenantsAsNotFound(t *testing.T) {
+	repository := &fakeBookRepository{books: map[string]models.Book{}, foreign: map[string]bool{"foreign": true}}
+	books := BookService{Repository: repository}
+
+	results, err := books.BulkUpsert(context.Background(), []models.Book{
+		{ID: "foreign", Title: "Dune", Author: "Frank Herbert", ISBN13: "9780441013593"},
+		{ID: "own", Title: "Emma", Author: "Jane Austen"},
+	})
+
+	if err != nil {
+		t.Fatalf("books cannot be upserted: %v", err)
+	}
+	if len(results) != 2 || results[1].Err != nil || !results[1].Created {
+		t.Fatalf("own book is not created: %+v", results)
+	}
+
+	// nothing tells that the id is taken by another tenant
+	foreign := results[0].Err
+	if !errors.Is(foreign, ErrBookNotFound) || errors.Is(foreign, ErrDuplicateISBN) ||
+		strings.Contains(foreign.Error(), "isbn") || strings.Contains(foreign.Error(), "tenant") {
+		t.Fatalf("expected %v, got %v", ErrBookNotFound, foreign)
+	}
+}
@@ -1,16 +1,53 @@
 package service
 
 import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/isbn"
 	"RestfulWithEcho/models"
 	"RestfulWithEcho/repository"
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
 	"time"
 )
 
+// ErrUnknownAuthor => a book references an author which doesn't exist
+var ErrUnknownAuthor = errors.New(errors.Invalid, "unknown_author", "unknown author")
+
+// ErrISBNMismatch => isbn10 and isbn13 of a book are not the same ISBN
+var ErrISBNMismatch = errors.New(errors.Invalid, "isbn_mismatch", "isbn10 and isbn13 are different books")
+
+// ErrInvalidISBN => isbn.ErrInvalid of a book or of a lookup
+var ErrInvalidISBN = errors.New(errors.Invalid, "invalid_isbn", "invalid isbn")
+
+// ErrBookNotFound => book doesn't exist or it is in trash
+var ErrBookNotFound = errors.New(errors.NotFound, "book_not_found", "book is not found")
+
+// ErrDuplicateISBN => another book has the same isbn
+var ErrDuplicateISBN = errors.New(errors.Conflict, "duplicate_isbn", "another book has the same isbn")
+
+// ErrVersionMismatch => book is not at the version which the client has (If-Match)
+var ErrVersionMismatch = errors.New(errors.PreconditionFailed, "version_mismatch", "book is changed by somebody else")
+
+// ErrConcurrentChange => book is changed between the read and the write of the service, the client can try again
+var ErrConcurrentChange = errors.New(errors.Conflict, "concurrent_change", "book is changed by somebody else, try again")
+
+// ErrHeldCopies => quantity of a book cannot be less than its copies which are held for reservations & holds
+var ErrHeldCopies = errors.New(errors.Conflict, "held_copies", "book has more held copies than the quantity")
+
 type BookService struct {
 	Repository repository.IBookRepository
+	// every mutation is recorded here
+	AuditRepository repository.IAuditRepository
+	// books reference authors by id
+	AuthorRepository repository.IAuthorRepository
+	// waiting holds are promoted when the quantity is increased
+	HoldService IHoldService
+	// deleted books stay in trash at least this long
+	TrashRetention time.Duration
 }
 
 // With singleton pattern to create just one Service we have to write like this or using once.
@@ -18,10 +55,12 @@ type BookService struct {
 // var lock = &sync.Mutex{}
 var singleInstanceService *BookService
 
-func GetSingleInstancesService(repository repository.IBookRepository) *BookService {
+func GetSingleInstancesService(repository repository.IBookRepository, auditRepository repository.IAuditRepository,
+	authorRepository repository.IAuthorRepository, holdService IHoldService, trashRetention time.Duration) *BookService {
 	if singleInstanceService == nil {
 		fmt.Println("Creating single service instance now.")
-		singleInstanceService = &BookService{Repository: repository}
+		singleInstanceService = &BookService{Repository: repository, AuditRepository: auditRepository,
+			AuthorRepository: authorRepository, HoldService: holdService, TrashRetention: trashRetention}
 	} else {
 		fmt.Println("Single service instance already created.")
 	}
@@ -30,41 +69,119 @@ func GetSingleInstancesService(repository repository.IBookRepository) *BookServi
 }
 
 type IBookService interface {
-	Insert(bookDto models.Book) (models.Book, error)
-	GetAll() ([]models.Book, error)
-	GetBookById(id string) (models.Book, error)
-	Update(bookDto models.Book) (bool, error)
-	Delete(id string) (bool, error)
+	Insert(ctx context.Context, bookDto models.Book) (models.Book, error)
+	GetAll(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error)
+	GetAllByCursor(ctx context.Context, query models.BookQuery, cursor string) ([]models.Book, string, error)
+	Export(ctx context.Context, query models.BookQuery, each func(book models.Book) error) error
+	GetBookById(ctx context.Context, id string) (models.Book, error)
+	GetBookByISBN(ctx context.Context, text string) (models.Book, error)
+	Search(ctx context.Context, text string, limit int64) ([]models.BookSearchResult, error)
+	Facets(ctx context.Context, query models.BookQuery, limit int64) (models.BookFacets, error)
+	Update(ctx context.Context, bookDto models.Book) (models.Book, error)
+	Patch(ctx context.Context, id string, version int, fields map[string]interface{}) (models.Book, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	GetTrash(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error)
+	Restore(ctx context.Context, id string) (bool, error)
+	PurgeTrash(ctx context.Context) (int64, error)
+	GetAuthors(ctx context.Context, books []models.Book) (map[string]models.Author, error)
+	BulkInsert(ctx context.Context, books []models.Book) ([]models.BulkResult, error)
+	BulkUpsert(ctx context.Context, books []models.Book) ([]models.BulkResult, error)
+	BulkDelete(ctx context.Context, ids []string) ([]models.BulkResult, error)
+	BulkCheck(ctx context.Context, books []models.Book) ([]models.BulkResult, error)
 }
 
-func (b BookService) Insert(book models.Book) (models.Book, error) {
+func (b BookService) Insert(ctx context.Context, book models.Book) (models.Book, error) {
 
 	// to create id and created date value
 	book.ID = uuid.New().String()
 	book.CreatedDate = primitive.NewDateTimeFromTime(time.Now())
+	book.UpdatedDate = book.CreatedDate
+	book.Version = 1
 
-	result, err := b.Repository.Insert(book)
+	var err error
+	if book.ISBN10, book.ISBN13, err = normalizeISBN(book.ISBN10, book.ISBN13); err != nil {
+		return book, bookError(err, book.ID)
+	}
 
-	if err != nil || result == false {
+	book.Category, book.CategoryPath = models.CategoryPath(book.Category)
+	book.Tags = models.NormalizeTags(book.Tags)
+
+	if err := b.resolveAuthors(ctx, &book); err != nil {
 		return book, err
 	}
 
+	result, err := b.Repository.Insert(ctx, book)
+
+	if err != nil || result == false {
+		return book, bookError(err, book.ID)
+	}
+
+	writeAudit(ctx, b.AuditRepository, entityBook, book.ID, models.AuditCreate, diff(nil, &book))
+
 	return book, nil
 }
 
-func (b BookService) GetAll() ([]models.Book, error) {
-	result, err := b.Repository.GetAll()
+func (b BookService) GetAll(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error) {
+	result, total, err := b.Repository.GetAll(ctx, query)
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return result, nil
+	return result, total, nil
+}
+
+// GetAllByCursor => to list books after the given opaque cursor, it returns the cursor of the next page ("" on the last page)
+func (b BookService) GetAllByCursor(ctx context.Context, query models.BookQuery, cursor string) ([]models.Book, string, error) {
+	var after *models.BookCursor
+
+	if cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &decoded
+	}
+
+	// one more book is requested to understand whether there is a next page
+	limit := query.Limit
+	query.Limit = limit + 1
+
+	result, err := b.Repository.GetAllAfter(ctx, query, after)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(result)) <= limit {
+		return result, "", nil
+	}
+
+	result = result[:limit]
+	last := result[len(result)-1]
+
+	return result, encodeCursor(models.BookCursor{CreatedDate: last.CreatedDate, ID: last.ID}), nil
+}
+
+// Export => books of the query are passed to each one by one, so a big catalog can be streamed
+func (b BookService) Export(ctx context.Context, query models.BookQuery, each func(book models.Book) error) error {
+	return b.Repository.Export(ctx, query, each)
 }
 
-func (b BookService) GetBookById(id string) (models.Book, error) {
+func (b BookService) GetBookById(ctx context.Context, id string) (models.Book, error) {
 
-	result, err := b.Repository.GetBookById(id)
+	result, err := b.Repository.GetBookById(ctx, id)
+
+	if err != nil {
+		return result, bookError(err, id)
+	}
+
+	return result, nil
+}
+
+// Facets => counts of the filtered books per category, tag and author
+func (b BookService) Facets(ctx context.Context, query models.BookQuery, limit int64) (models.BookFacets, error) {
+	result, err := b.Repository.Facets(ctx, query, limit)
 
 	if err != nil {
 		return result, err
@@ -73,25 +190,316 @@ func (b BookService) GetBookById(id string) (models.Book, error) {
 	return result, nil
 }
 
-func (b BookService) Update(book models.Book) (bool, error) {
+// GetBookByISBN => text can be an ISBN-10 or an ISBN-13 with or without hyphens, ErrInvalidISBN if it is not
+func (b BookService) GetBookByISBN(ctx context.Context, text string) (models.Book, error) {
+	_, isbn13, err := isbn.Parse(text)
+
+	if err != nil {
+		return models.Book{}, ErrInvalidISBN.Withf("{%v} is an invalid isbn", text)
+	}
+
+	result, err := b.Repository.GetBookByISBN(ctx, isbn13)
+
+	if err == mongo.ErrNoDocuments {
+		return result, ErrBookNotFound.Withf("{%v} with isbn book is not found", text)
+	}
+
+	return result, err
+}
+
+// Search => full-text search, matched terms are highlighted in title and author
+func (b BookService) Search(ctx context.Context, text string, limit int64) ([]models.BookSearchResult, error) {
+	result, err := b.Repository.Search(ctx, text, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	terms := searchTerms(text)
+	for i := range result {
+		result[i].Highlights = map[string]string{
+			"title":  highlight(result[i].Title, terms),
+			"author": highlight(result[i].Author, terms),
+		}
+	}
+
+	return result, nil
+}
+
+// Update => book.Version is the expected current version, zero means "update whatever the version is"
+func (b BookService) Update(ctx context.Context, book models.Book) (models.Book, error) {
 	// to create updated date value
 	book.UpdatedDate = primitive.NewDateTimeFromTime(time.Now())
 
-	result, err := b.Repository.Update(book)
+	// to keep the old values for audit
+	before, err := b.Repository.GetBookById(ctx, book.ID)
 
-	if err != nil || result == false {
+	if err != nil {
+		return before, bookError(err, book.ID)
+	}
+
+	if book.ISBN10, book.ISBN13, err = normalizeISBN(book.ISBN10, book.ISBN13); err != nil {
+		return before, bookError(err, book.ID)
+	}
+
+	book.Category, book.CategoryPath = models.CategoryPath(book.Category)
+	book.Tags = models.NormalizeTags(book.Tags)
+
+	if err := b.resolveAuthors(ctx, &book); err != nil {
+		return before, err
+	}
+
+	result, err := b.Repository.Update(ctx, book)
+
+	if err == repository.ErrVersionConflict {
+		// version of an update is always the one which the client has
+		return result, ErrVersionMismatch.Withf("{%v} with id book is changed after version %v", book.ID, book.Version)
+	}
+	if err != nil {
+		return result, bookError(err, book.ID)
+	}
+
+	writeAudit(ctx, b.AuditRepository, entityBook, result.ID, models.AuditUpdate, diff(&before, &result))
+
+	if result.Quantity > before.Quantity {
+		promoteHolds(ctx, b.HoldService, result.ID)
+	}
+
+	return result, nil
+}
+
+// Patch => to change only the given fields (bson name => value), version must be the version which is patched
+func (b BookService) Patch(ctx context.Context, id string, version int, fields map[string]interface{}) (models.Book, error) {
+	// to create updated date value
+	fields["updateddate"] = primitive.NewDateTimeFromTime(time.Now())
+
+	// to keep the old values for audit
+	before, err := b.Repository.GetBookById(ctx, id)
+
+	if err != nil {
+		return before, bookError(err, id)
+	}
+
+	isbn10, hasISBN10 := fields["isbn10"].(string)
+	isbn13, hasISBN13 := fields["isbn13"].(string)
+	if hasISBN10 || hasISBN13 {
+		if fields["isbn10"], fields["isbn13"], err = normalizeISBN(isbn10, isbn13); err != nil {
+			return before, bookError(err, id)
+		}
+	}
+
+	if category, ok := fields["category"].(string); ok {
+		fields["category"], fields["categorypath"] = models.CategoryPath(category)
+	}
+	if tags, ok := fields["tags"].([]string); ok {
+		fields["tags"] = models.NormalizeTags(tags)
+	}
+
+	if authorIDs, ok := fields["authorids"].([]string); ok {
+		if _, err := b.knownAuthors(ctx, authorIDs); err != nil {
+			return before, err
+		}
+	}
+
+	result, err := b.Repository.UpdateFields(ctx, id, version, fields)
+
+	if err != nil {
+		return result, bookError(err, id)
+	}
+
+	writeAudit(ctx, b.AuditRepository, entityBook, id, models.AuditPatch, diff(&before, &result))
+
+	if result.Quantity > before.Quantity {
+		promoteHolds(ctx, b.HoldService, id)
+	}
+
+	return result, nil
+}
+
+// Delete => ErrBookNotFound if the book doesn't exist or it is already in trash
+func (b BookService) Delete(ctx context.Context, id string) (bool, error) {
+	result, err := b.Repository.Delete(ctx, id)
+
+	if err != nil {
 		return false, err
 	}
+	if result == false {
+		return false, ErrBookNotFound.Withf("{%v} with id book is not found", id)
+	}
+
+	writeAudit(ctx, b.AuditRepository, entityBook, id, models.AuditDelete, nil)
 
 	return true, nil
 }
 
-func (b BookService) Delete(id string) (bool, error) {
-	result, err := b.Repository.Delete(id)
+// GetTrash => deleted books which are not purged yet
+func (b BookService) GetTrash(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error) {
+	query.Deleted = true
 
-	if err != nil || result == false {
+	result, total, err := b.Repository.GetAll(ctx, query)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// Restore => ErrBookNotFound if the book is not in trash
+func (b BookService) Restore(ctx context.Context, id string) (bool, error) {
+	result, err := b.Repository.Restore(ctx, id)
+
+	if err != nil {
 		return false, err
 	}
+	if result == false {
+		return false, ErrBookNotFound.Withf("{%v} with id book is not found in trash", id)
+	}
+
+	writeAudit(ctx, b.AuditRepository, entityBook, id, models.AuditRestore, nil)
 
 	return true, nil
 }
+
+// PurgeTrash => to delete permanently the books which have been in trash longer than retention, it returns the count
+func (b BookService) PurgeTrash(ctx context.Context) (int64, error) {
+	ids, err := b.Repository.Purge(ctx, time.Now().Add(-b.TrashRetention))
+
+	for _, id := range ids {
+		writeAudit(ctx, b.AuditRepository, entityBook, id, models.AuditPurge, nil)
+	}
+
+	return int64(len(ids)), err
+}
+
+// GetAuthors => authors of the books by id, to expand book responses
+func (b BookService) GetAuthors(ctx context.Context, books []models.Book) (map[string]models.Author, error) {
+	var ids []string
+	for _, book := range books {
+		ids = append(ids, book.AuthorIDs...)
+	}
+
+	return b.getAuthors(ctx, ids)
+}
+
+// resolveAuthors => author ids of a book must exist, author names are used as the display name if it is empty
+func (b BookService) resolveAuthors(ctx context.Context, book *models.Book) error {
+	if len(book.AuthorIDs) == 0 {
+		return nil
+	}
+
+	authors, err := b.getAuthors(ctx, book.AuthorIDs)
+
+	if err != nil {
+		return err
+	}
+
+	return setAuthorNames(book, authors)
+}
+
+// setAuthorNames => author ids of a book must be in authors (ErrUnknownAuthor), their names are used as the display name if it is empty
+func setAuthorNames(book *models.Book, authors map[string]models.Author) error {
+	for _, id := range book.AuthorIDs {
+		if _, ok := authors[id]; !ok {
+			return ErrUnknownAuthor.Withf("{%v} with id author is unknown", id)
+		}
+	}
+
+	if book.Author == "" {
+		names := make([]string, 0, len(book.AuthorIDs))
+		for _, id := range book.AuthorIDs {
+			names = append(names, authors[id].Name)
+		}
+		book.Author = strings.Join(names, ", ")
+	}
+
+	return nil
+}
+
+// knownAuthors => like getAuthors, but ErrUnknownAuthor is returned if one of the ids doesn't exist
+func (b BookService) knownAuthors(ctx context.Context, ids []string) (map[string]models.Author, error) {
+	authors, err := b.getAuthors(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if _, ok := authors[id]; !ok {
+			return nil, ErrUnknownAuthor.Withf("{%v} with id author is unknown", id)
+		}
+	}
+
+	return authors, nil
+}
+
+// getAuthors => authors by id, unknown ids are skipped
+func (b BookService) getAuthors(ctx context.Context, ids []string) (map[string]models.Author, error) {
+	authors := map[string]models.Author{}
+
+	if len(ids) == 0 {
+		return authors, nil
+	}
+
+	result, err := b.AuthorRepository.GetByIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, author := range result {
+		authors[author.ID] = author
+	}
+
+	return authors, nil
+}
+
+// normalizeISBN => hyphens are removed and the missing form is derived from the given one,
+// ErrISBNMismatch if both are given but they are not the same ISBN
+func normalizeISBN(isbn10 string, isbn13 string) (string, string, error) {
+	isbn10, isbn13 = isbn.Normalize(isbn10), isbn.Normalize(isbn13)
+
+	if isbn10 == "" && isbn13 == "" {
+		return "", "", nil
+	}
+
+	if isbn10 != "" {
+		derived, err := isbn.To13(isbn10)
+		if err != nil {
+			return "", "", err
+		}
+		if isbn13 != "" && isbn13 != derived {
+			return "", "", ErrISBNMismatch
+		}
+		return isbn10, derived, nil
+	}
+
+	derived, err := isbn.To10(isbn13)
+	if err == isbn.ErrNotConvertible {
+		// 979 prefix => there is no ISBN-10
+		return "", isbn13, nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return derived, isbn13, nil
+}
+
+// bookError => errors of the book repository & of isbn as domain errors, the others are internal errors
+func bookError(err error, id string) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return ErrBookNotFound.Withf("{%v} with id book is not found", id)
+	case isbn.ErrInvalid:
+		return ErrInvalidISBN
+	case repository.ErrDuplicateKey:
+		return ErrDuplicateISBN
+	case repository.ErrVersionConflict:
+		return ErrConcurrentChange.Withf("{%v} with id book is changed by somebody else, try again", id)
+	case repository.ErrInsufficientStock:
+		return ErrHeldCopies.Withf("{%v} with id book has more held copies than the quantity", id)
+	}
+
+	return err
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// ErrInvalidCursor => cursor cannot be decoded, clients have to use the next_cursor value they got as it is
+var ErrInvalidCursor = errors.New(errors.Invalid, "invalid_cursor", "invalid cursor")
+
+// encodeCursor => cursor is opaque for clients => base64(json)
+func encodeCursor(cursor models.BookCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (models.BookCursor, error) {
+	var cursor models.BookCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"context"
+	"encoding/base64"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []models.BookCursor{
+		{CreatedDate: primitive.NewDateTimeFromTime(time.Date(2024, 5, 6, 7, 8, 9, 123e6, time.UTC)), ID: "0b6c8f5e-1d2a-4b7e-9c3f-5a6d7e8f9a0b"},
+		{ID: "only-id"},
+		{CreatedDate: primitive.NewDateTimeFromTime(time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC)), ID: `"quoted" / ünicode`},
+	}
+
+	for _, cursor := range cursors {
+		encoded := encodeCursor(cursor)
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("%+v: cursor is not url safe: %v", cursor, encoded)
+		}
+
+		decoded, err := decodeCursor(encoded)
+		if err != nil || decoded != cursor {
+			t.Errorf("expected %+v, got %+v (%v)", cursor, decoded, err)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsInvalidCursors(t *testing.T) {
+	valid := encodeCursor(models.BookCursor{CreatedDate: primitive.NewDateTimeFromTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), ID: "book-1"})
+	encode := func(text string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(text))
+	}
+
+	tests := map[string]string{
+		"garbage":              "not a cursor!",
+		"padded":               valid + "==",
+		"standard base64":      base64.StdEncoding.EncodeToString([]byte(`{"c":"2024-01-01T00:00:00Z","i":"b+/"}`)),
+		"truncated":            valid[:len(valid)-3],
+		"tampered byte":        valid[:5] + string(valid[5]^1) + valid[6:],
+		"not json":             encode("book-1"),
+		"json array":           encode(`["2024-01-01T00:00:00Z","book-1"]`),
+		"null":                 encode("null"),
+		"without id":           encode(`{"c":"2024-01-01T00:00:00Z"}`),
+		"empty id":             encode(`{"c":"2024-01-01T00:00:00Z","i":""}`),
+		"id is not a string":   encode(`{"c":"2024-01-01T00:00:00Z","i":1}`),
+		"date is not a date":   encode(`{"c":"yesterday","i":"book-1"}`),
+		"date is not a string": encode(`{"c":{"$date":1},"i":"book-1"}`),
+		"trailing data":        encode(`{"c":"2024-01-01T00:00:00Z","i":"book-1"}}`),
+		"unterminated json":    encode(`{"c":"2024-01-01T00:00:00Z","i":"book-1"`),
+		"control characters":   "\x00\x01\x02",
+		"very long":            strings.Repeat("A", 1<<16),
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			cursor, err := decodeCursor(value)
+
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("expected %v, got %+v (%v)", ErrInvalidCursor, cursor, err)
+			}
+			if e, ok := errors.As(err); !ok || e.Kind.Status() != http.StatusBadRequest {
+				t.Fatalf("invalid cursor must be a bad request: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetAllByCursorRejectsInvalidCursor(t *testing.T) {
+	books := BookService{Repository: &fakeBookRepository{books: map[string]models.Book{}}}
+
+	_, _, err := books.GetAllByCursor(context.Background(), models.BookQuery{Limit: 10}, "garbage!")
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected %v, got %v", ErrInvalidCursor, err)
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// searchTerms => words of a text search query, negated terms (-word) are not highlighted
+func searchTerms(text string) []string {
+	var terms []string
+
+	for _, word := range strings.Fields(strings.ReplaceAll(text, `"`, " ")) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		terms = append(terms, regexp.QuoteMeta(word))
+	}
+
+	return terms
+}
+
+// highlight => to wrap the words starting with a search term in <em></em>, e.g. "tolkien" => "J.R.R. <em>Tolkien</em>"
+// => mongodb uses stemming, so "book" finds "books" too; matching by word prefix is close enough for highlighting
+func highlight(text string, terms []string) string {
+	escaped := html.EscapeString(text)
+
+	if len(terms) == 0 {
+		return escaped
+	}
+
+	pattern := regexp.MustCompile(`(?i)\b(` + strings.Join(terms, "|") + `)\w*`)
+
+	return pattern.ReplaceAllString(escaped, "<em>$0</em>")
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ErrBookAvailable => a hold can be placed only when the book has no available copies
+var ErrBookAvailable = errors.New(errors.Conflict, "book_available", "book has available copies")
+
+// ErrHoldNotFound => hold doesn't exist
+var ErrHoldNotFound = errors.New(errors.NotFound, "hold_not_found", "hold is not found")
+
+// ErrHoldNotActive => hold is already fulfilled, cancelled or expired
+var ErrHoldNotActive = errors.New(errors.Conflict, "hold_not_active", "hold is not active")
+
+// ErrDuplicateHold => member is already waiting for the book
+var ErrDuplicateHold = errors.New(errors.Conflict, "duplicate_hold", "member is already waiting for the book")
+
+type HoldService struct {
+	Repository          repository.IHoldRepository
+	MemberRepository    repository.IMemberRepository
+	BookRepository      repository.IBookRepository
+	InventoryRepository repository.IInventoryRepository
+	// a promoted hold keeps its copy this long
+	PickupWindow time.Duration
+}
+
+var singleInstanceHoldService *HoldService
+
+func GetSingleInstancesHoldService(repository repository.IHoldRepository, memberRepository repository.IMemberRepository,
+	bookRepository repository.IBookRepository, inventoryRepository repository.IInventoryRepository, pickupWindow time.Duration) *HoldService {
+	if singleInstanceHoldService == nil {
+		fmt.Println("Creating single hold service instance now.")
+		singleInstanceHoldService = &HoldService{Repository: repository, MemberRepository: memberRepository,
+			BookRepository: bookRepository, InventoryRepository: inventoryRepository, PickupWindow: pickupWindow}
+	} else {
+		fmt.Println("Single hold service instance already created.")
+	}
+
+	return singleInstanceHoldService
+}
+
+type IHoldService interface {
+	Place(ctx context.Context, bookID string, memberID string) (models.Hold, int64, error)
+	GetById(ctx context.Context, id string) (models.Hold, int64, error)
+	GetQueue(ctx context.Context, bookID string, skip int64, limit int64) ([]models.Hold, int64, error)
+	Cancel(ctx context.Context, id string) (models.Hold, error)
+	TakeReady(ctx context.Context, bookID string, memberID string) (models.Hold, error)
+	Restore(ctx context.Context, hold models.Hold)
+	Promote(ctx context.Context, bookID string) (int, error)
+	ReapExpired(ctx context.Context) (int, error)
+}
+
+// Place => member joins the end of the hold queue of an out of stock book, it returns the position too
+func (h HoldService) Place(ctx context.Context, bookID string, memberID string) (models.Hold, int64, error) {
+	var hold models.Hold
+
+	if _, err := h.MemberRepository.GetById(ctx, memberID); err != nil {
+		return hold, 0, memberError(err, memberID)
+	}
+
+	book, err := h.BookRepository.GetBookById(ctx, bookID)
+
+	if err != nil {
+		return hold, 0, bookError(err, bookID)
+	}
+
+	if book.Quantity-book.Reserved > 0 {
+		return hold, 0, ErrBookAvailable.Withf("{%v} with id book has available copies, it can be checked out", bookID)
+	}
+
+	hold = models.Hold{
+		ID:          uuid.New().String(),
+		BookID:      bookID,
+		MemberID:    memberID,
+		Status:      models.HoldWaiting,
+		Active:      true,
+		CreatedDate: primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	if err := h.Repository.Insert(ctx, hold); err != nil {
+		if err == repository.ErrDuplicateKey {
+			return hold, 0, ErrDuplicateHold.Withf("{%v} with id member is already waiting for {%v} with id book", memberID, bookID)
+		}
+		return hold, 0, err
+	}
+
+	// a copy can be given back between the check and the insert, nobody would promote the hold then
+	promoteHolds(ctx, h, bookID)
+
+	return h.GetById(ctx, hold.ID)
+}
+
+// GetById => hold with its position in queue (zero if it is not waiting)
+func (h HoldService) GetById(ctx context.Context, id string) (models.Hold, int64, error) {
+	hold, err := h.Repository.GetById(ctx, id)
+
+	if err != nil {
+		return hold, 0, holdError(err, id)
+	}
+
+	position, err := h.Repository.Position(ctx, hold)
+
+	if err != nil {
+		return hold, 0, err
+	}
+
+	return hold, position, nil
+}
+
+// GetQueue => active holds of a book, ready ones first and then the waiting ones in FIFO order
+func (h HoldService) GetQueue(ctx context.Context, bookID string, skip int64, limit int64) ([]models.Hold, int64, error) {
+	result, total, err := h.Repository.GetQueue(ctx, bookID, skip, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// Cancel => member leaves the queue, copy of a ready hold goes to the next member
+func (h HoldService) Cancel(ctx context.Context, id string) (models.Hold, error) {
+	hold, err := h.finishAndRelease(ctx, id, []string{models.HoldWaiting, models.HoldReady}, models.HoldCancelled)
+
+	return hold, holdError(err, id)
+}
+
+// TakeReady => ready hold of the member is fulfilled, its copy is still reserved and it is consumed by the checkout
+// => mongo.ErrNoDocuments is returned if the member has no ready hold for the book
+func (h HoldService) TakeReady(ctx context.Context, bookID string, memberID string) (models.Hold, error) {
+	hold, err := h.Repository.GetReady(ctx, bookID, memberID)
+
+	if err != nil {
+		return hold, err
+	}
+
+	now := time.Now()
+
+	return h.Repository.Finish(ctx, hold.ID, []string{models.HoldReady}, models.HoldFulfilled, &now)
+}
+
+// Restore => fulfilled hold is ready again, e.g. the checkout which took its copy fails
+func (h HoldService) Restore(ctx context.Context, hold models.Hold) {
+	h.reopen(ctx, hold, models.HoldFulfilled, models.HoldReady)
+}
+
+// Promote => available copies of a book are given to the first waiting holds, it returns the count of promoted holds
+// => a copy is reserved before a hold is promoted, both steps are atomic, so concurrent promotions and updates
+// cannot give the same copy twice
+func (h HoldService) Promote(ctx context.Context, bookID string) (int, error) {
+	promoted := 0
+
+	for {
+		// most books have no queue, their version is not changed for nothing
+		waiting, err := h.Repository.HasWaiting(ctx, bookID)
+
+		if err != nil || !waiting {
+			return promoted, err
+		}
+
+		if _, err := h.InventoryRepository.Reserve(ctx, bookID, 1); err != nil {
+			if err == repository.ErrInsufficientStock || err == mongo.ErrNoDocuments {
+				return promoted, nil
+			}
+			return promoted, err
+		}
+
+		now := time.Now()
+		if _, err := h.Repository.PromoteFirst(ctx, bookID, now, now.Add(h.PickupWindow)); err != nil {
+			// queue is emptied meanwhile => copy is available again
+			if _, unreserveErr := h.InventoryRepository.Unreserve(ctx, bookID, 1); unreserveErr != nil {
+				logrus.Errorf("Copy of {%v} cannot be given back: %v", bookID, unreserveErr)
+			}
+			if err == mongo.ErrNoDocuments {
+				return promoted, nil
+			}
+			return promoted, err
+		}
+
+		promoted++
+	}
+}
+
+// ReapExpired => to expire ready holds which are not picked up in time and to promote the waiting holds
+// of books whose copies are available again, e.g. after a reservation is released
+func (h HoldService) ReapExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	reaped, err := reapBatches("hold",
+		func(after *repository.ExpiryCursor) ([]models.Hold, error) {
+			return h.Repository.GetExpired(ctx, now, after, reaperBatchSize)
+		},
+		func(hold models.Hold) repository.ExpiryCursor {
+			return repository.ExpiryCursor{Date: *hold.PickupUntil, ID: hold.ID}
+		},
+		func(hold models.Hold) (bool, error) {
+			_, err := h.finishAndRelease(inTenant(ctx, hold.TenantID), hold.ID, []string{models.HoldReady}, models.HoldExpired)
+			// picked up or cancelled meanwhile
+			if err == repository.ErrHoldNotActive {
+				return false, nil
+			}
+			return err == nil, err
+		})
+
+	if err != nil {
+		return reaped, err
+	}
+
+	bookIDs, err := h.Repository.GetWaitingBooks(ctx)
+
+	if err != nil {
+		return reaped, err
+	}
+
+	for _, bookID := range bookIDs {
+		promoteHolds(ctx, h, bookID)
+	}
+
+	return reaped, nil
+}
+
+// RunReaper => to reap expired holds periodically until ctx is done
+func (h HoldService) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if count, err := h.ReapExpired(ctx); err != nil {
+				logrus.Errorf("Expired holds cannot be reaped: %v", err)
+			} else if count > 0 {
+				logrus.Infof("%d expired holds are reaped.", count)
+			}
+		}
+	}
+}
+
+func (h HoldService) finishAndRelease(ctx context.Context, id string, from []string, status string) (models.Hold, error) {
+	hold, err := h.Repository.Finish(ctx, id, from, status, nil)
+
+	if err != nil {
+		return hold, err
+	}
+
+	// only a ready hold was holding a copy
+	if hold.ReadyDate == nil {
+		return hold, nil
+	}
+
+	// copy is in the tenant of the hold
+	ctx = inTenant(ctx, hold.TenantID)
+	if _, err := h.InventoryRepository.Unreserve(ctx, hold.BookID, 1); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// book is purged, there is no copy to give back
+			logrus.Warnf("{%v} with id book of {%v} with id hold doesn't exist anymore", hold.BookID, id)
+			return hold, nil
+		}
+		// copy is still held => hold must stay ready, so it is given back when the pickup window is over
+		h.reopen(ctx, hold, status, models.HoldReady)
+		return hold, err
+	}
+
+	promoteHolds(ctx, h, hold.BookID)
+
+	return hold, nil
+}
+
+// reopen => compensation of Finish when the copy of the hold cannot be handled
+func (h HoldService) reopen(ctx context.Context, hold models.Hold, status string, to string) {
+	if err := h.Repository.Reopen(ctx, hold.ID, status, to); err != nil {
+		logrus.Errorf("{%v} with id hold cannot be reopened, copy of {%v} stays held: %v", hold.ID, hold.BookID, err)
+	}
+}
+
+// promoteHolds => to promote waiting holds after copies of a book are available again
+// => the change is already done, so a failure is logged instead of being returned, reaper tries again later
+func promoteHolds(ctx context.Context, holdService IHoldService, bookID string) {
+	if holdService == nil {
+		return
+	}
+
+	if _, err := holdService.Promote(ctx, bookID); err != nil {
+		logrus.Errorf("Holds of {%v} cannot be promoted: %v", bookID, err)
+	}
+}
+
+// holdError => errors of the hold repository as domain errors
+func holdError(err error, id string) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return ErrHoldNotFound.Withf("{%v} with id hold is not found", id)
+	case repository.ErrHoldNotActive:
+		return ErrHoldNotActive.Withf("{%v} with id hold is not active", id)
+	}
+
+	return err
+}
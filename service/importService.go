@@ -0,0 +1,256 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+// importBatchSize => rows are written with one bulk write per batch, progress of the job is saved after every batch
+const importBatchSize = 1000
+
+// maxImportErrors => error report of a job keeps at most this many rows, Failed is the count of all of them
+const maxImportErrors = 10000
+
+// ErrImportNotFound => import job doesn't exist
+var ErrImportNotFound = errors.New(errors.NotFound, "import_not_found", "import is not found")
+
+type ImportService struct {
+	Repository repository.IImportRepository
+	// rows are matched with the existing books for upserts
+	BookRepository repository.IBookRepository
+	// rows are written with the bulk operations of books, so they are checked and audited like them
+	BookService IBookService
+}
+
+var singleInstanceImportService *ImportService
+
+func GetSingleInstancesImportService(repository repository.IImportRepository, bookRepository repository.IBookRepository,
+	bookService IBookService) *ImportService {
+	if singleInstanceImportService == nil {
+		fmt.Println("Creating single import service instance now.")
+		singleInstanceImportService = &ImportService{Repository: repository, BookRepository: bookRepository, BookService: bookService}
+	} else {
+		fmt.Println("Single import service instance already created.")
+	}
+
+	return singleInstanceImportService
+}
+
+type IImportService interface {
+	Start(ctx context.Context, job models.ImportJob, rows []models.ImportRow, background bool) (models.ImportJob, error)
+	GetById(ctx context.Context, id string) (models.ImportJob, error)
+	FailInterrupted(ctx context.Context) (int64, error)
+}
+
+// Start => to create an import job for the parsed rows and run it
+// => background jobs return immediately, their progress can be polled with GetById; the others return when they finish
+func (s ImportService) Start(ctx context.Context, job models.ImportJob, rows []models.ImportRow, background bool) (models.ImportJob, error) {
+	info := requestinfo.From(ctx)
+
+	job.ID = uuid.New().String()
+	job.Status = models.ImportRunning
+	job.Actor = info.Actor
+	job.Total = len(rows)
+	job.CreatedDate = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := s.Repository.Insert(ctx, job); err != nil {
+		return job, err
+	}
+
+	if background {
+		// request is finished before the job, actor & request id are kept for audit records
+		go s.run(requestinfo.With(context.Background(), info), job, rows)
+		return job, nil
+	}
+
+	return s.run(ctx, job, rows), nil
+}
+
+func (s ImportService) GetById(ctx context.Context, id string) (models.ImportJob, error) {
+	result, err := s.Repository.GetById(ctx, id)
+
+	if err == mongo.ErrNoDocuments {
+		return result, ErrImportNotFound.Withf("{%v} with id import is not found", id)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// FailInterrupted => jobs which were running when the app stopped cannot be continued, their rows are not kept
+func (s ImportService) FailInterrupted(ctx context.Context) (int64, error) {
+	return s.Repository.FailRunning(ctx, "import is interrupted, the app is restarted")
+}
+
+// run => rows are written batch by batch, a failed batch stops the job (rows of the previous batches stay written)
+func (s ImportService) run(ctx context.Context, job models.ImportJob, rows []models.ImportRow) models.ImportJob {
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		if err := s.runBatch(ctx, &job, rows[start:end]); err != nil {
+			job.Status = models.ImportFailed
+			job.Error = err.Error()
+			break
+		}
+
+		job.Processed = end
+		if end < len(rows) {
+			s.save(ctx, job)
+		}
+	}
+
+	if job.Status == models.ImportRunning {
+		job.Status = models.ImportDone
+	}
+	finishedDate := primitive.NewDateTimeFromTime(time.Now())
+	job.FinishedDate = &finishedDate
+
+	s.save(ctx, job)
+
+	logrus.Infof("Import {%v} is %v: %v created, %v updated, %v failed of %v rows.",
+		job.ID, job.Status, job.Created, job.Updated, job.Failed, job.Total)
+
+	return job
+}
+
+// runBatch => invalid rows are reported, the valid ones are checked (dry run), upserted or created with one bulk write
+func (s ImportService) runBatch(ctx context.Context, job *models.ImportJob, rows []models.ImportRow) error {
+	var books []models.Book
+	var lines []int
+
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			addImportError(job, row.Line, strings.Join(row.Errors, "; "))
+			continue
+		}
+		books = append(books, row.Book)
+		lines = append(lines, row.Line)
+	}
+
+	if len(books) == 0 {
+		return nil
+	}
+
+	matched := map[int]bool{}
+	if job.Upsert {
+		var err error
+		if matched, err = s.match(ctx, books); err != nil {
+			return err
+		}
+	}
+
+	var results []models.BulkResult
+	var err error
+	switch {
+	case job.DryRun:
+		results, err = s.BookService.BulkCheck(ctx, books)
+	case job.Upsert:
+		results, err = s.BookService.BulkUpsert(ctx, books)
+	default:
+		results, err = s.BookService.BulkInsert(ctx, books)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			addImportError(job, lines[result.Index], result.Err.Error())
+			continue
+		}
+
+		updated := !result.Created
+		if job.DryRun {
+			updated = matched[result.Index]
+		}
+		if updated {
+			job.Updated++
+		} else {
+			job.Created++
+		}
+	}
+
+	return nil
+}
+
+// match => books of an upsert get the ids of the existing books with the same ISBN-13,
+// or with the same title & author if they don't have an ISBN, the others get new ids
+// => it returns the indexes of the books which are matched
+func (s ImportService) match(ctx context.Context, books []models.Book) (map[int]bool, error) {
+	var isbn13s []string
+	var titleAuthors []models.TitleAuthor
+
+	keys := make([]string, len(books))
+	for i, book := range books {
+		// invalid ISBNs are reported by the bulk write
+		if _, isbn13, err := normalizeISBN(book.ISBN10, book.ISBN13); err == nil && isbn13 != "" {
+			keys[i] = isbn13
+			isbn13s = append(isbn13s, isbn13)
+		} else if err == nil {
+			titleAuthors = append(titleAuthors, models.TitleAuthor{Title: book.Title, Author: book.Author})
+		}
+	}
+
+	existing, err := s.BookRepository.GetBooksByKeys(ctx, isbn13s, titleAuthors)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byISBN := map[string]string{}
+	byTitleAuthor := map[models.TitleAuthor]string{}
+	for _, book := range existing {
+		if book.ISBN13 != "" {
+			byISBN[book.ISBN13] = book.ID
+		}
+		byTitleAuthor[models.TitleAuthor{Title: book.Title, Author: book.Author}] = book.ID
+	}
+
+	matched := map[int]bool{}
+	for i := range books {
+		id := byISBN[keys[i]]
+		if keys[i] == "" {
+			id = byTitleAuthor[models.TitleAuthor{Title: books[i].Title, Author: books[i].Author}]
+		}
+
+		if id == "" {
+			books[i].ID = uuid.New().String()
+			continue
+		}
+		books[i].ID = id
+		matched[i] = true
+	}
+
+	return matched, nil
+}
+
+// save => progress of a job is best effort, the rows are already written, so a failure is logged
+func (s ImportService) save(ctx context.Context, job models.ImportJob) {
+	if err := s.Repository.Update(ctx, job); err != nil {
+		logrus.Errorf("Import {%v} cannot be saved: %v", job.ID, err)
+	}
+}
+
+// addImportError => failed count has all the errors, the report has at most maxImportErrors of them
+func addImportError(job *models.ImportJob, line int, message string) {
+	job.Failed++
+	if len(job.Errors) < maxImportErrors {
+		job.Errors = append(job.Errors, models.ImportError{Line: line, Message: message})
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeImportRepository => import jobs in memory, every saved state of a job is kept
+type fakeImportRepository struct {
+	mu    sync.Mutex
+	saved []models.ImportJob
+}
+
+func (f *fakeImportRepository) Insert(ctx context.Context, job models.ImportJob) error {
+	return f.Update(ctx, job)
+}
+
+func (f *fakeImportRepository) GetById(ctx context.Context, id string) (models.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := len(f.saved) - 1; i >= 0; i-- {
+		if f.saved[i].ID == id {
+			return f.saved[i], nil
+		}
+	}
+	return models.ImportJob{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeImportRepository) Update(ctx context.Context, job models.ImportJob) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.saved = append(f.saved, job)
+	return nil
+}
+
+func (f *fakeImportRepository) FailRunning(ctx context.Context, message string) (int64, error) {
+	return 0, nil
+}
+
+// fakeBookRepository => books in memory for the bulk writes, ISBN-13s are unique like the index of the collection
+// => the other methods of the repository are not used by imports
+type fakeBookRepository struct {
+	repository.IBookRepository
+	books map[string]models.Book
+	// ids of the books of other tenants
+	foreign map[string]bool
+	err     error
+}
+
+func (f *fakeBookRepository) owner(book models.Book) string {
+	for id, stored := range f.books {
+		if book.ISBN13 != "" && stored.ISBN13 == book.ISBN13 {
+			return id
+		}
+	}
+	return ""
+}
+
+func (f *fakeBookRepository) BulkInsert(ctx context.Context, books []models.Book) (map[int]error, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	failed := map[int]error{}
+	for i, book := range books {
+		if f.owner(book) != "" {
+			failed[i] = repository.ErrDuplicateKey
+			continue
+		}
+		f.books[book.ID] = book
+	}
+	return failed, nil
+}
+
+func (f *fakeBookRepository) BulkUpsert(ctx context.Context, books []models.Book) (map[int]bool, map[int]error, error) {
+	created, failed := map[int]bool{}, map[int]error{}
+	for i, book := range books {
+		if f.foreign[book.ID] {
+			failed[i] = mongo.ErrNoDocuments
+			continue
+		}
+		if owner := f.owner(book); owner != "" && owner != book.ID {
+			failed[i] = repository.ErrDuplicateKey
+			continue
+		}
+		_, exists := f.books[book.ID]
+		created[i] = !exists
+		f.books[book.ID] = book
+	}
+	return created, failed, nil
+}
+
+func (f *fakeBookRepository) GetBooksByIds(ctx context.Context, ids []string) ([]models.Book, error) {
+	var books []models.Book
+	for _, id := range ids {
+		if book, ok := f.books[id]; ok {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
+
+func (f *fakeBookRepository) GetBooksByKeys(ctx context.Context, isbn13s []string, titleAuthors []models.TitleAuthor) ([]models.Book, error) {
+	var books []models.Book
+	for _, book := range f.books {
+		for _, isbn13 := range isbn13s {
+			if book.ISBN13 == isbn13 {
+				books = append(books, book)
+			}
+		}
+		for _, titleAuthor := range titleAuthors {
+			if book.ISBN13 == "" && book.Title == titleAuthor.Title && book.Author == titleAuthor.Author {
+				books = append(books, book)
+			}
+		}
+	}
+	return books, nil
+}
+
+// newTestImportService => import service with the real book service over in memory books
+func newTestImportService(stored ...models.Book) (ImportService, *fakeImportRepository, *fakeBookRepository) {
+	books := &fakeBookRepository{books: map[string]models.Book{}}
+	for _, book := range stored {
+		books.books[book.ID] = book
+	}
+	jobs := &fakeImportRepository{}
+
+	return ImportService{Repository: jobs, BookRepository: books, BookService: BookService{Repository: books}}, jobs, books
+}
+
+func TestImportReportsFailedRows(t *testing.T) {
+	existing := models.Book{ID: "existing", Title: "Emma", Author: "Jane Austen", ISBN10: "0141439580", ISBN13: "9780141439587"}
+	dune := models.Book{Title: "Dune", Author: "Frank Herbert", ISBN13: "978-0-441-01359-3", Quantity: 2}
+
+	rows := []models.ImportRow{
+		{Line: 2, Book: dune},
+		{Line: 3, Errors: []string{"title is required", "quantity must be 0 or greater"}},
+		// same ISBN as line 2 in the same file
+		{Line: 4, Book: dune},
+		{Line: 5, Book: models.Book{Title: "Untitled", Author: "Nobody"}},
+		{Line: 6, Book: models.Book{Title: "Emma", Author: "Jane Austen", ISBN13: "978-0-14-143958-7"}},
+		{Line: 7, Book: models.Book{Title: "Mixed", Author: "Nobody", ISBN10: "0306406152", ISBN13: "9780441013593"}},
+	}
+
+	tests := []struct {
+		name    string
+		job     models.ImportJob
+		created int
+		updated int
+		errors  []models.ImportError
+		stored  int
+	}{
+		{name: "insert", created: 2, stored: 3,
+			errors: []models.ImportError{{Line: 3, Message: "title is required; quantity must be 0 or greater"},
+				{Line: 4, Message: ErrDuplicateISBN.Error()}, {Line: 6, Message: ErrDuplicateISBN.Error()},
+				{Line: 7, Message: ErrISBNMismatch.Error()}}},
+		// rows of an existing book are updated, a new book is created by the first of its rows only
+		{name: "upsert", job: models.ImportJob{Upsert: true}, created: 2, updated: 1, stored: 3,
+			errors: []models.ImportError{{Line: 3, Message: "title is required; quantity must be 0 or greater"},
+				{Line: 4, Message: ErrUpsertConflict.Message}, {Line: 7, Message: ErrISBNMismatch.Error()}}},
+		// unique ISBNs are checked by the database, so a dry run doesn't find the duplicates
+		{name: "dry run", job: models.ImportJob{DryRun: true, Upsert: true}, created: 3, updated: 1, stored: 1,
+			errors: []models.ImportError{{Line: 3, Message: "title is required; quantity must be 0 or greater"},
+				{Line: 7, Message: ErrISBNMismatch.Error()}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			imports, jobs, books := newTestImportService(existing)
+
+			input := make([]models.ImportRow, len(rows))
+			copy(input, rows)
+			job, err := imports.Start(context.Background(), test.job, input, false)
+
+			if err != nil {
+				t.Fatalf("import cannot be started: %v", err)
+			}
+			if job.Status != models.ImportDone || job.Total != len(rows) || job.Processed != len(rows) || job.FinishedDate == nil {
+				t.Fatalf("import is not done: %+v", job)
+			}
+			if job.Created != test.created || job.Updated != test.updated || job.Failed != len(test.errors) {
+				t.Fatalf("expected %d created, %d updated, %d failed, got %+v", test.created, test.updated, len(test.errors), job)
+			}
+
+			// messages of upsert conflicts start with the id of the book, only their ends are compared
+			if len(job.Errors) != len(test.errors) {
+				t.Fatalf("expected %+v, got %+v", test.errors, job.Errors)
+			}
+			for i, expected := range test.errors {
+				if got := job.Errors[i]; got.Line != expected.Line || !strings.HasSuffix(got.Message, expected.Message) {
+					t.Fatalf("expected %+v, got %+v", test.errors, job.Errors)
+				}
+			}
+
+			if len(books.books) != test.stored {
+				t.Fatalf("expected %d stored books, got %d", test.stored, len(books.books))
+			}
+			if saved, _ := jobs.GetById(context.Background(), job.ID); !reflect.DeepEqual(saved, job) {
+				t.Fatalf("final state of the job is not saved: %+v", saved)
+			}
+		})
+	}
+}
+
+func TestImportFailsJobIfWriteFails(t *testing.T) {
+	imports, _, books := newTestImportService()
+	books.err = errors.New(errors.Internal, "write_failed", "books cannot be written")
+
+	job, err := imports.Start(context.Background(), models.ImportJob{},
+		[]models.ImportRow{{Line: 2, Book: models.Book{Title: "Dune", Author: "Frank Herbert"}}}, false)
+
+	if err != nil {
+		t.Fatalf("import cannot be started: %v", err)
+	}
+	if job.Status != models.ImportFailed || job.Error == "" || job.Processed != 0 || job.Created != 0 {
+		t.Fatalf("import must fail: %+v", job)
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidMovement => reason cannot be used with the movement type, e.g. a sale cannot increment
+var ErrInvalidMovement = errors.New(errors.Invalid, "invalid_stock_movement", "invalid stock movement")
+
+// ErrInsufficientStock => book doesn't have enough copies which are not held for reservations & holds
+var ErrInsufficientStock = errors.New(errors.Conflict, "insufficient_stock", "book doesn't have enough available copies")
+
+// movementReasons => which movement types a reason can be used with
+var movementReasons = map[string][]string{
+	models.ReasonRestock:    {models.StockIncrement},
+	models.ReasonSale:       {models.StockDecrement},
+	models.ReasonDamage:     {models.StockDecrement},
+	models.ReasonAdjustment: {models.StockIncrement, models.StockDecrement},
+}
+
+type InventoryService struct {
+	Repository      repository.IInventoryRepository
+	BookRepository  repository.IBookRepository
+	AuditRepository repository.IAuditRepository
+	// waiting holds are promoted after an increment
+	HoldService IHoldService
+	// books with quantity less than or equal to this are low in stock
+	LowStockThreshold int
+}
+
+var singleInstanceInventoryService *InventoryService
+
+func GetSingleInstancesInventoryService(repository repository.IInventoryRepository, bookRepository repository.IBookRepository,
+	auditRepository repository.IAuditRepository, holdService IHoldService, lowStockThreshold int) *InventoryService {
+	if singleInstanceInventoryService == nil {
+		fmt.Println("Creating single inventory service instance now.")
+		singleInstanceInventoryService = &InventoryService{Repository: repository, BookRepository: bookRepository,
+			AuditRepository: auditRepository, HoldService: holdService, LowStockThreshold: lowStockThreshold}
+	} else {
+		fmt.Println("Single inventory service instance already created.")
+	}
+
+	return singleInstanceInventoryService
+}
+
+type IInventoryService interface {
+	Move(ctx context.Context, bookID string, movementType string, reason string, quantity int, note string) (models.StockMovement, error)
+	GetMovements(ctx context.Context, bookID string, skip int64, limit int64) ([]models.StockMovement, int64, error)
+	LowStock(ctx context.Context, threshold *int, skip int64, limit int64) ([]models.Book, int64, error)
+}
+
+// Move => to increment or decrement the quantity of a book and to write the movement into the ledger
+// => quantity is changed back if the movement cannot be written, so every stock change has its ledger entry
+func (i InventoryService) Move(ctx context.Context, bookID string, movementType string, reason string, quantity int, note string) (models.StockMovement, error) {
+	var movement models.StockMovement
+
+	if !allowedMovement(movementType, reason) || quantity < 1 {
+		return movement, ErrInvalidMovement.Withf("{%v} reason cannot be used with {%v}", reason, movementType)
+	}
+
+	delta := quantity
+	if movementType == models.StockDecrement {
+		delta = -quantity
+	}
+
+	book, err := i.Repository.ChangeQuantity(ctx, bookID, delta)
+
+	if err != nil {
+		return movement, stockError(err, bookID, quantity)
+	}
+
+	info := requestinfo.From(ctx)
+	movement = models.StockMovement{
+		ID:            uuid.New().String(),
+		BookID:        bookID,
+		Date:          book.UpdatedDate,
+		Type:          movementType,
+		Reason:        reason,
+		Quantity:      quantity,
+		QuantityAfter: book.Quantity,
+		Note:          note,
+		Actor:         info.Actor,
+		RequestID:     info.RequestID,
+	}
+
+	// a stock change without its ledger entry cannot be explained => quantity is changed back and the move fails
+	if err := i.Repository.InsertMovement(ctx, movement); err != nil {
+		i.undoMove(ctx, bookID, delta)
+		return models.StockMovement{}, err
+	}
+
+	writeAudit(ctx, i.AuditRepository, entityBook, bookID, models.AuditStock, []models.FieldChange{
+		{Field: "quantity", Before: book.Quantity - delta, After: book.Quantity},
+	})
+
+	if delta > 0 {
+		promoteHolds(ctx, i.HoldService, bookID)
+	}
+
+	return movement, nil
+}
+
+// undoMove => compensation of the quantity change of Move when its movement cannot be written into the ledger
+func (i InventoryService) undoMove(ctx context.Context, bookID string, delta int) {
+	var err error
+	if delta < 0 {
+		_, err = i.Repository.Restock(ctx, bookID, -delta)
+	} else {
+		// added copies can be reserved meanwhile, then they cannot be taken back
+		_, err = i.Repository.ChangeQuantity(ctx, bookID, -delta)
+	}
+
+	if err != nil {
+		logrus.Errorf("Quantity of {%v} cannot be changed back by %v, the change has no ledger entry: %v", bookID, -delta, err)
+	}
+}
+
+// GetMovements => ledger of a book, newest first
+func (i InventoryService) GetMovements(ctx context.Context, bookID string, skip int64, limit int64) ([]models.StockMovement, int64, error) {
+	result, total, err := i.Repository.GetMovements(ctx, bookID, skip, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// LowStock => books whose quantity is at or below the threshold (configured one if it is nil), lowest first
+func (i InventoryService) LowStock(ctx context.Context, threshold *int, skip int64, limit int64) ([]models.Book, int64, error) {
+	limitQuantity := i.LowStockThreshold
+	if threshold != nil {
+		limitQuantity = *threshold
+	}
+
+	query := models.BookQuery{
+		Skip:    skip,
+		Limit:   limit,
+		Sort:    []models.SortField{{Field: "quantity"}},
+		Filters: []models.Filter{{Field: "quantity", Operator: "lte", Value: limitQuantity}},
+	}
+
+	result, total, err := i.BookRepository.GetAll(ctx, query)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func allowedMovement(movementType string, reason string) bool {
+	for _, allowed := range movementReasons[reason] {
+		if allowed == movementType {
+			return true
+		}
+	}
+	return false
+}
+
+// stockError => errors of a stock change as domain errors, quantity is the count of the copies which are asked for
+func stockError(err error, bookID string, quantity int) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return ErrBookNotFound.Withf("{%v} with id book is not found", bookID)
+	case repository.ErrInsufficientStock:
+		return ErrInsufficientStock.Withf("{%v} with id book has less than %v available copies", bookID, quantity)
+	}
+
+	return err
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"context"
+	"testing"
+)
+
+// fakeInventoryRepository => stock of a single book in memory, the ledger can be made to fail
+// => the other methods of the repository are not used by Move
+type fakeInventoryRepository struct {
+	repository.IInventoryRepository
+	book      models.Book
+	movements []models.StockMovement
+	ledgerErr error
+}
+
+func (f *fakeInventoryRepository) ChangeQuantity(ctx context.Context, bookID string, delta int) (models.Book, error) {
+	if f.book.Quantity-f.book.Reserved+delta < 0 {
+		return models.Book{}, repository.ErrInsufficientStock
+	}
+	f.book.Quantity += delta
+	return f.book, nil
+}
+
+func (f *fakeInventoryRepository) Restock(ctx context.Context, bookID string, quantity int) (models.Book, error) {
+	f.book.Quantity += quantity
+	return f.book, nil
+}
+
+func (f *fakeInventoryRepository) InsertMovement(ctx context.Context, movement models.StockMovement) error {
+	if f.ledgerErr != nil {
+		return f.ledgerErr
+	}
+	f.movements = append(f.movements, movement)
+	return nil
+}
+
+func TestMoveChangesQuantityBackIfLedgerFails(t *testing.T) {
+	ledgerErr := errors.New(errors.Internal, "ledger_down", "ledger cannot be written")
+
+	tests := []struct {
+		name         string
+		movementType string
+		reason       string
+		ledgerErr    error
+		quantity     int
+		movements    int
+	}{
+		{name: "increment", movementType: models.StockIncrement, reason: models.ReasonRestock, quantity: 8, movements: 1},
+		{name: "decrement", movementType: models.StockDecrement, reason: models.ReasonSale, quantity: 2, movements: 1},
+		{name: "increment without ledger", movementType: models.StockIncrement, reason: models.ReasonRestock,
+			ledgerErr: ledgerErr, quantity: 5},
+		{name: "decrement without ledger", movementType: models.StockDecrement, reason: models.ReasonSale,
+			ledgerErr: ledgerErr, quantity: 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventory := &fakeInventoryRepository{book: models.Book{ID: "book-1", Quantity: 5}, ledgerErr: test.ledgerErr}
+			service := InventoryService{Repository: inventory}
+
+			movement, err := service.Move(context.Background(), "book-1", test.movementType, test.reason, 3, "")
+
+			if test.ledgerErr != nil {
+				if !errors.Is(err, test.ledgerErr) || movement.ID != "" {
+					t.Fatalf("expected %v, got %+v (%v)", test.ledgerErr, movement, err)
+				}
+			} else if err != nil || movement.QuantityAfter != test.quantity {
+				t.Fatalf("unexpected movement %+v (%v)", movement, err)
+			}
+			if inventory.book.Quantity != test.quantity || len(inventory.movements) != test.movements {
+				t.Fatalf("expected quantity %d with %d movements, got %d with %d",
+					test.quantity, test.movements, inventory.book.Quantity, len(inventory.movements))
+			}
+		})
+	}
+}
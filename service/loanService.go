@@ -0,0 +1,244 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ErrLoanNotFound => loan doesn't exist
+var ErrLoanNotFound = errors.New(errors.NotFound, "loan_not_found", "loan is not found")
+
+// ErrLoanReturned => loan is already returned
+var ErrLoanReturned = errors.New(errors.Conflict, "loan_returned", "loan is already returned")
+
+// ErrLoanLimitReached => member has as many active loans as its limit
+var ErrLoanLimitReached = errors.New(errors.Conflict, "loan_limit_reached", "loan limit is reached")
+
+type LoanService struct {
+	Repository          repository.ILoanRepository
+	MemberRepository    repository.IMemberRepository
+	InventoryRepository repository.IInventoryRepository
+	AuditRepository     repository.IAuditRepository
+	// ready holds are picked up by checkouts, returned copies go to waiting holds
+	HoldService IHoldService
+	// a loan is overdue after this long
+	LoanPeriod time.Duration
+	// loan limit of the members without their own limit
+	DefaultMaxLoans int
+}
+
+var singleInstanceLoanService *LoanService
+
+func GetSingleInstancesLoanService(repository repository.ILoanRepository, memberRepository repository.IMemberRepository,
+	inventoryRepository repository.IInventoryRepository, auditRepository repository.IAuditRepository, holdService IHoldService,
+	loanPeriod time.Duration, defaultMaxLoans int) *LoanService {
+	if singleInstanceLoanService == nil {
+		fmt.Println("Creating single loan service instance now.")
+		singleInstanceLoanService = &LoanService{Repository: repository, MemberRepository: memberRepository,
+			InventoryRepository: inventoryRepository, AuditRepository: auditRepository, HoldService: holdService,
+			LoanPeriod: loanPeriod, DefaultMaxLoans: defaultMaxLoans}
+	} else {
+		fmt.Println("Single loan service instance already created.")
+	}
+
+	return singleInstanceLoanService
+}
+
+type ILoanService interface {
+	Checkout(ctx context.Context, memberID string, bookID string) (models.Loan, error)
+	GetById(ctx context.Context, id string) (models.Loan, error)
+	Return(ctx context.Context, id string) (models.Loan, error)
+	GetOverdue(ctx context.Context, skip int64, limit int64) ([]models.Loan, int64, error)
+}
+
+// Checkout => a member borrows a copy of a book, the copy leaves the available stock until it is returned
+// => ErrMemberNotFound or ErrBookNotFound is returned for an unknown member or book
+func (l LoanService) Checkout(ctx context.Context, memberID string, bookID string) (models.Loan, error) {
+	var loan models.Loan
+
+	member, err := l.MemberRepository.GetById(ctx, memberID)
+
+	if err != nil {
+		return loan, memberError(err, memberID)
+	}
+
+	maxLoans := member.MaxLoans
+	if maxLoans == 0 {
+		maxLoans = l.DefaultMaxLoans
+	}
+
+	// loan limit is taken first, so parallel checkouts of a member cannot pass the limit
+	if err := l.MemberRepository.IncActiveLoans(ctx, memberID, maxLoans); err != nil {
+		if err == repository.ErrLoanLimitReached {
+			return loan, ErrLoanLimitReached.Withf("{%v} with id member has reached the limit of %v loans", memberID, maxLoans)
+		}
+		return loan, memberError(err, memberID)
+	}
+
+	book, err := l.takeCopy(ctx, bookID, memberID)
+
+	if err != nil {
+		l.giveBackLoanLimit(ctx, memberID)
+		if err == repository.ErrInsufficientStock {
+			return loan, ErrInsufficientStock.Withf("{%v} with id book has no available copies", bookID)
+		}
+		return loan, stockError(err, bookID, 1)
+	}
+
+	now := time.Now()
+	loan = models.Loan{
+		ID:       uuid.New().String(),
+		MemberID: memberID,
+		BookID:   bookID,
+		LoanDate: primitive.NewDateTimeFromTime(now),
+		DueDate:  primitive.NewDateTimeFromTime(now.Add(l.LoanPeriod)),
+	}
+
+	if err := l.Repository.Insert(ctx, loan); err != nil {
+		// without a loan nobody would return the copy
+		if _, changeErr := l.InventoryRepository.Restock(ctx, bookID, 1); changeErr != nil {
+			logrus.Errorf("Copy of {%v} cannot be given back: %v", bookID, changeErr)
+		}
+		l.giveBackLoanLimit(ctx, memberID)
+		return loan, err
+	}
+
+	l.writeMovement(ctx, loan, book, models.StockDecrement, models.ReasonLoan)
+
+	return loan, nil
+}
+
+func (l LoanService) GetById(ctx context.Context, id string) (models.Loan, error) {
+	result, err := l.Repository.GetById(ctx, id)
+
+	if err != nil {
+		return result, loanError(err, id)
+	}
+
+	return result, nil
+}
+
+// Return => borrowed copy is available again, ErrLoanReturned is returned for a second return
+func (l LoanService) Return(ctx context.Context, id string) (models.Loan, error) {
+	loan, err := l.Repository.MarkReturned(ctx, id, time.Now())
+
+	if err != nil {
+		return loan, loanError(err, id)
+	}
+
+	// copy and member are in the tenant of the loan
+	ctx = inTenant(ctx, loan.TenantID)
+
+	book, err := l.InventoryRepository.Restock(ctx, loan.BookID, 1)
+
+	if err == mongo.ErrNoDocuments {
+		// book is purged, the loan is over anyway
+		logrus.Warnf("{%v} with id book of {%v} with id loan doesn't exist anymore", loan.BookID, id)
+		l.giveBackLoanLimit(ctx, loan.MemberID)
+		return loan, nil
+	}
+	if err != nil {
+		// copy is not in the stock again => loan must stay active, so it can be returned again
+		if reopenErr := l.Repository.Reopen(ctx, id); reopenErr != nil {
+			logrus.Errorf("{%v} with id loan cannot be reopened, its copy of {%v} is lost: %v", id, loan.BookID, reopenErr)
+		}
+		return loan, err
+	}
+
+	l.giveBackLoanLimit(ctx, loan.MemberID)
+	l.writeMovement(ctx, loan, book, models.StockIncrement, models.ReasonReturn)
+
+	// returned copy goes to the first member in the hold queue
+	promoteHolds(ctx, l.HoldService, loan.BookID)
+
+	return loan, nil
+}
+
+// GetOverdue => active loans whose due date is passed, most overdue first
+func (l LoanService) GetOverdue(ctx context.Context, skip int64, limit int64) ([]models.Loan, int64, error) {
+	result, total, err := l.Repository.GetOverdue(ctx, time.Now(), skip, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// takeCopy => copy which is held for the member is taken if the member has a ready hold, otherwise an available one
+func (l LoanService) takeCopy(ctx context.Context, bookID string, memberID string) (models.Book, error) {
+	if l.HoldService != nil {
+		hold, err := l.HoldService.TakeReady(ctx, bookID, memberID)
+
+		if err == nil {
+			book, err := l.InventoryRepository.ConsumeReserved(ctx, bookID, 1)
+			if err != nil {
+				// copy is still held => hold must stay ready for the member
+				l.HoldService.Restore(ctx, hold)
+			}
+			return book, err
+		}
+		if err != mongo.ErrNoDocuments && err != repository.ErrHoldNotActive {
+			return models.Book{}, err
+		}
+	}
+
+	return l.InventoryRepository.ChangeQuantity(ctx, bookID, -1)
+}
+
+func (l LoanService) giveBackLoanLimit(ctx context.Context, memberID string) {
+	if err := l.MemberRepository.DecActiveLoans(ctx, memberID); err != nil {
+		logrus.Errorf("Active loans of {%v} member cannot be decremented: %v", memberID, err)
+	}
+}
+
+// writeMovement => checkouts and returns are written into the stock ledger like other movements
+func (l LoanService) writeMovement(ctx context.Context, loan models.Loan, book models.Book, movementType string, reason string) {
+	info := requestinfo.From(ctx)
+	movement := models.StockMovement{
+		ID:            uuid.New().String(),
+		BookID:        loan.BookID,
+		Date:          book.UpdatedDate,
+		Type:          movementType,
+		Reason:        reason,
+		Quantity:      1,
+		QuantityAfter: book.Quantity,
+		Note:          fmt.Sprintf("loan %v of member %v", loan.ID, loan.MemberID),
+		Actor:         info.Actor,
+		RequestID:     info.RequestID,
+	}
+
+	if err := l.InventoryRepository.InsertMovement(ctx, movement); err != nil {
+		logrus.Errorf("Stock movement of {%v} cannot be written into ledger: %v", loan.BookID, err)
+	}
+
+	delta := 1
+	if movementType == models.StockDecrement {
+		delta = -1
+	}
+
+	writeAudit(ctx, l.AuditRepository, entityBook, loan.BookID, models.AuditStock, []models.FieldChange{
+		{Field: "quantity", Before: book.Quantity - delta, After: book.Quantity},
+	})
+}
+
+// loanError => errors of the loan repository as domain errors
+func loanError(err error, id string) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return ErrLoanNotFound.Withf("{%v} with id loan is not found", id)
+	case repository.ErrLoanReturned:
+		return ErrLoanReturned.Withf("{%v} with id loan is already returned", id)
+	}
+
+	return err
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ErrMemberHasLoans => member cannot be deleted before returning the books
+var ErrMemberHasLoans = errors.New(errors.Conflict, "member_has_loans", "member has active loans")
+
+// ErrMemberNotFound => member doesn't exist
+var ErrMemberNotFound = errors.New(errors.NotFound, "member_not_found", "member is not found")
+
+// ErrDuplicateEmail => email of a member is used by another member
+var ErrDuplicateEmail = errors.New(errors.Conflict, "duplicate_email", "email is used by another member")
+
+// entityMember => entity type of member audit records
+const entityMember = "member"
+
+type MemberService struct {
+	Repository      repository.IMemberRepository
+	LoanRepository  repository.ILoanRepository
+	AuditRepository repository.IAuditRepository
+}
+
+var singleInstanceMemberService *MemberService
+
+func GetSingleInstancesMemberService(repository repository.IMemberRepository, loanRepository repository.ILoanRepository,
+	auditRepository repository.IAuditRepository) *MemberService {
+	if singleInstanceMemberService == nil {
+		fmt.Println("Creating single member service instance now.")
+		singleInstanceMemberService = &MemberService{Repository: repository, LoanRepository: loanRepository,
+			AuditRepository: auditRepository}
+	} else {
+		fmt.Println("Single member service instance already created.")
+	}
+
+	return singleInstanceMemberService
+}
+
+type IMemberService interface {
+	Insert(ctx context.Context, member models.Member) (models.Member, error)
+	GetAll(ctx context.Context, skip int64, limit int64) ([]models.Member, int64, error)
+	GetById(ctx context.Context, id string) (models.Member, error)
+	Update(ctx context.Context, member models.Member) (models.Member, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	GetLoans(ctx context.Context, id string, skip int64, limit int64) ([]models.Loan, int64, error)
+}
+
+func (m MemberService) Insert(ctx context.Context, member models.Member) (models.Member, error) {
+	// to create id and created date value
+	member.ID = uuid.New().String()
+	member.CreatedDate = primitive.NewDateTimeFromTime(time.Now())
+	member.UpdatedDate = member.CreatedDate
+	member.ActiveLoans = 0
+
+	if err := m.Repository.Insert(ctx, member); err != nil {
+		return member, memberError(err, member.ID)
+	}
+
+	writeAudit(ctx, m.AuditRepository, entityMember, member.ID, models.AuditCreate, diff(nil, &member))
+
+	return member, nil
+}
+
+func (m MemberService) GetAll(ctx context.Context, skip int64, limit int64) ([]models.Member, int64, error) {
+	result, total, err := m.Repository.GetAll(ctx, skip, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (m MemberService) GetById(ctx context.Context, id string) (models.Member, error) {
+	result, err := m.Repository.GetById(ctx, id)
+
+	if err != nil {
+		return result, memberError(err, id)
+	}
+
+	return result, nil
+}
+
+func (m MemberService) Update(ctx context.Context, member models.Member) (models.Member, error) {
+	// to create updated date value
+	member.UpdatedDate = primitive.NewDateTimeFromTime(time.Now())
+
+	// to keep the old values for audit
+	before, err := m.Repository.GetById(ctx, member.ID)
+
+	if err != nil {
+		return before, memberError(err, member.ID)
+	}
+
+	result, err := m.Repository.Update(ctx, member)
+
+	if err != nil {
+		return result, memberError(err, member.ID)
+	}
+
+	writeAudit(ctx, m.AuditRepository, entityMember, result.ID, models.AuditUpdate, diff(&before, &result))
+
+	return result, nil
+}
+
+// Delete => members with active loans are kept, ErrMemberHasLoans is returned for them
+func (m MemberService) Delete(ctx context.Context, id string) (bool, error) {
+	member, err := m.Repository.GetById(ctx, id)
+
+	if err != nil {
+		return false, memberError(err, id)
+	}
+
+	if member.ActiveLoans > 0 {
+		return false, ErrMemberHasLoans.Withf("{%v} with id member has active loans", id)
+	}
+
+	result, err := m.Repository.Delete(ctx, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	if result == false {
+		// a book is checked out meanwhile
+		return false, ErrMemberHasLoans.Withf("{%v} with id member has active loans", id)
+	}
+
+	writeAudit(ctx, m.AuditRepository, entityMember, id, models.AuditDelete, nil)
+
+	return true, nil
+}
+
+// GetLoans => loan history of a member, newest first
+func (m MemberService) GetLoans(ctx context.Context, id string, skip int64, limit int64) ([]models.Loan, int64, error) {
+	if _, err := m.Repository.GetById(ctx, id); err != nil {
+		return nil, 0, memberError(err, id)
+	}
+
+	result, total, err := m.LoanRepository.GetByMember(ctx, id, skip, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// memberError => errors of the member repository as domain errors
+func memberError(err error, id string) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return ErrMemberNotFound.Withf("{%v} with id member is not found", id)
+	case repository.ErrDuplicateKey:
+		return ErrDuplicateEmail
+	}
+
+	return err
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"RestfulWithEcho/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// reaperBatchSize => expired records are handled in batches
+const reaperBatchSize = 100
+
+// reapBatches => to reap expired records batch by batch, every batch is read after the last record of the previous one
+// => a record which cannot be reaped is logged, counted and tried again on the next run, it doesn't block the newer ones
+// reap returns false if the record is finished meanwhile (e.g. by another instance), it is neither reaped nor failed
+func reapBatches[T any](entity string, fetch func(after *repository.ExpiryCursor) ([]T, error),
+	position func(T) repository.ExpiryCursor, reap func(T) (bool, error)) (int, error) {
+	reaped, failed := 0, 0
+	var after *repository.ExpiryCursor
+
+	for {
+		expired, err := fetch(after)
+
+		if err != nil {
+			return reaped, err
+		}
+
+		for _, record := range expired {
+			cursor := position(record)
+			after = &cursor
+
+			done, err := reap(record)
+			if err != nil {
+				logrus.Errorf("{%v} with id %v cannot be reaped: %v", cursor.ID, entity, err)
+				failed++
+				continue
+			}
+			if done {
+				reaped++
+			}
+		}
+
+		if len(expired) < reaperBatchSize {
+			break
+		}
+	}
+
+	if failed > 0 {
+		logrus.Warnf("%d expired %vs cannot be reaped, they are tried again on the next run.", failed, entity)
+	}
+
+	return reaped, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/repository"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+// expiredRecord => record of the fake repository, position is its place in the expiry order
+type expiredRecord struct {
+	position repository.ExpiryCursor
+	poison   bool
+	finished bool
+}
+
+// after => records after the cursor like GetExpired, records are already in the expiry order
+func after(records []*expiredRecord, cursor *repository.ExpiryCursor, limit int) []*expiredRecord {
+	var batch []*expiredRecord
+	for _, record := range records {
+		if record.finished {
+			continue
+		}
+		if cursor != nil && (record.position.Date < cursor.Date ||
+			record.position.Date == cursor.Date && record.position.ID <= cursor.ID) {
+			continue
+		}
+		if batch = append(batch, record); len(batch) == limit {
+			break
+		}
+	}
+	return batch
+}
+
+func TestReapBatchesPagesPastFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		poison int
+		ready  int
+		reaped int
+	}{
+		{name: "nothing expired"},
+		{name: "all reaped", ready: 2*reaperBatchSize + 1, reaped: 2*reaperBatchSize + 1},
+		{name: "full batch of poison records", poison: reaperBatchSize, ready: 50, reaped: 50},
+		{name: "poison records across batches", poison: reaperBatchSize + 30, ready: reaperBatchSize, reaped: reaperBatchSize},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var records []*expiredRecord
+			for i := 0; i < test.poison+test.ready; i++ {
+				// oldest ones are poison, same dates make _id decide the order
+				records = append(records, &expiredRecord{
+					position: repository.ExpiryCursor{Date: primitive.DateTime(i / 3), ID: fmt.Sprintf("%05d", i)},
+					poison:   i < test.poison,
+				})
+			}
+
+			fetches := 0
+			reaped, err := reapBatches("record",
+				func(cursor *repository.ExpiryCursor) ([]*expiredRecord, error) {
+					if fetches++; fetches > len(records)/reaperBatchSize+2 {
+						t.Fatal("reaper doesn't make progress")
+					}
+					return after(records, cursor, reaperBatchSize), nil
+				},
+				func(record *expiredRecord) repository.ExpiryCursor {
+					return record.position
+				},
+				func(record *expiredRecord) (bool, error) {
+					if record.poison {
+						return false, errors.New(errors.Internal, "poison", "record cannot be reaped")
+					}
+					record.finished = true
+					return true, nil
+				})
+
+			if err != nil || reaped != test.reaped {
+				t.Fatalf("expected %d reaped records, got %d (%v)", test.reaped, reaped, err)
+			}
+		})
+	}
+}
+
+func TestReapBatchesSkipsRecordsFinishedMeanwhile(t *testing.T) {
+	records := []*expiredRecord{
+		{position: repository.ExpiryCursor{Date: 1, ID: "a"}},
+		{position: repository.ExpiryCursor{Date: 2, ID: "b"}, finished: true},
+	}
+
+	reaped, err := reapBatches("record",
+		func(cursor *repository.ExpiryCursor) ([]*expiredRecord, error) {
+			if cursor != nil {
+				return nil, nil
+			}
+			return records, nil
+		},
+		func(record *expiredRecord) repository.ExpiryCursor {
+			return record.position
+		},
+		func(record *expiredRecord) (bool, error) {
+			return !record.finished, nil
+		})
+
+	if err != nil || reaped != 1 {
+		t.Fatalf("expected 1 reaped record, got %d (%v)", reaped, err)
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"RestfulWithEcho/errors"
+	"RestfulWithEcho/models"
+	"RestfulWithEcho/repository"
+	"RestfulWithEcho/requestinfo"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ErrInvalidReservation => quantity or ttl of a reservation is out of limits
+var ErrInvalidReservation = errors.New(errors.Invalid, "invalid_reservation", "invalid reservation")
+
+// ErrReservationExpired => reservation cannot be confirmed, its copies are (or will soon be) given back
+var ErrReservationExpired = errors.New(errors.Conflict, "reservation_expired", "reservation is expired")
+
+// ErrReservationNotFound => reservation doesn't exist
+var ErrReservationNotFound = errors.New(errors.NotFound, "reservation_not_found", "reservation is not found")
+
+// ErrReservationNotPending => reservation is already confirmed, released or expired
+var ErrReservationNotPending = errors.New(errors.Conflict, "reservation_not_pending", "reservation is not pending")
+
+type ReservationService struct {
+	Repository          repository.IReservationRepository
+	InventoryRepository repository.IInventoryRepository
+	AuditRepository     repository.IAuditRepository
+	DefaultTTL          time.Duration
+	MaxTTL              time.Duration
+}
+
+var singleInstanceReservationService *ReservationService
+
+func GetSingleInstancesReservationService(repository repository.IReservationRepository, inventoryRepository repository.IInventoryRepository,
+	auditRepository repository.IAuditRepository, defaultTTL time.Duration, maxTTL time.Duration) *ReservationService {
+	if singleInstanceReservationService == nil {
+		fmt.Println("Creating single reservation service instance now.")
+		singleInstanceReservationService = &ReservationService{Repository: repository, InventoryRepository: inventoryRepository,
+			AuditRepository: auditRepository, DefaultTTL: defaultTTL, MaxTTL: maxTTL}
+	} else {
+		fmt.Println("Single reservation service instance already created.")
+	}
+
+	return singleInstanceReservationService
+}
+
+type IReservationService interface {
+	Reserve(ctx context.Context, bookID string, quantity int, ttl time.Duration) (models.Reservation, error)
+	GetById(ctx context.Context, id string) (models.Reservation, error)
+	Confirm(ctx context.Context, id string) (models.Reservation, error)
+	Release(ctx context.Context, id string) (models.Reservation, error)
+	ReapExpired(ctx context.Context) (int, error)
+}
+
+// Reserve => to hold copies of a book for ttl (default one if it is zero)
+func (r ReservationService) Reserve(ctx context.Context, bookID string, quantity int, ttl time.Duration) (models.Reservation, error) {
+	var reservation models.Reservation
+
+	if ttl == 0 {
+		ttl = r.DefaultTTL
+	}
+	if quantity < 1 || ttl < 0 || ttl > r.MaxTTL {
+		return reservation, ErrInvalidReservation.Withf("invalid reservation, ttl can be at most %v seconds",
+			int(r.MaxTTL.Seconds()))
+	}
+
+	// copies are held first, so two customers cannot reserve the last copy
+	if _, err := r.InventoryRepository.Reserve(ctx, bookID, quantity); err != nil {
+		return reservation, stockError(err, bookID, quantity)
+	}
+
+	now := time.Now()
+	reservation = models.Reservation{
+		ID:          uuid.New().String(),
+		BookID:      bookID,
+		Quantity:    quantity,
+		Status:      models.ReservationPending,
+		CreatedDate: primitive.NewDateTimeFromTime(now),
+		ExpiresAt:   primitive.NewDateTimeFromTime(now.Add(ttl)),
+		Actor:       requestinfo.From(ctx).Actor,
+	}
+
+	if err := r.Repository.Insert(ctx, reservation); err != nil {
+		// without a reservation nobody would give the copies back
+		if _, unreserveErr := r.InventoryRepository.Unreserve(ctx, bookID, quantity); unreserveErr != nil {
+			logrus.Errorf("%v copies of {%v} cannot be given back: %v", quantity, bookID, unreserveErr)
+		}
+		return reservation, err
+	}
+
+	return reservation, nil
+}
+
+func (r ReservationService) GetById(ctx context.Context, id string) (models.Reservation, error) {
+	result, err := r.Repository.GetById(ctx, id)
+
+	if err != nil {
+		return result, reservationError(err, id)
+	}
+
+	return result, nil
+}
+
+// Confirm => held copies leave the stock as a sale
+func (r ReservationService) Confirm(ctx context.Context, id string) (models.Reservation, error) {
+	now := time.Now()
+
+	reservation, err := r.Repository.Finish(ctx, id, models.ReservationConfirmed, &now)
+
+	if err == repository.ErrReservationNotPending {
+		// pending but out of time => reaper gives its copies back
+		if current, getErr := r.Repository.GetById(ctx, id); getErr == nil && current.Status == models.ReservationPending {
+			return current, ErrReservationExpired.Withf("{%v} with id reservation is expired", id)
+		}
+	}
+	if err != nil {
+		return reservation, reservationError(err, id)
+	}
+
+	// copies are in the tenant of the reservation
+	ctx = inTenant(ctx, reservation.TenantID)
+	book, err := r.InventoryRepository.ConsumeReserved(ctx, reservation.BookID, reservation.Quantity)
+
+	if err != nil {
+		// copies are still held => reservation must stay pending, so they are given back when it expires
+		r.reopen(ctx, reservation, models.ReservationConfirmed)
+		return reservation, stockError(err, reservation.BookID, reservation.Quantity)
+	}
+
+	info := requestinfo.From(ctx)
+	movement := models.StockMovement{
+		ID:            uuid.New().String(),
+		BookID:        reservation.BookID,
+		Date:          book.UpdatedDate,
+		Type:          models.StockDecrement,
+		Reason:        models.ReasonSale,
+		Quantity:      reservation.Quantity,
+		QuantityAfter: book.Quantity,
+		Note:          fmt.Sprintf("reservation %v", reservation.ID),
+		Actor:         info.Actor,
+		RequestID:     info.RequestID,
+	}
+
+	if err := r.InventoryRepository.InsertMovement(ctx, movement); err != nil {
+		logrus.Errorf("Stock movement of {%v} cannot be written into ledger: %v", reservation.BookID, err)
+	}
+
+	writeAudit(ctx, r.AuditRepository, entityBook, reservation.BookID, models.AuditStock, []models.FieldChange{
+		{Field: "quantity", Before: book.Quantity + reservation.Quantity, After: book.Quantity},
+	})
+
+	return reservation, nil
+}
+
+// Release => held copies are available again
+func (r ReservationService) Release(ctx context.Context, id string) (models.Reservation, error) {
+	reservation, err := r.finishAndUnreserve(ctx, id, models.ReservationReleased)
+
+	return reservation, reservationError(err, id)
+}
+
+// ReapExpired => to give back the copies of expired reservations, it returns the count of them
+// => a reservation which cannot be reaped is logged and tried again on the next run, the others are still reaped
+func (r ReservationService) ReapExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	return reapBatches("reservation",
+		func(after *repository.ExpiryCursor) ([]models.Reservation, error) {
+			return r.Repository.GetExpired(ctx, now, after, reaperBatchSize)
+		},
+		func(reservation models.Reservation) repository.ExpiryCursor {
+			return repository.ExpiryCursor{Date: reservation.ExpiresAt, ID: reservation.ID}
+		},
+		func(reservation models.Reservation) (bool, error) {
+			// another instance can reap the same reservation, only one of them can finish it
+			_, err := r.finishAndUnreserve(inTenant(ctx, reservation.TenantID), reservation.ID, models.ReservationExpired)
+			if err == repository.ErrReservationNotPending {
+				return false, nil
+			}
+			return err == nil, err
+		})
+}
+
+// RunReaper => to reap expired reservations periodically until ctx is done
+func (r ReservationService) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if count, err := r.ReapExpired(ctx); err != nil {
+				logrus.Errorf("Expired reservations cannot be reaped: %v", err)
+			} else if count > 0 {
+				logrus.Infof("%d expired reservations are reaped.", count)
+			}
+		}
+	}
+}
+
+func (r ReservationService) finishAndUnreserve(ctx context.Context, id string, status string) (models.Reservation, error) {
+	reservation, err := r.Repository.Finish(ctx, id, status, nil)
+
+	if err != nil {
+		return reservation, err
+	}
+
+	// copies are in the tenant of the reservation
+	ctx = inTenant(ctx, reservation.TenantID)
+	if _, err := r.InventoryRepository.Unreserve(ctx, reservation.BookID, reservation.Quantity); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// book is purged, there are no copies to give back
+			logrus.Warnf("{%v} with id book of {%v} with id reservation doesn't exist anymore", reservation.BookID, id)
+			return reservation, nil
+		}
+		r.reopen(ctx, reservation, status)
+		return reservation, err
+	}
+
+	return reservation, nil
+}
+
+// reopen => compensation of Finish when the copies of the reservation cannot be handled
+func (r ReservationService) reopen(ctx context.Context, reservation models.Reservation, status string) {
+	if err := r.Repository.Reopen(ctx, reservation.ID, status); err != nil {
+		logrus.Errorf("{%v} with id reservation cannot be reopened, %v copies of {%v} stay held: %v",
+			reservation.ID, reservation.Quantity, reservation.BookID, err)
+	}
+}
+
+// reservationError => errors of the reservation repository as domain errors
+func reservationError(err error, id string) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return ErrReservationNotFound.Withf("{%v} with id reservation is not found", id)
+	case repository.ErrReservationNotPending:
+		return ErrReservationNotPending.Withf("{%v} with id reservation is not pending", id)
+	}
+
+	return err
+}
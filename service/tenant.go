@@ -0,0 +1,18 @@
+package service
+
+import (
+	"RestfulWithEcho/requestinfo"
+	"context"
+)
+
+// inTenant => context of the tenant which a record belongs to, so the books of a reservation, hold or loan are found
+// in its tenant even if the context has none (e.g. reapers) => records without a tenant keep the tenant of ctx
+func inTenant(ctx context.Context, tenantID string) context.Context {
+	if tenantID == "" {
+		return ctx
+	}
+
+	info := requestinfo.From(ctx)
+	info.TenantID = tenantID
+	return requestinfo.With(ctx, info)
+}
@@ -0,0 +1,62 @@
+package tenant
+
+import (
+	"RestfulWithEcho/errors"
+	"regexp"
+	"strings"
+)
+
+// Default => tenant of the requests which don't have one and of the books which were created before tenants
+const Default = "default"
+
+// ErrInvalidTenant => tenant id must be lowercase letters, digits and dashes, e.g. acme-books
+var ErrInvalidTenant = errors.New(errors.Invalid, "invalid_tenant", "invalid tenant")
+
+// ErrTenantRequired => request has no tenant and the default tenant is disabled
+var ErrTenantRequired = errors.New(errors.Invalid, "tenant_required", "tenant is required")
+
+// ErrTenantMismatch => requested tenant is not the tenant of the access token or api key
+var ErrTenantMismatch = errors.New(errors.Forbidden, "tenant_mismatch", "tenant is not the tenant of the credentials")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Normalize => tenant ids are case insensitive
+func Normalize(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// Valid => ErrInvalidTenant if the id is not a normalized tenant id
+func Valid(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrInvalidTenant.Withf("{%v} is not a tenant, tenants are lowercase letters, digits and dashes", id)
+	}
+	return nil
+}
+
+// OrDefault => Default for an empty tenant, e.g. users and api keys which were created before tenants
+func OrDefault(id string) string {
+	if id == "" {
+		return Default
+	}
+	return id
+}
+
+// FromHost => tenant of a subdomain of the base domain, acme.books.example.com => acme for books.example.com
+// => empty if the host is not a subdomain of the base domain or the base domain is empty
+func FromHost(host string, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+
+	// port is not a part of the domain
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+
+	subdomain := strings.TrimSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if subdomain == strings.ToLower(host) || strings.Contains(subdomain, ".") {
+		return ""
+	}
+
+	return subdomain
+}
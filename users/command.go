@@ -0,0 +1,69 @@
+package users
+
+import (
+	"RestfulWithEcho/rbac"
+	"RestfulWithEcho/requestinfo"
+	"RestfulWithEcho/service"
+	"RestfulWithEcho/tenant"
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RunCommand => "user add [-tenant id] [-roles editor,admin] username" subcommand of the binary, password is read from the first line of in
+// => there is no sign up endpoint, users of the api are created by the operators
+func RunCommand(authService service.IAuthService, args []string, in io.Reader, out io.Writer) error {
+	usage := fmt.Errorf("usage: user add [-tenant id] [-roles %v] username (password is read from stdin)", strings.Join(rbac.Roles(), ","))
+	if len(args) == 0 || args[0] != "add" {
+		return usage
+	}
+
+	flags := flag.NewFlagSet("user add", flag.ContinueOnError)
+	flags.SetOutput(out)
+	roles := flags.String("roles", rbac.Viewer, "comma separated roles of the user")
+	tenantID := flags.String("tenant", tenant.Default, "tenant (bookstore) of the user")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usage
+	}
+	*tenantID = tenant.Normalize(*tenantID)
+	if err := tenant.Valid(*tenantID); err != nil {
+		return err
+	}
+
+	password, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	// user is created in the tenant of the context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ctx = requestinfo.With(ctx, requestinfo.Info{Actor: requestinfo.Anonymous, TenantID: *tenantID})
+
+	user, err := authService.CreateUser(ctx, flags.Arg(0), strings.TrimRight(password, "\r\n"), splitRoles(*roles))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "user %v of tenant %v is created with id %v and roles %v\n", user.Username, user.TenantID, user.ID,
+		strings.Join(user.Roles, ","))
+	return nil
+}
+
+func splitRoles(text string) []string {
+	var roles []string
+	for _, role := range strings.Split(text, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}